@@ -1,9 +1,12 @@
 package application
 
 import (
+	"context"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
 	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
 	"github.com/samuelmahr/appt-scheduling/internal/repo"
 	"github.com/samuelmahr/appt-scheduling/internal/routers"
 	"log"
@@ -12,8 +15,13 @@ import (
 )
 
 type APIApplication struct {
-	config *configuration.AppConfig
-	srv    *http.Server
+	config    *configuration.AppConfig
+	srv       *http.Server
+	holdsRepo repo.HoldsRepoType
+	// waitlistRepo offers the time slots of expired holds to the waitlist
+	waitlistRepo repo.WaitlistRepoType
+	relay        Relay
+	dispatcher   Dispatcher
 }
 
 func NewAPIApplication(c *configuration.AppConfig) *APIApplication {
@@ -27,8 +35,26 @@ func NewAPIApplication(c *configuration.AppConfig) *APIApplication {
 	db.SetMaxOpenConns(c.PostgresMaxOpenConns)
 
 	appointmentsRepo := repo.NewAppointmentsRepository(db)
+	workingHoursRepo := repo.NewWorkingHoursRepository(db)
+	timeOffRepo := repo.NewTimeOffRepository(db)
+	sessionTypesRepo := repo.NewSessionTypesRepository(db)
+	trainersRepo := repo.NewTrainersRepository(db)
+	usersRepo := repo.NewUsersRepository(db)
+	holdsRepo := repo.NewHoldsRepository(db)
+	waitlistRepo := repo.NewWaitlistRepository(db)
+	outboxRepo := repo.NewOutboxRepository(db)
+	webhooksRepo := repo.NewWebhooksRepository(db)
+	calendarsRepo := repo.NewCalendarsRepository(db)
+	sink, err := newPublisher(c)
+	if err != nil {
+		log.Fatalf("can't set up the outbox sink: %v", err)
+	}
+
+	// webhooks get every event through the outbox too, on top of the configured sink
+	publisher := events.NewMultiPublisher(sink, &webhookFanout{webhooks: &webhooksRepo})
+
 	rootRouter := mux.NewRouter()
-	r := routers.NewV1Router(c, appointmentsRepo)
+	r := routers.NewV1Router(c, appointmentsRepo, workingHoursRepo, timeOffRepo, sessionTypesRepo, trainersRepo, usersRepo, holdsRepo, waitlistRepo, webhooksRepo, calendarsRepo)
 	r.Register(rootRouter)
 
 	srv := &http.Server{
@@ -40,13 +66,74 @@ func NewAPIApplication(c *configuration.AppConfig) *APIApplication {
 	}
 
 	return &APIApplication{
-		config: c,
-		srv:    srv,
+		config:       c,
+		srv:          srv,
+		holdsRepo:    holdsRepo,
+		waitlistRepo: waitlistRepo,
+		relay:        NewRelay(&outboxRepo, &publisher, c.OutboxBatchSize),
+		dispatcher:   NewDispatcher(&webhooksRepo, time.Duration(c.WebhookTimeoutSeconds)*time.Second, c.WebhookBatchSize, c.WebhookMaxAttempts),
 	}
 }
 
 func (a *APIApplication) Run() {
 	// hard coded... didn't know if I had it running or not
+	go a.sweepHolds(time.Duration(a.config.HoldSweepIntervalSeconds) * time.Second)
+	go a.relay.Run(time.Duration(a.config.OutboxRelayIntervalSeconds) * time.Second)
+	go a.dispatcher.Run(time.Duration(a.config.WebhookDispatchIntervalSeconds) * time.Second)
+
 	log.Println("listening on port 8000")
 	log.Fatal(a.srv.ListenAndServe())
 }
+
+// sweepHolds releases expired holds every interval, expired holds already stop blocking their time slot,
+// this closes them out and offers their time slots to the waitlist. it also retries offering canceled appointments'
+// time that didn't get offered when they were canceled
+func (a *APIApplication) sweepHolds(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		a.offerUnofferedSlots()
+
+		released, err := a.holdsRepo.ReleaseExpiredHolds(context.Background())
+		if err != nil {
+			log.Printf("error releasing expired holds: %v\n", err)
+			continue
+		}
+
+		if len(released) > 0 {
+			log.Printf("released %d expired holds\n", len(released))
+		}
+
+		for _, hold := range released {
+			a.offerFreedSlot(hold)
+		}
+	}
+}
+
+// offerFreedSlot offers the expired hold's time slot to the waitlist, the offers' events are published from the outbox
+func (a *APIApplication) offerFreedSlot(hold models.Hold) {
+	ttl := time.Duration(a.config.WaitlistOfferTTLSeconds) * time.Second
+	_, err := a.waitlistRepo.OfferFreedSlot(context.Background(), hold.TrainerID, hold.StartsAt, hold.EndsAt, ttl)
+	if err != nil {
+		log.Printf("error offering expired hold %d to the waitlist: %v\n", hold.ID, err)
+	}
+}
+
+// offerUnofferedSlots offers the time of canceled appointments someone is still waiting for, offering it after the
+// cancel is best effort, so this is what makes sure it happens
+func (a *APIApplication) offerUnofferedSlots() {
+	slots, err := a.waitlistRepo.ListUnofferedFreedSlots(context.Background())
+	if err != nil {
+		log.Printf("error listing unoffered freed time slots: %v\n", err)
+		return
+	}
+
+	ttl := time.Duration(a.config.WaitlistOfferTTLSeconds) * time.Second
+	for _, slot := range slots {
+		_, err = a.waitlistRepo.OfferFreedSlot(context.Background(), slot.TrainerID, slot.StartsAt, slot.EndsAt, ttl)
+		if err != nil {
+			log.Printf("error offering trainer %d time slot %s to the waitlist: %v\n", slot.TrainerID, slot.StartsAt, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookFanout is the relay's publisher for webhooks, it queues a delivery of each event for every subscribed webhook
+// the dispatcher sends them, so one slow or failing webhook doesn't hold up the outbox or the others
+type webhookFanout struct {
+	webhooks repo.WebhooksRepository
+}
+
+func (f *webhookFanout) Publish(ctx context.Context, event events.Event) error {
+	_, err := f.webhooks.EnqueueWebhookDeliveries(ctx, event)
+	return err
+}
+
+// Dispatcher sends queued webhook deliveries signed with the webhook's secret, recording every attempt. a delivery that
+// doesn't get a 2xx is retried with backoff until it has been tried maxAttempts times
+type Dispatcher struct {
+	webhooks    repo.WebhooksRepository
+	client      *http.Client
+	batchSize   int
+	maxAttempts int
+}
+
+func NewDispatcher(webhooks repo.WebhooksRepository, timeout time.Duration, batchSize int, maxAttempts int) Dispatcher {
+	return Dispatcher{
+		webhooks:    webhooks,
+		client:      &http.Client{Timeout: timeout},
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// DispatchBatch sends a batch of due deliveries, returning how many were claimed
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	claimed, err := d.webhooks.ClaimWebhookDeliveries(ctx, d.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, dispatch := range claimed {
+		attempt := d.send(ctx, dispatch)
+		delivery, err := d.webhooks.RecordWebhookDeliveryAttempt(ctx, dispatch.ID, attempt, d.maxAttempts)
+		if err != nil {
+			// it's sent again when the next attempt is due
+			log.Printf("error recording webhook delivery %d attempt: %v\n", dispatch.ID, err)
+			continue
+		}
+
+		if delivery.Status == models.WebhookDeliveryFailed {
+			log.Printf("webhook delivery %d to webhook %d failed after %d attempts\n", dispatch.ID, dispatch.WebhookID, delivery.AttemptCount)
+		}
+	}
+
+	return len(claimed), nil
+}
+
+// send posts the delivery's payload to the webhook, the attempt has the response status or why there wasn't one
+func (d *Dispatcher) send(ctx context.Context, dispatch models.WebhookDispatch) models.WebhookDeliveryAttempt {
+	started := time.Now()
+	attempt := models.WebhookDeliveryAttempt{}
+	failed := func(err error) models.WebhookDeliveryAttempt {
+		msg := err.Error()
+		attempt.Error = &msg
+		attempt.DurationMS = int(time.Since(started).Milliseconds())
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(dispatch.Payload))
+	if err != nil {
+		return failed(err)
+	}
+
+	timestamp := started.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(events.DeliveryHeader, strconv.FormatInt(dispatch.ID, 10))
+	req.Header.Set(events.EventTypeHeader, dispatch.EventType)
+	req.Header.Set(events.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(events.SignatureHeader, events.Sign(dispatch.Secret, timestamp, dispatch.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return failed(err)
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	status := resp.StatusCode
+	attempt.ResponseStatus = &status
+	attempt.DurationMS = int(time.Since(started).Milliseconds())
+	return attempt
+}
+
+// Run dispatches batches every interval, a full batch is followed right away by the next one to catch up
+func (d *Dispatcher) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for {
+			claimed, err := d.DispatchBatch(context.Background())
+			if err != nil {
+				log.Printf("error dispatching webhook deliveries: %v\n", err)
+				break
+			}
+
+			if claimed < d.batchSize {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDispatcher_DispatchBatch(t *testing.T) {
+	payload := json.RawMessage(`{"id":7,"type":"appointment.created","occurred_at":"2022-03-17T19:00:00Z","data":{}}`)
+
+	tests := []struct {
+		name       string
+		status     int
+		closed     bool
+		wantStatus *int
+		wantError  bool
+	}{
+		{
+			name:       "happy path",
+			status:     http.StatusOK,
+			wantStatus: intPtr(http.StatusOK),
+		},
+		{
+			name:       "fail server error",
+			status:     http.StatusServiceUnavailable,
+			wantStatus: intPtr(http.StatusServiceUnavailable),
+		},
+		{
+			name:      "fail no response",
+			closed:    true,
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var gotBody []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				gotBody, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			if tt.closed {
+				srv.Close()
+			}
+
+			webhooks := repo.MockWebhooks{
+				ClaimWebhookDeliveriesResponse: []models.WebhookDispatch{
+					{
+						WebhookDelivery: models.WebhookDelivery{ID: 3, WebhookID: 1, EventID: 7, EventType: events.AppointmentCreated, Payload: payload},
+						URL:             srv.URL,
+						Secret:          "0123456789abcdef",
+					},
+				},
+			}
+			dispatcher := NewDispatcher(&webhooks, time.Second, 10, 5)
+
+			claimed, err := dispatcher.DispatchBatch(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, 1, claimed)
+
+			if assert.Len(t, webhooks.Recorded, 1) {
+				attempt := webhooks.Recorded[0]
+				assert.Equal(t, int64(3), attempt.DeliveryID)
+				assert.Equal(t, tt.wantStatus, attempt.ResponseStatus)
+				assert.Equal(t, tt.wantError, attempt.Error != nil)
+			}
+
+			if tt.closed {
+				return
+			}
+
+			// the receiver can check the signature with the shared secret
+			assert.JSONEq(t, string(payload), string(gotBody))
+			assert.Equal(t, "3", got.Header.Get(events.DeliveryHeader))
+			assert.Equal(t, events.AppointmentCreated, got.Header.Get(events.EventTypeHeader))
+
+			timestamp, err := strconv.ParseInt(got.Header.Get(events.TimestampHeader), 10, 64)
+			assert.NoError(t, err)
+			assert.Equal(t, events.Sign("0123456789abcdef", timestamp, gotBody), got.Header.Get(events.SignatureHeader))
+		})
+	}
+}
+
+func TestDispatcher_DispatchBatch_ClaimFails(t *testing.T) {
+	webhooks := repo.MockWebhooks{ClaimWebhookDeliveriesErr: errors.New("db is down")}
+	dispatcher := NewDispatcher(&webhooks, time.Second, 10, 5)
+
+	_, err := dispatcher.DispatchBatch(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, webhooks.Recorded)
+}
+
+func TestWebhookFanout_Publish(t *testing.T) {
+	webhooks := repo.MockWebhooks{}
+	sink := events.MockPublisher{}
+	publisher := events.NewMultiPublisher(&sink, &webhookFanout{webhooks: &webhooks})
+
+	event := events.Event{ID: 7, Type: events.AppointmentCanceled}
+	err := publisher.Publish(context.Background(), event)
+	assert.NoError(t, err)
+	assert.Equal(t, []events.Event{event}, sink.Published)
+	assert.Equal(t, []events.Event{event}, webhooks.Queued)
+
+	// queueing failing fails the event, so the relay retries it
+	webhooks.EnqueueWebhookDeliveriesErr = errors.New("db is down")
+	err = publisher.Publish(context.Background(), event)
+	assert.Error(t, err)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
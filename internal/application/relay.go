@@ -0,0 +1,103 @@
+package application
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"log"
+	"time"
+)
+
+// Relay publishes the events in the outbox, at least once. an event is only marked published after the publisher
+// accepts it, so one published but not marked (ex. the app stopped in between) is published again
+type Relay struct {
+	outbox    repo.OutboxRepository
+	publisher events.Publisher
+	batchSize int
+}
+
+func NewRelay(outbox repo.OutboxRepository, publisher events.Publisher, batchSize int) Relay {
+	return Relay{
+		outbox:    outbox,
+		publisher: publisher,
+		batchSize: batchSize,
+	}
+}
+
+// RelayBatch publishes a batch of due events, returning how many were claimed. an event that fails to publish keeps
+// the error and is retried once its next attempt is due, it doesn't stop the rest of the batch
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	claimed, err := r.outbox.ClaimOutboxEvents(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range claimed {
+		err = r.publisher.Publish(ctx, events.NewOutboxEvent(e))
+		if err != nil {
+			log.Printf("error publishing outbox event %d, attempt %d: %v\n", e.ID, e.Attempts, err)
+			if err := r.outbox.MarkOutboxEventFailed(ctx, e.ID, err); err != nil {
+				log.Printf("error saving outbox event %d failure: %v\n", e.ID, err)
+			}
+			continue
+		}
+
+		err = r.outbox.MarkOutboxEventPublished(ctx, e.ID)
+		if err != nil {
+			// it's published again when the next attempt is due
+			log.Printf("error marking outbox event %d published: %v\n", e.ID, err)
+		}
+	}
+
+	return len(claimed), nil
+}
+
+// Run relays batches every interval, a full batch is followed right away by the next one to catch up
+func (r *Relay) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for {
+			claimed, err := r.RelayBatch(context.Background())
+			if err != nil {
+				log.Printf("error relaying outbox events: %v\n", err)
+				break
+			}
+
+			if claimed < r.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// newPublisher is the sink the relay publishes to, from outbox_sink
+func newPublisher(c *configuration.AppConfig) (events.Publisher, error) {
+	switch c.OutboxSink {
+	case "log":
+		p := events.NewLogPublisher()
+		return &p, nil
+	case "stdout":
+		p := events.NewStdoutPublisher()
+		return &p, nil
+	case "file":
+		p, err := events.NewFilePublisher(c.OutboxFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return &p, nil
+	case "webhook":
+		if c.OutboxWebhookURL == "" {
+			return nil, errors.New("outbox_webhook_url is required for the webhook sink")
+		}
+
+		p := events.NewWebhookPublisher(c.OutboxWebhookURL, time.Duration(c.OutboxWebhookTimeoutSeconds)*time.Second)
+		return &p, nil
+	default:
+		return nil, errors.Errorf("unknown outbox sink %s", c.OutboxSink)
+	}
+}
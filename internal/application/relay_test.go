@@ -0,0 +1,75 @@
+package application
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRelay_RelayBatch(t *testing.T) {
+	claimed := []models.OutboxEvent{
+		{ID: 1, EventType: events.AppointmentCreated, Payload: []byte(`{"appointment":{"id":1}}`), OccurredAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC), Attempts: 1},
+		{ID: 2, EventType: events.AppointmentCanceled, Payload: []byte(`{"appointment":{"id":1}}`), OccurredAt: time.Date(2022, 03, 17, 19, 5, 0, 0, time.UTC), Attempts: 3},
+	}
+
+	tests := []struct {
+		name          string
+		outbox        repo.MockOutbox
+		publisher     events.MockPublisher
+		wantClaimed   int
+		wantErr       bool
+		wantPublished []int64
+		wantFailed    []int64
+	}{
+		{
+			name:          "happy path",
+			outbox:        repo.MockOutbox{ClaimOutboxEventsResponse: claimed},
+			wantClaimed:   2,
+			wantPublished: []int64{1, 2},
+		},
+		{
+			name:        "nothing to publish",
+			outbox:      repo.MockOutbox{ClaimOutboxEventsResponse: []models.OutboxEvent{}},
+			wantClaimed: 0,
+		},
+		{
+			name:        "publish fails keeps events for a retry",
+			outbox:      repo.MockOutbox{ClaimOutboxEventsResponse: claimed},
+			publisher:   events.MockPublisher{PublishErr: errors.New("sink is down")},
+			wantClaimed: 2,
+			wantFailed:  []int64{1, 2},
+		},
+		{
+			name:    "fail claiming",
+			outbox:  repo.MockOutbox{ClaimOutboxEventsErr: errors.New("db is down")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relay := NewRelay(&tt.outbox, &tt.publisher, 10)
+
+			got, err := relay.RelayBatch(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantClaimed, got)
+			assert.Equal(t, tt.wantPublished, tt.outbox.PublishedIDs)
+			assert.Equal(t, tt.wantFailed, tt.outbox.FailedIDs)
+
+			for i, event := range tt.publisher.Published {
+				assert.Equal(t, claimed[i].ID, event.ID)
+				assert.Equal(t, claimed[i].EventType, event.Type)
+				assert.Equal(t, claimed[i].OccurredAt, event.OccurredAt)
+			}
+		})
+	}
+}
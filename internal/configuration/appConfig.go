@@ -15,6 +15,31 @@ type AppConfig struct {
 	PostgresMaxIdleConns           int
 	PostgresMaxConnLifetimeSeconds int
 
+	// HoldTTLSeconds is how long a hold lasts when the request doesn't set ttl_seconds, MaxHoldTTLSeconds caps it
+	HoldTTLSeconds    int
+	MaxHoldTTLSeconds int
+	// HoldSweepIntervalSeconds is how often expired holds are released
+	HoldSweepIntervalSeconds int
+	// WaitlistOfferTTLSeconds is how long the hold lasts when a freed time slot is offered to a waitlisted user
+	WaitlistOfferTTLSeconds int
+
+	// OutboxSink is where the outbox relay publishes events, one of log, stdout, file or webhook
+	// OutboxFile is the file for the file sink, OutboxWebhookURL the url the webhook sink posts to
+	OutboxSink                  string
+	OutboxFile                  string
+	OutboxWebhookURL            string
+	OutboxWebhookTimeoutSeconds int
+	// OutboxRelayIntervalSeconds is how often the relay looks for events to publish, up to OutboxBatchSize at a time
+	OutboxRelayIntervalSeconds int
+	OutboxBatchSize            int
+
+	// WebhookDispatchIntervalSeconds is how often queued webhook deliveries are sent, up to WebhookBatchSize at a time
+	// a delivery is tried WebhookMaxAttempts times, waiting up to WebhookTimeoutSeconds for a response
+	WebhookDispatchIntervalSeconds int
+	WebhookBatchSize               int
+	WebhookMaxAttempts             int
+	WebhookTimeoutSeconds          int
+
 	TestDatabaseURL string
 }
 
@@ -51,11 +76,80 @@ func Configure() (*AppConfig, error) {
 		log.Fatal(err)
 	}
 
+	holdTTL, err := strconv.Atoi(getEnv("hold_ttl_seconds", "600"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	maxHoldTTL, err := strconv.Atoi(getEnv("max_hold_ttl_seconds", "3600"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	holdSweepInterval, err := strconv.Atoi(getEnv("hold_sweep_interval_seconds", "60"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	waitlistOfferTTL, err := strconv.Atoi(getEnv("waitlist_offer_ttl_seconds", "1800"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhookTimeout, err := strconv.Atoi(getEnv("outbox_webhook_timeout_seconds", "10"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	relayInterval, err := strconv.Atoi(getEnv("outbox_relay_interval_seconds", "5"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outboxBatchSize, err := strconv.Atoi(getEnv("outbox_batch_size", "100"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhookDispatchInterval, err := strconv.Atoi(getEnv("webhook_dispatch_interval_seconds", "5"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhookBatchSize, err := strconv.Atoi(getEnv("webhook_batch_size", "50"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhookMaxAttempts, err := strconv.Atoi(getEnv("webhook_max_attempts", "10"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhookDeliveryTimeout, err := strconv.Atoi(getEnv("webhook_timeout_seconds", "10"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	c := AppConfig{}
 	c.DatabaseURL = getEnv("database_url", "")
 	c.PostgresMaxOpenConns = maxConns
 	c.PostgresMaxIdleConns = maxIdle
 	c.PostgresMaxConnLifetimeSeconds = maxLifetime
+	c.HoldTTLSeconds = holdTTL
+	c.MaxHoldTTLSeconds = maxHoldTTL
+	c.HoldSweepIntervalSeconds = holdSweepInterval
+	c.WaitlistOfferTTLSeconds = waitlistOfferTTL
+	c.OutboxSink = getEnv("outbox_sink", "log")
+	c.OutboxFile = getEnv("outbox_file", "events.jsonl")
+	c.OutboxWebhookURL = getEnv("outbox_webhook_url", "")
+	c.OutboxWebhookTimeoutSeconds = webhookTimeout
+	c.OutboxRelayIntervalSeconds = relayInterval
+	c.OutboxBatchSize = outboxBatchSize
+	c.WebhookDispatchIntervalSeconds = webhookDispatchInterval
+	c.WebhookBatchSize = webhookBatchSize
+	c.WebhookMaxAttempts = webhookMaxAttempts
+	c.WebhookTimeoutSeconds = webhookDeliveryTimeout
 	c.TestDatabaseURL = getEnv("test_database_url", "")
 
 	c.Log.SetFormatter(&log.JSONFormatter{})
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"log"
+	"net/http"
+)
+
+// internalErrorType is the error type for a batch item that failed because of something on our end
+const internalErrorType = "internal_error"
+
+// CreateAppointmentBatch books several appointments at once. each one is checked the same way as creating a single
+// appointment, the results are in request order with a status and error type for each
+// responds 201 when every appointment is booked, 207 when some are and 422 when none are, or 500 when none are and some
+// failed on our end
+func (a *V1AppointmentsController) CreateAppointmentBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	batch := models.AppointmentBatchRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&batch)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = batch.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check mode and appointments", err)
+		return
+	}
+
+	results := make([]models.AppointmentBatchItemResult, len(batch.Appointments))
+	checked := make([]int, 0, len(batch.Appointments))
+	for i, newAppointment := range batch.Appointments {
+		results[i] = models.AppointmentBatchItemResult{Index: i}
+
+		message, err := a.checkBatchAppointment(ctx, newAppointment)
+		if err != nil {
+			failBatchItem(&results[i], message, err)
+			continue
+		}
+
+		checked = append(checked, i)
+	}
+
+	if batch.Mode == models.AppointmentBatchAllOrNothing {
+		a.createAllOrNothing(ctx, batch.Appointments, checked, results)
+	} else {
+		a.createBestEffort(ctx, batch.Appointments, checked, results)
+	}
+
+	respondModel(ctx, w, batchStatus(results), models.AppointmentBatchResult{Mode: batch.Mode, Results: results})
+	return
+}
+
+// checkBatchAppointment runs the same checks as creating a single appointment, returns the message for the result when one fails
+func (a *V1AppointmentsController) checkBatchAppointment(ctx context.Context, newAppointment models.AppointmentCreateRequest) (string, error) {
+	sessionType, err := a.getSessionType(ctx, newAppointment.SessionTypeID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return "bad request payload, unknown session type", typedBatchError{errType: "unknown_session_type", err: err}
+	}
+
+	if err != nil {
+		return "something bad happened", err
+	}
+
+	schedule, err := a.getWorkingSchedule(ctx, newAppointment.TrainerID, newAppointment.StartsAt, newAppointment.EndsAt)
+	if err != nil {
+		return "something bad happened", err
+	}
+
+	err = validateRequest(newAppointment, schedule, sessionType)
+	if err != nil {
+		return "bad request payload, check times", typedBatchError{errType: "invalid_request", err: err}
+	}
+
+	err = a.checkTrainer(ctx, newAppointment.TrainerID)
+	if isUnprocessableParticipant(err) {
+		return "trainer can't be booked", err
+	}
+
+	if err != nil {
+		return "something bad happened", err
+	}
+
+	return "", nil
+}
+
+// createAllOrNothing books the checked appointments in one transaction, only when every appointment passed its checks
+// otherwise, or when the transaction fails, the appointments that weren't the problem are marked skipped
+func (a *V1AppointmentsController) createAllOrNothing(ctx context.Context, newAppointments []models.AppointmentCreateRequest, checked []int, results []models.AppointmentBatchItemResult) {
+	if len(checked) != len(newAppointments) {
+		skipBatchItems(results)
+		return
+	}
+
+	appointments, err := a.repo.CreateAppointments(ctx, newAppointments)
+	if err != nil {
+		itemErr := repo.BatchItemError{}
+		if !errors.As(err, &itemErr) {
+			for i := range results {
+				failBatchItem(&results[i], "something bad happened", err)
+			}
+
+			return
+		}
+
+		failBatchItem(&results[itemErr.Index], createErrorMessage(itemErr.Err), itemErr.Err)
+		skipBatchItems(results)
+		return
+	}
+
+	for i := range appointments {
+		results[i].Status = models.AppointmentBatchItemCreated
+		results[i].Appointment = &appointments[i]
+	}
+}
+
+// createBestEffort books the checked appointments one at a time, an appointment that can't be booked doesn't stop the rest
+func (a *V1AppointmentsController) createBestEffort(ctx context.Context, newAppointments []models.AppointmentCreateRequest, checked []int, results []models.AppointmentBatchItemResult) {
+	for _, i := range checked {
+		appointment, err := a.repo.CreateAppointment(ctx, newAppointments[i])
+		if err != nil {
+			failBatchItem(&results[i], createErrorMessage(err), err)
+			continue
+		}
+
+		results[i].Status = models.AppointmentBatchItemCreated
+		results[i].Appointment = &appointment
+	}
+}
+
+// createErrorMessage gets the message for an error creating an appointment, the same as creating a single appointment
+func createErrorMessage(err error) string {
+	if errors.As(err, &repo.SlotTakenError{}) {
+		return "time slot is already booked"
+	}
+
+	if isUnprocessableParticipant(err) {
+		return "trainer or user can't be booked"
+	}
+
+	return "something bad happened"
+}
+
+// typedBatchError gives an error without its own type, like a validation error, a type for the batch result
+type typedBatchError struct {
+	errType string
+	err     error
+}
+
+func (e typedBatchError) Error() string {
+	return e.err.Error()
+}
+
+// ErrorType satisfies the errorTyper interface
+func (e typedBatchError) ErrorType() string {
+	return e.errType
+}
+
+// failBatchItem marks the result failed, errors without a type are unexpected, so they're logged and typed internal_error
+func failBatchItem(result *models.AppointmentBatchItemResult, message string, err error) {
+	result.Status = models.AppointmentBatchItemFailed
+	result.Error = message
+	result.ErrorType = internalErrorType
+
+	if typer, ok := errors.Cause(err).(errorTyper); ok {
+		result.ErrorType = typer.ErrorType()
+		return
+	}
+
+	log.Printf("error booking appointment %d in batch: %v\n", result.Index, err)
+}
+
+// skipBatchItems marks every result that hasn't failed as skipped
+func skipBatchItems(results []models.AppointmentBatchItemResult) {
+	for i := range results {
+		if results[i].Status != models.AppointmentBatchItemFailed {
+			results[i].Status = models.AppointmentBatchItemSkipped
+		}
+	}
+}
+
+// batchStatus is 201 when every appointment was booked, 207 when some were and 422 when none were because of the requests
+// when none were booked and any failed on our end, ex. the database is down, it's 500 so it isn't mistaken for bad requests
+func batchStatus(results []models.AppointmentBatchItemResult) int {
+	created := 0
+	internal := false
+	for _, result := range results {
+		if result.Status == models.AppointmentBatchItemCreated {
+			created++
+		}
+
+		if result.Status == models.AppointmentBatchItemFailed && result.ErrorType == internalErrorType {
+			internal = true
+		}
+	}
+
+	switch {
+	case created == len(results):
+		return http.StatusCreated
+	case created == 0 && internal:
+		return http.StatusInternalServerError
+	case created == 0:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusMultiStatus
+	}
+}
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestV1Appointments_CreateAppointmentBatch(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		request []byte
+		aRepo   repo.MockAppointments
+		whRepo  repo.MockWorkingHours
+		toRepo  repo.MockTimeOff
+		stRepo  repo.MockSessionTypes
+		trRepo  repo.MockTrainers
+	}
+
+	trainer := models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true}
+	booked := []models.Appointment{
+		{
+			ID:        1,
+			TrainerID: 1,
+			UserID:    1,
+			StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			ID:        2,
+			TrainerID: 1,
+			UserID:    2,
+			StartsAt:  time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+		},
+	}
+
+	// 3/17/2022 is a thursday, 19:00 UTC is noon pacific and 04:00 UTC is 9pm pacific
+	requestWithInvalid := func(mode string) []byte {
+		return []byte(`{
+			"mode": "` + mode + `",
+			"appointments": [
+				{"trainer_id": 1, "user_id": 1, "starts_at": "2022-03-17T19:00:00Z", "ends_at": "2022-03-17T19:30:00Z"},
+				{"trainer_id": 1, "user_id": 2, "starts_at": "2022-03-18T04:00:00Z", "ends_at": "2022-03-18T04:30:00Z"}
+			]
+		}`)
+	}
+
+	validRequest := []byte(`{
+		"appointments": [
+			{"trainer_id": 1, "user_id": 1, "starts_at": "2022-03-17T19:00:00Z", "ends_at": "2022-03-17T19:30:00Z"},
+			{"trainer_id": 1, "user_id": 2, "starts_at": "2022-03-17T19:30:00Z", "ends_at": "2022-03-17T20:00:00Z"}
+		]
+	}`)
+
+	tests := []struct {
+		name         string
+		args         args
+		response     int
+		errMsg       string
+		wantStatuses []models.AppointmentBatchItemStatus
+		wantTypes    []string
+	}{
+		{
+			name: "happy path all or nothing",
+			args: args{
+				ctx:     context.TODO(),
+				request: validRequest,
+				aRepo:   repo.MockAppointments{CreateAppointmentsBatchResponse: booked},
+				trRepo:  repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:     http.StatusCreated,
+			wantStatuses: []models.AppointmentBatchItemStatus{models.AppointmentBatchItemCreated, models.AppointmentBatchItemCreated},
+			wantTypes:    []string{"", ""},
+		},
+		{
+			name: "all or nothing skips the rest when one is invalid",
+			args: args{
+				ctx:     context.TODO(),
+				request: requestWithInvalid("all_or_nothing"),
+				aRepo:   repo.MockAppointments{CreateAppointmentsBatchResponse: booked},
+				trRepo:  repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:     http.StatusUnprocessableEntity,
+			wantStatuses: []models.AppointmentBatchItemStatus{models.AppointmentBatchItemSkipped, models.AppointmentBatchItemFailed},
+			wantTypes:    []string{"", "invalid_request"},
+		},
+		{
+			name: "all or nothing booked since checking",
+			args: args{
+				ctx:     context.TODO(),
+				request: validRequest,
+				aRepo: repo.MockAppointments{
+					CreateAppointmentsBatchErr: repo.BatchItemError{
+						Index: 1,
+						Err: repo.SlotTakenError{
+							TrainerID: 1,
+							StartsAt:  time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:     http.StatusUnprocessableEntity,
+			wantStatuses: []models.AppointmentBatchItemStatus{models.AppointmentBatchItemSkipped, models.AppointmentBatchItemFailed},
+			wantTypes:    []string{"", "slot_taken"},
+		},
+		{
+			name: "best effort books the valid appointments",
+			args: args{
+				ctx:     context.TODO(),
+				request: requestWithInvalid("best_effort"),
+				aRepo:   repo.MockAppointments{CreateAppointmentsResponse: booked[0]},
+				trRepo:  repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:     http.StatusMultiStatus,
+			wantStatuses: []models.AppointmentBatchItemStatus{models.AppointmentBatchItemCreated, models.AppointmentBatchItemFailed},
+			wantTypes:    []string{"", "invalid_request"},
+		},
+		{
+			name: "fail all or nothing on the database",
+			args: args{
+				ctx:     context.TODO(),
+				request: validRequest,
+				aRepo:   repo.MockAppointments{CreateAppointmentsBatchErr: errors.New("connection refused")},
+				trRepo:  repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:     http.StatusInternalServerError,
+			wantStatuses: []models.AppointmentBatchItemStatus{models.AppointmentBatchItemFailed, models.AppointmentBatchItemFailed},
+			wantTypes:    []string{"internal_error", "internal_error"},
+		},
+		{
+			name: "fail best effort on the database",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"mode": "best_effort",
+					"appointments": [
+						{"trainer_id": 1, "user_id": 1, "starts_at": "2022-03-17T19:00:00Z", "ends_at": "2022-03-17T19:30:00Z"},
+						{"trainer_id": 1, "user_id": 2, "starts_at": "2022-03-17T19:30:00Z", "ends_at": "2022-03-17T20:00:00Z"}
+					]
+				}`),
+				aRepo:  repo.MockAppointments{CreateAppointmentsErr: errors.New("connection refused")},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:     http.StatusInternalServerError,
+			wantStatuses: []models.AppointmentBatchItemStatus{models.AppointmentBatchItemFailed, models.AppointmentBatchItemFailed},
+			wantTypes:    []string{"internal_error", "internal_error"},
+		},
+		{
+			name: "best effort inactive trainer",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"mode": "best_effort",
+					"appointments": [
+						{"trainer_id": 3, "user_id": 1, "starts_at": "2022-03-17T19:00:00Z", "ends_at": "2022-03-17T19:30:00Z"}
+					]
+				}`),
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 3, Name: "Trainer 3", Email: "trainer3@example.com", Active: false},
+				},
+			},
+			response:     http.StatusUnprocessableEntity,
+			wantStatuses: []models.AppointmentBatchItemStatus{models.AppointmentBatchItemFailed},
+			wantTypes:    []string{"inactive_trainer"},
+		},
+		{
+			name: "fail unknown mode",
+			args: args{
+				ctx:     context.TODO(),
+				request: requestWithInvalid("some"),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check mode and appointments",
+		},
+		{
+			name: "fail no appointments",
+			args: args{
+				ctx:     context.TODO(),
+				request: []byte(`{"appointments": []}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check mode and appointments",
+		},
+	}
+
+	endpoint := "/appointments/batch"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
+
+			handler := http.HandlerFunc(appointmentsController.CreateAppointmentBatch)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response == http.StatusBadRequest {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			result := models.AppointmentBatchResult{}
+			err = json.Unmarshal(response.Body.Bytes(), &result)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotStatuses := make([]models.AppointmentBatchItemStatus, 0)
+			gotTypes := make([]string, 0)
+			for i, item := range result.Results {
+				assert.Equal(t, i, item.Index)
+				assert.Equal(t, item.Status == models.AppointmentBatchItemCreated, item.Appointment != nil)
+				gotStatuses = append(gotStatuses, item.Status)
+				gotTypes = append(gotTypes, item.ErrorType)
+			}
+
+			assert.Equal(t, tt.wantStatuses, gotStatuses)
+			assert.Equal(t, tt.wantTypes, gotTypes)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+)
+
+// seriesConflictError is returned when occurrences of a series can't be booked, the conflicts are in the error details
+type seriesConflictError struct {
+	conflicts []models.SeriesConflict
+}
+
+func (e seriesConflictError) Error() string {
+	return "appointment series has conflicts"
+}
+
+// ErrorType satisfies the errorTyper interface
+func (e seriesConflictError) ErrorType() string {
+	return "series_conflict"
+}
+
+// ErrorDetails satisfies the errorDetailer interface
+func (e seriesConflictError) ErrorDetails() map[string]interface{} {
+	return map[string]interface{}{
+		"conflicts": e.conflicts,
+	}
+}
+
+// CreateAppointmentSeries books a recurring appointment. every occurrence is checked against the trainer's working hours,
+// time off and bookings first, any conflict rejects the whole series unless skip_conflicts is set
+func (a *V1AppointmentsController) CreateAppointmentSeries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	newSeries := models.AppointmentSeriesCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&newSeries)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = newSeries.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check frequency, count and until", err)
+		return
+	}
+
+	sessionType, err := a.getSessionType(ctx, newSeries.SessionTypeID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			// not passing err along, respondError would turn sql.ErrNoRows into a 404
+			respondError(ctx, w, http.StatusBadRequest, "bad request payload, unknown session type", errors.Errorf("unknown session type %d", newSeries.SessionTypeID))
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	// the length and start minute are the same for every occurrence, so they're checked once
+	if newSeries.EndsAt.Sub(newSeries.StartsAt) != sessionType.Duration() || !isValidSlot(newSeries.StartsAt, sessionType.Granularity()) {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check times", errors.Errorf("invalid time slot, must be %d minutes", sessionType.DurationMinutes))
+		return
+	}
+
+	err = a.checkTrainer(ctx, newSeries.TrainerID)
+	if err != nil {
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer can't be booked", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	occurrences := newSeries.Occurrences()
+	firstStartsAt, lastEndsAt := occurrences[0].StartsAt, occurrences[len(occurrences)-1].EndsAt
+
+	schedule, err := a.getWorkingSchedule(ctx, newSeries.TrainerID, firstStartsAt, lastEndsAt)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	booked, err := a.repo.GetBookedTimeSlots(ctx, newSeries.TrainerID, firstStartsAt, lastEndsAt)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	free, conflicts := findSeriesConflicts(occurrences, schedule, booked)
+	if len(free) == 0 || (len(conflicts) > 0 && !newSeries.SkipConflicts) {
+		respondError(ctx, w, http.StatusConflict, "appointment series has conflicts", seriesConflictError{conflicts: conflicts})
+		return
+	}
+
+	result, err := a.repo.CreateAppointmentSeries(ctx, newSeries, free)
+	if err != nil {
+		if errors.As(err, &repo.SlotTakenError{}) {
+			respondError(ctx, w, http.StatusConflict, "time slot is already booked", err)
+			return
+		}
+
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer or user can't be booked", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	result.Conflicts = conflicts
+	respondModel(ctx, w, http.StatusCreated, result)
+	return
+}
+
+func (a *V1AppointmentsController) GetAppointmentSeries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid appointment series ID", err)
+		return
+	}
+
+	result, err := a.repo.GetAppointmentSeries(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, result)
+	return
+}
+
+// findSeriesConflicts splits the occurrences into the ones that can be booked and the ones that conflict with the
+// trainer's working hours, time off or another booking
+func findSeriesConflicts(occurrences []models.TimeSlot, schedule models.WorkingSchedule, booked []models.TimeSlot) ([]models.TimeSlot, []models.SeriesConflict) {
+	free := make([]models.TimeSlot, 0, len(occurrences))
+	conflicts := make([]models.SeriesConflict, 0)
+	for _, occurrence := range occurrences {
+		if !schedule.Contains(occurrence.StartsAt, occurrence.EndsAt) {
+			conflicts = append(conflicts, models.SeriesConflict{
+				StartsAt: occurrence.StartsAt,
+				EndsAt:   occurrence.EndsAt,
+				Type:     "unavailable",
+				Message:  "outside the trainer's working hours or during time off",
+			})
+			continue
+		}
+
+		if isBooked(booked, occurrence.StartsAt, occurrence.EndsAt) {
+			conflicts = append(conflicts, models.SeriesConflict{
+				StartsAt: occurrence.StartsAt,
+				EndsAt:   occurrence.EndsAt,
+				Type:     "slot_taken",
+				Message:  "trainer is already booked",
+			})
+			continue
+		}
+
+		free = append(free, occurrence)
+	}
+
+	return free, conflicts
+}
@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestV1Appointments_CreateAppointmentSeries(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		request []byte
+		aRepo   repo.MockAppointments
+		whRepo  repo.MockWorkingHours
+		toRepo  repo.MockTimeOff
+		stRepo  repo.MockSessionTypes
+		trRepo  repo.MockTrainers
+	}
+
+	trainer := models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true}
+
+	// 3/17/2022 is a thursday, 19:00 UTC is noon pacific
+	weeklyRequest := []byte(`{
+		"trainer_id": 1,
+		"user_id": 1,
+		"starts_at": "2022-03-17T19:00:00Z",
+		"ends_at": "2022-03-17T19:30:00Z",
+		"frequency": "weekly",
+		"count": 3
+	}`)
+
+	bookedSecondWeek := []models.TimeSlot{
+		{
+			TrainerID: 1,
+			StartsAt:  time.Date(2022, 03, 24, 19, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 24, 19, 30, 0, 0, time.UTC),
+		},
+	}
+
+	tests := []struct {
+		name          string
+		args          args
+		response      int
+		errMsg        string
+		errType       string
+		wantConflicts int
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx:     context.TODO(),
+				request: weeklyRequest,
+				aRepo: repo.MockAppointments{
+					GetBookedTimeSlotsResponse: []models.TimeSlot{},
+					CreateAppointmentSeriesResponse: models.AppointmentSeriesResult{
+						Series: models.AppointmentSeries{ID: 1, TrainerID: 1, UserID: 1, Frequency: models.SeriesFrequencyWeekly},
+					}},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail occurrence already booked",
+			args: args{
+				ctx:     context.TODO(),
+				request: weeklyRequest,
+				aRepo: repo.MockAppointments{
+					GetBookedTimeSlotsResponse: bookedSecondWeek,
+				},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:      http.StatusConflict,
+			errMsg:        "appointment series has conflicts",
+			errType:       "series_conflict",
+			wantConflicts: 1,
+		},
+		{
+			name: "happy path skip conflicts",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"user_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z",
+					"frequency": "weekly",
+					"count": 3,
+					"skip_conflicts": true
+				}`),
+				aRepo: repo.MockAppointments{
+					GetBookedTimeSlotsResponse: bookedSecondWeek,
+					CreateAppointmentSeriesResponse: models.AppointmentSeriesResult{
+						Series: models.AppointmentSeries{ID: 1, TrainerID: 1, UserID: 1, Frequency: models.SeriesFrequencyWeekly},
+					}},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:      http.StatusCreated,
+			wantConflicts: 1,
+		},
+		{
+			name: "fail booked since checking",
+			args: args{
+				ctx:     context.TODO(),
+				request: weeklyRequest,
+				aRepo: repo.MockAppointments{
+					GetBookedTimeSlotsResponse: []models.TimeSlot{},
+					CreateAppointmentSeriesErr: repo.SlotTakenError{
+						TrainerID: 1,
+						StartsAt:  time.Date(2022, 03, 24, 19, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 24, 19, 30, 0, 0, time.UTC),
+					},
+				},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusConflict,
+			errMsg:   "time slot is already booked",
+			errType:  "slot_taken",
+		},
+		{
+			name: "fail count and until",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"user_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z",
+					"frequency": "weekly",
+					"count": 3,
+					"until": "2022-06-01T00:00:00Z"
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check frequency, count and until",
+		},
+		{
+			name: "fail unknown frequency",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"user_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z",
+					"frequency": "daily",
+					"count": 3
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check frequency, count and until",
+		},
+		{
+			name: "fail session length",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"user_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T20:00:00Z",
+					"frequency": "biweekly",
+					"count": 3
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "fail inactive trainer",
+			args: args{
+				ctx:     context.TODO(),
+				request: weeklyRequest,
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: false},
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer can't be booked",
+			errType:  "inactive_trainer",
+		},
+	}
+
+	endpoint := "/appointment-series"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
+
+			handler := http.HandlerFunc(appointmentsController.CreateAppointmentSeries)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := struct {
+					Error   string `json:"error"`
+					Type    string `json:"type"`
+					Details struct {
+						Conflicts []models.SeriesConflict `json:"conflicts"`
+					} `json:"details"`
+				}{}
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp.Error)
+				assert.Equal(t, tt.errType, resp.Type)
+				assert.Len(t, resp.Details.Conflicts, tt.wantConflicts)
+				return
+			}
+
+			result := models.AppointmentSeriesResult{}
+			err = json.Unmarshal(response.Body.Bytes(), &result)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, result.Conflicts, tt.wantConflicts)
+		})
+	}
+}
+
+func TestV1Appointments_GetAppointmentSeries(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		id    string
+		aRepo repo.MockAppointments
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					GetAppointmentSeriesResponse: models.AppointmentSeriesResult{
+						Series: models.AppointmentSeries{ID: 1, TrainerID: 1, UserID: 1, Frequency: models.SeriesFrequencyWeekly},
+					}},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "100",
+				aRepo: repo.MockAppointments{
+					GetAppointmentSeriesErr: errors.Wrap(sql.ErrNoRows, "error getting appointment series"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/appointment-series/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &repo.MockWorkingHours{}, &repo.MockTimeOff{}, &repo.MockSessionTypes{}, &repo.MockTrainers{}, &repo.MockWaitlist{})
+
+			handler := http.HandlerFunc(appointmentsController.GetAppointmentSeries)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func Test_findSeriesConflicts(t *testing.T) {
+	type args struct {
+		series  models.AppointmentSeriesCreateRequest
+		booked  []models.TimeSlot
+		timeOff []models.TimeOff
+	}
+
+	until := time.Date(2022, 04, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		args          args
+		wantFree      []time.Time
+		wantConflicts []string
+	}{
+		{
+			name: "weekly keeps the local time across daylight saving",
+			args: args{
+				// 3/10/2022 is a thursday, 20:00 UTC is noon pacific before daylight saving starts on 3/13
+				series: models.AppointmentSeriesCreateRequest{
+					TrainerID: 1,
+					StartsAt:  time.Date(2022, 03, 10, 20, 0, 0, 0, time.UTC),
+					EndsAt:    time.Date(2022, 03, 10, 20, 30, 0, 0, time.UTC),
+					Frequency: models.SeriesFrequencyWeekly,
+					Count:     2,
+				},
+			},
+			wantFree: []time.Time{
+				time.Date(2022, 03, 10, 20, 0, 0, 0, time.UTC),
+				time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+			},
+			wantConflicts: []string{},
+		},
+		{
+			name: "biweekly until a date with booked and time off conflicts",
+			args: args{
+				series: models.AppointmentSeriesCreateRequest{
+					TrainerID: 1,
+					StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+					EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					Frequency: models.SeriesFrequencyBiweekly,
+					Until:     &until,
+				},
+				booked: []models.TimeSlot{
+					{TrainerID: 1, StartsAt: time.Date(2022, 03, 31, 19, 15, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 31, 20, 0, 0, 0, time.UTC)},
+				},
+				timeOff: []models.TimeOff{
+					{StartsAt: time.Date(2022, 04, 14, 0, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 04, 15, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+			wantFree: []time.Time{
+				time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+			},
+			wantConflicts: []string{"slot_taken", "unavailable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := models.NewWorkingSchedule(1, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			schedule = schedule.WithTimeOff(tt.args.timeOff)
+			free, conflicts := findSeriesConflicts(tt.args.series.Occurrences(), schedule, tt.args.booked)
+
+			gotFree := make([]time.Time, 0)
+			for _, occurrence := range free {
+				gotFree = append(gotFree, occurrence.StartsAt)
+			}
+
+			gotConflicts := make([]string, 0)
+			for _, conflict := range conflicts {
+				gotConflicts = append(gotConflicts, conflict.Type)
+			}
+
+			assert.Equal(t, tt.wantFree, gotFree)
+			assert.Equal(t, tt.wantConflicts, gotConflicts)
+		})
+	}
+}
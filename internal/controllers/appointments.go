@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -10,29 +12,80 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"time"
 )
 
+const (
+	// defaultPageLimit is how many scheduled appointments are returned when there is no limit param
+	defaultPageLimit = 100
+	maxPageLimit     = 500
+
+	// cancelScopeThis and cancelScopeFollowing are the scope param values for canceling an appointment in a series
+	cancelScopeThis      = "this"
+	cancelScopeFollowing = "following"
+
+	// defaultNextAvailableCount is how many slots the next available search returns when there is no count param
+	defaultNextAvailableCount = 5
+	maxNextAvailableCount     = 50
+
+	// defaultHorizonDays is how far ahead the next available search looks when there is no horizon_days param
+	defaultHorizonDays = 14
+	maxHorizonDays     = 90
+
+	// nextAvailableWindow is how much time the next available search loads at once, it stops at the first window with enough slots
+	nextAvailableWindow = 7 * 24 * time.Hour
+)
+
 type V1AppointmentsController struct {
 	config *configuration.AppConfig
 	repo   repo.AppointmentsRepository
+	whRepo repo.WorkingHoursRepository
+	toRepo repo.TimeOffRepository
+	stRepo repo.SessionTypesRepository
+	trRepo repo.TrainersRepository
+	wlRepo repo.WaitlistRepository
 }
 
-func NewV1AppointmentsController(c *configuration.AppConfig, aRepo repo.AppointmentsRepository) V1AppointmentsController {
+func NewV1AppointmentsController(c *configuration.AppConfig, aRepo repo.AppointmentsRepository, whRepo repo.WorkingHoursRepository, toRepo repo.TimeOffRepository, stRepo repo.SessionTypesRepository, trRepo repo.TrainersRepository, wlRepo repo.WaitlistRepository) V1AppointmentsController {
 	return V1AppointmentsController{
 		config: c,
 		repo:   aRepo,
+		whRepo: whRepo,
+		toRepo: toRepo,
+		stRepo: stRepo,
+		trRepo: trRepo,
+		wlRepo: wlRepo,
 	}
 }
 
 func (a *V1AppointmentsController) RegisterRoutes(v1 *mux.Router) {
 	v1.Path("/appointments/available").Name("GetAvailableAppointments").Handler(http.HandlerFunc(a.ListAvailableAppointments)).Methods(http.MethodGet)
+	v1.Path("/appointments/next-available").Name("GetNextAvailableAppointments").Handler(http.HandlerFunc(a.ListNextAvailableAppointments)).Methods(http.MethodGet)
 	v1.Path("/appointments/scheduled").Name("GetScheduledAppointments").Handler(http.HandlerFunc(a.ListScheduledAppointments)).Methods(http.MethodGet)
+	v1.Path("/appointments/batch").Name("CreateAppointmentBatch").Handler(http.HandlerFunc(a.CreateAppointmentBatch)).Methods(http.MethodPost)
 	v1.Path("/appointments").Name("CreateAppointments").Handler(http.HandlerFunc(a.CreateAppointment)).Methods(http.MethodPost)
+	v1.Path("/appointments/{id:[0-9]+}").Name("GetAppointment").Handler(http.HandlerFunc(a.GetAppointment)).Methods(http.MethodGet)
+	v1.Path("/appointments/{id:[0-9]+}").Name("RescheduleAppointment").Handler(http.HandlerFunc(a.RescheduleAppointment)).Methods(http.MethodPatch)
+	v1.Path("/appointments/{id:[0-9]+}/cancel").Name("CancelAppointment").Handler(http.HandlerFunc(a.CancelAppointment)).Methods(http.MethodPost)
+	v1.Path("/appointment-series").Name("CreateAppointmentSeries").Handler(http.HandlerFunc(a.CreateAppointmentSeries)).Methods(http.MethodPost)
+	v1.Path("/appointment-series/{id:[0-9]+}").Name("GetAppointmentSeries").Handler(http.HandlerFunc(a.GetAppointmentSeries)).Methods(http.MethodGet)
+	v1.Path("/users/{id:[0-9]+}/appointments").Name("GetUserAppointments").Handler(http.HandlerFunc(a.ListUserAppointments)).Methods(http.MethodGet)
 }
 
+// CreateAppointment books an appointment, with an Idempotency-Key header a retry gets the first response instead of booking again
 func (a *V1AppointmentsController) CreateAppointment(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key != "" {
+		a.idempotent(w, r, key, a.createAppointment)
+		return
+	}
+
+	a.createAppointment(w, r)
+}
+
+func (a *V1AppointmentsController) createAppointment(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	newAppointment := models.AppointmentCreateRequest{}
 
@@ -42,23 +95,317 @@ func (a *V1AppointmentsController) CreateAppointment(w http.ResponseWriter, r *h
 		return
 	}
 
-	err = validateRequest(newAppointment)
+	sessionType, err := a.getSessionType(ctx, newAppointment.SessionTypeID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			// not passing err along, respondError would turn sql.ErrNoRows into a 404
+			respondError(ctx, w, http.StatusBadRequest, "bad request payload, unknown session type", errors.Errorf("unknown session type %d", newAppointment.SessionTypeID))
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	schedule, err := a.getWorkingSchedule(ctx, newAppointment.TrainerID, newAppointment.StartsAt, newAppointment.EndsAt)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	err = validateRequest(newAppointment, schedule, sessionType)
 	if err != nil {
 		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check times", err)
 		return
 	}
 
+	err = a.checkTrainer(ctx, newAppointment.TrainerID)
+	if err != nil {
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer can't be booked", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
 	appointment, err := a.repo.CreateAppointment(ctx, newAppointment)
 	if err != nil {
+		if errors.As(err, &repo.SlotTakenError{}) {
+			respondError(ctx, w, http.StatusConflict, "time slot is already booked", err)
+			return
+		}
+
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer or user can't be booked", err)
+			return
+		}
+
 		respondError(ctx, w, http.StatusInternalServerError, err.Error(), err)
 		return
 	}
 
+	w.Header().Set("ETag", appointment.ETag())
 	respondModel(ctx, w, http.StatusCreated, appointment)
 	return
 }
 
-func validateRequest(appointment models.AppointmentCreateRequest) error {
+func (a *V1AppointmentsController) GetAppointment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid appointment ID", err)
+		return
+	}
+
+	appointment, err := a.repo.GetAppointment(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	w.Header().Set("ETag", appointment.ETag())
+	respondModel(ctx, w, http.StatusOK, appointment)
+	return
+}
+
+// CancelAppointment cancels just the appointment, or with scope=following the appointment and the rest of its series
+func (a *V1AppointmentsController) CancelAppointment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid appointment ID", err)
+		return
+	}
+
+	scope, err := getCancelScope(r.URL.Query())
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid scope", err)
+		return
+	}
+
+	version, ok := a.getVersion(w, r)
+	if !ok {
+		return
+	}
+
+	if scope == cancelScopeFollowing {
+		a.cancelFollowingAppointments(w, r, id, version)
+		return
+	}
+
+	appointment, err := a.repo.CancelAppointment(ctx, id, version)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrAlreadyCanceled {
+			respondError(ctx, w, http.StatusConflict, "appointment already canceled", err)
+			return
+		}
+
+		if errors.Cause(err) == repo.ErrStaleVersion {
+			respondError(ctx, w, http.StatusPreconditionFailed, "appointment was changed, get it again", err)
+			return
+		}
+
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	a.offerFreedSlot(ctx, appointment.TrainerID, appointment.StartsAt, appointment.EndsAt)
+
+	w.Header().Set("ETag", appointment.ETag())
+	respondModel(ctx, w, http.StatusOK, appointment)
+	return
+}
+
+// cancelFollowingAppointments cancels the appointment and every later appointment in its series
+func (a *V1AppointmentsController) cancelFollowingAppointments(w http.ResponseWriter, r *http.Request, id int64, version int64) {
+	ctx := r.Context()
+
+	appointments, err := a.repo.CancelFollowingAppointments(ctx, id, version)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrAlreadyCanceled {
+			respondError(ctx, w, http.StatusConflict, "appointment already canceled", err)
+			return
+		}
+
+		if errors.Cause(err) == repo.ErrStaleVersion {
+			respondError(ctx, w, http.StatusPreconditionFailed, "appointment was changed, get it again", err)
+			return
+		}
+
+		if errors.Cause(err) == repo.ErrNotInSeries {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "appointment is not part of a series", err)
+			return
+		}
+
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	for _, appointment := range appointments {
+		a.offerFreedSlot(ctx, appointment.TrainerID, appointment.StartsAt, appointment.EndsAt)
+	}
+
+	respondModel(ctx, w, http.StatusOK, appointments)
+	return
+}
+
+// offerFreedSlot offers a freed time slot to the waitlist, the offers' events are published from the outbox
+// the time slot was already freed, so errors are only logged, the hold sweeper offers it again
+func (a *V1AppointmentsController) offerFreedSlot(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time) {
+	ttl := time.Duration(a.config.WaitlistOfferTTLSeconds) * time.Second
+	_, err := a.wlRepo.OfferFreedSlot(ctx, trainerID, startsAt, endsAt, ttl)
+	if err != nil {
+		log.Printf("error offering trainer %d time slot %s to the waitlist: %v\n", trainerID, startsAt, err)
+	}
+}
+
+func (a *V1AppointmentsController) RescheduleAppointment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid appointment ID", err)
+		return
+	}
+
+	version, ok := a.getVersion(w, r)
+	if !ok {
+		return
+	}
+
+	reschedule := models.AppointmentRescheduleRequest{}
+	err = json.NewDecoder(r.Body).Decode(&reschedule)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	if reschedule.TrainerID < 0 {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", errors.New("invalid trainer Id"))
+		return
+	}
+
+	// the appointment keeps its session type, and its trainer unless a new one is given
+	current, err := a.repo.GetAppointment(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	trainerID := reschedule.TrainerID
+	if trainerID == 0 {
+		trainerID = current.TrainerID
+	} else {
+		err = a.checkTrainer(ctx, trainerID)
+		if err != nil {
+			if isUnprocessableParticipant(err) {
+				respondError(ctx, w, http.StatusUnprocessableEntity, "trainer can't be booked", err)
+				return
+			}
+
+			respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+			return
+		}
+	}
+
+	var sessionTypeID int64
+	if current.SessionTypeID != nil {
+		sessionTypeID = *current.SessionTypeID
+	}
+
+	sessionType, err := a.getSessionType(ctx, sessionTypeID)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	schedule, err := a.getWorkingSchedule(ctx, trainerID, reschedule.StartsAt, reschedule.EndsAt)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	err = validateTimeSlot(reschedule.StartsAt, reschedule.EndsAt, schedule, sessionType)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check times", err)
+		return
+	}
+
+	appointment, err := a.repo.RescheduleAppointment(ctx, id, reschedule, version)
+	if err != nil {
+		switch {
+		case errors.As(err, &repo.SlotTakenError{}):
+			respondError(ctx, w, http.StatusConflict, "time slot is already booked", err)
+		case errors.Cause(err) == repo.ErrAlreadyCanceled:
+			respondError(ctx, w, http.StatusConflict, "appointment already canceled", err)
+		case errors.Cause(err) == repo.ErrStaleVersion:
+			respondError(ctx, w, http.StatusPreconditionFailed, "appointment was changed, get it again", err)
+		case isUnprocessableParticipant(err):
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer can't be booked", err)
+		default:
+			respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", appointment.ETag())
+	respondModel(ctx, w, http.StatusOK, appointment)
+	return
+}
+
+// getVersion gets the appointment version the client has from If-Match, responding with an error when it can't
+func (a *V1AppointmentsController) getVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	version, err := getIfMatchVersion(r)
+	if err == errIfMatchRequired {
+		respondError(r.Context(), w, http.StatusPreconditionRequired, "If-Match header is required", err)
+		return 0, false
+	}
+
+	if err != nil {
+		respondError(r.Context(), w, http.StatusBadRequest, "invalid If-Match header", err)
+		return 0, false
+	}
+
+	return version, true
+}
+
+// checkTrainer makes sure the trainer exists and is active, returns repo.ErrUnknownTrainer or repo.ErrInactiveTrainer if not
+func (a *V1AppointmentsController) checkTrainer(ctx context.Context, trainerID int64) error {
+	trainer, err := a.trRepo.GetTrainer(ctx, trainerID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return repo.ErrUnknownTrainer
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !trainer.Active {
+		return repo.ErrInactiveTrainer
+	}
+
+	return nil
+}
+
+// isUnprocessableParticipant checks if the error is from an unknown or inactive trainer or user, which is a 422
+func isUnprocessableParticipant(err error) bool {
+	switch errors.Cause(err) {
+	case repo.ErrUnknownTrainer, repo.ErrInactiveTrainer, repo.ErrUnknownUser:
+		return true
+	}
+
+	return false
+}
+
+func validateRequest(appointment models.AppointmentCreateRequest, schedule models.WorkingSchedule, sessionType models.SessionType) error {
 	// validate user ID is not 0
 	if appointment.UserID == 0 {
 		return errors.New("invalid user Id")
@@ -68,7 +415,7 @@ func validateRequest(appointment models.AppointmentCreateRequest) error {
 		return errors.New("invalid user Id")
 	}
 
-	err := validateTimeSlot(appointment.StartsAt, appointment.EndsAt)
+	err := validateTimeSlot(appointment.StartsAt, appointment.EndsAt, schedule, sessionType)
 	if err != nil {
 		return err
 	}
@@ -76,42 +423,82 @@ func validateRequest(appointment models.AppointmentCreateRequest) error {
 	return nil
 }
 
-func validateTimeSlot(startsAt time.Time, endsAt time.Time) error {
-	// Calling LoadLocation
-	// method with its parameter
-	loc, err := time.LoadLocation("America/Los_Angeles")
-	if err != nil {
-		return errors.New("could not load timezone location")
+func validateTimeSlot(startsAt time.Time, endsAt time.Time, schedule models.WorkingSchedule, sessionType models.SessionType) error {
+	if endsAt.Sub(startsAt) != sessionType.Duration() {
+		return errors.Errorf("invalid time slot, must be %d minutes", sessionType.DurationMinutes)
 	}
 
-	if endsAt.Sub(startsAt).Minutes() != 30 {
-		return errors.New("invalid time slot, must be 30 minutes")
-	}
-
-	if !isValidSlot(startsAt) && !startsWithinBusinessHours(startsAt, loc) {
+	if !isValidSlot(startsAt, sessionType.Granularity()) || !schedule.Contains(startsAt, endsAt) {
 		return errors.New("invalid start/end datetime ")
 	}
 
 	return nil
 }
 
-func startsWithinBusinessHours(startsAt time.Time, loc *time.Location) bool {
-	// earliest start is 8am, latest start is 4pm and latest start is 4:30pm based on previous conditions
-	pacificStart := startsAt.In(loc)
-	weekday := pacificStart.Weekday()
-	hour := pacificStart.Hour()
-	log.Println("checking business hours")
+// getSessionType gets the session type, an ID of 0 is the default 30-minute session
+func (a *V1AppointmentsController) getSessionType(ctx context.Context, sessionTypeID int64) (models.SessionType, error) {
+	if sessionTypeID == 0 {
+		return models.DefaultSessionType, nil
+	}
 
-	log.Printf("weekday: %s\n", weekday)
-	log.Printf("hour: %d\n", hour)
-	return hour >= 8 && hour <= 16 && weekday != time.Saturday && weekday != time.Sunday
+	return a.stRepo.GetSessionType(ctx, sessionTypeID)
 }
 
-func isValidSlot(time time.Time) bool {
-	return time.Minute() != 0 && time.Minute() != 30 && time.Second() != 0 && time.Nanosecond() != 0
+// getWorkingSchedule gets the trainer's working hours and any time off between startsAt and endsAt
+// trainers without working hours work the default business hours
+func (a *V1AppointmentsController) getWorkingSchedule(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time) (models.WorkingSchedule, error) {
+	hours := make([]models.WorkingHours, 0)
+	if trainerID != 0 {
+		var err error
+		hours, err = a.whRepo.ListWorkingHours(ctx, trainerID)
+		if err != nil {
+			return models.WorkingSchedule{}, err
+		}
+	}
+
+	schedule, err := models.NewWorkingSchedule(trainerID, hours)
+	if err != nil {
+		return models.WorkingSchedule{}, err
+	}
+
+	timeOff, err := a.toRepo.ListTimeOffForTrainer(ctx, trainerID, startsAt, endsAt)
+	if err != nil {
+		return models.WorkingSchedule{}, err
+	}
+
+	return schedule.WithTimeOff(timeOff), nil
+}
+
+// isValidSlot checks the time starts on the granularity, ex. on 00 and 30 for a 30-minute granularity
+func isValidSlot(t time.Time, granularity time.Duration) bool {
+	return t.Truncate(granularity).Equal(t)
 }
 
 func (a *V1AppointmentsController) ListScheduledAppointments(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.URL.Query())
+	if err != nil {
+		respondError(r.Context(), w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	a.listScheduledAppointments(w, r, userID)
+	return
+}
+
+// ListUserAppointments lists a user's appointments, it takes the same params as ListScheduledAppointments
+func (a *V1AppointmentsController) ListUserAppointments(w http.ResponseWriter, r *http.Request) {
+	userID, err := getPathID(r, "id")
+	if err != nil {
+		respondError(r.Context(), w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	a.listScheduledAppointments(w, r, userID)
+	return
+}
+
+// listScheduledAppointments responds with a page of scheduled appointments using the query params, a user ID of 0 is every user
+func (a *V1AppointmentsController) listScheduledAppointments(w http.ResponseWriter, r *http.Request, userID int64) {
 	ctx := r.Context()
 	queryParams := r.URL.Query()
 
@@ -127,13 +514,53 @@ func (a *V1AppointmentsController) ListScheduledAppointments(w http.ResponseWrit
 		return
 	}
 
-	appointments, err := a.repo.GetScheduledAppointments(ctx, trainerID, startsAt, endsAt)
+	status, err := getStatus(queryParams)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid status", err)
+		return
+	}
+
+	sort, err := getSort(queryParams)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid sort", err)
+		return
+	}
+
+	limit, err := getLimit(queryParams)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid limit", err)
+		return
+	}
+
+	cursor, err := getCursor(queryParams, sort)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid cursor", err)
+		return
+	}
+
+	// get one extra appointment to know if there is another page
+	appointments, err := a.repo.GetScheduledAppointments(ctx, models.AppointmentsFilter{
+		TrainerID: trainerID,
+		UserID:    userID,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		Status:    status,
+		Sort:      sort,
+		Limit:     limit + 1,
+		Cursor:    cursor,
+	})
 	if err != nil {
 		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
 		return
 	}
 
-	respondModel(ctx, w, http.StatusOK, appointments)
+	page := models.AppointmentsPage{Appointments: appointments}
+	if len(appointments) > limit {
+		page.Appointments = appointments[:limit]
+		page.NextCursor = models.NewAppointmentsCursor(sort, page.Appointments[limit-1]).Encode()
+	}
+
+	respondModel(ctx, w, http.StatusOK, page)
 	return
 }
 
@@ -158,62 +585,328 @@ func (a *V1AppointmentsController) ListAvailableAppointments(w http.ResponseWrit
 		return
 	}
 
-	timeSlots, err := a.repo.GetScheduledAppointmentsAsTimeSlots(ctx, trainerID, startsAt, endsAt)
+	sessionType, err := a.getAvailabilitySessionType(ctx, queryParams)
 	if err != nil {
-		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		if errors.Cause(err) == sql.ErrNoRows {
+			// not passing err along, respondError would turn sql.ErrNoRows into a 404
+			respondError(ctx, w, http.StatusBadRequest, "unknown session type", errors.New("unknown session type"))
+			return
+		}
+
+		respondError(ctx, w, http.StatusBadRequest, "invalid session type or duration", err)
 		return
 	}
 
-	availableAppointments, err := buildAvailableAppointments(startsAt, endsAt, trainerID, timeSlots)
+	// one trainer or any of them, the response is slots either way so clients decode it the same
+	availableSlots, err := a.buildAvailableSlots(ctx, trainerID, startsAt, endsAt, sessionType)
 	if err != nil {
 		respondError(ctx, w, http.StatusInternalServerError, "something went wrong", err)
 		return
 	}
 
-	respondModel(ctx, w, http.StatusOK, availableAppointments)
+	respondModel(ctx, w, http.StatusOK, availableSlots)
 	return
 }
 
-func buildAvailableAppointments(startsAt time.Time, endsAt time.Time, trainerID int64, timeSlots map[int64]int64) ([]models.Appointment, error) {
-	// Calling LoadLocation
-	// method with its parameter
-	loc, err := time.LoadLocation("America/Los_Angeles")
+// ListNextAvailableAppointments finds the first free slots after a time, scanning forward until the horizon
+func (a *V1AppointmentsController) ListNextAvailableAppointments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	queryParams := r.URL.Query()
+
+	trainerID, err := getTrainerID(queryParams)
 	if err != nil {
-		return []models.Appointment{}, errors.New("could not load timezone location")
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
 	}
 
-	appointments := make([]models.Appointment, 0)
-	currentTimeSlot := startsAt
+	after, err := getAfter(queryParams)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid after value", err)
+		return
+	}
+
+	count, err := getNextAvailableCount(queryParams)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid count value", err)
+		return
+	}
+
+	horizonDays, err := getHorizonDays(queryParams)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid horizon_days value", err)
+		return
+	}
+
+	sessionType, err := a.getAvailabilitySessionType(ctx, queryParams)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			// not passing err along, respondError would turn sql.ErrNoRows into a 404
+			respondError(ctx, w, http.StatusBadRequest, "unknown session type", errors.New("unknown session type"))
+			return
+		}
+
+		respondError(ctx, w, http.StatusBadRequest, "invalid session type or duration", err)
+		return
+	}
+
+	if trainerID != 0 {
+		err = a.checkTrainer(ctx, trainerID)
+		if err != nil {
+			if isUnprocessableParticipant(err) {
+				respondError(ctx, w, http.StatusUnprocessableEntity, "trainer can't be booked", err)
+				return
+			}
+
+			respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+			return
+		}
+	}
+
+	horizon := after.AddDate(0, 0, horizonDays)
+	slots, err := a.findNextAvailable(ctx, trainerID, after, horizon, sessionType, count)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something went wrong", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, slots)
+	return
+}
 
-	log.Printf("scheduled timeslots unix time: %#v\n", timeSlots)
-	for {
-		if currentTimeSlot.Equal(endsAt) {
-			break
+// findNextAvailable scans forward from after one window at a time until it has count slots or reaches the horizon
+// a trainer ID of 0 searches every active trainer
+func (a *V1AppointmentsController) findNextAvailable(ctx context.Context, trainerID int64, after time.Time, horizon time.Time, sessionType models.SessionType, count int) ([]models.AvailableSlot, error) {
+	slots := make([]models.AvailableSlot, 0, count)
+	for windowStart := after; windowStart.Before(horizon) && len(slots) < count; windowStart = windowStart.Add(nextAvailableWindow) {
+		windowEnd := windowStart.Add(nextAvailableWindow)
+		if windowEnd.After(horizon) {
+			windowEnd = horizon
 		}
 
-		log.Println("checking timeslot")
+		// load past the window end so a session starting near the end isn't cut off, slots starting after it are left to the next window
+		searchEnd := windowEnd.Add(sessionType.Duration())
+		if searchEnd.After(horizon) {
+			searchEnd = horizon
+		}
+
+		windowSlots, err := a.buildAvailableSlots(ctx, trainerID, windowStart, searchEnd, sessionType)
+		if err != nil {
+			return []models.AvailableSlot{}, err
+		}
+
+		for _, slot := range windowSlots {
+			if !slot.StartsAt.Before(windowEnd) || len(slots) == count {
+				break
+			}
+
+			slots = append(slots, slot)
+		}
+	}
+
+	return slots, nil
+}
+
+// buildAvailableSlots finds the available time slots for one trainer, or any active trainer when the trainer ID is 0
+func (a *V1AppointmentsController) buildAvailableSlots(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time, sessionType models.SessionType) ([]models.AvailableSlot, error) {
+	if trainerID == 0 {
+		return a.buildAnyTrainerAvailability(ctx, startsAt, endsAt, sessionType)
+	}
+
+	booked, err := a.repo.GetBookedTimeSlots(ctx, trainerID, startsAt, endsAt)
+	if err != nil {
+		return []models.AvailableSlot{}, err
+	}
+
+	schedule, err := a.getWorkingSchedule(ctx, trainerID, startsAt, endsAt)
+	if err != nil {
+		return []models.AvailableSlot{}, err
+	}
+
+	availableAppointments, err := buildAvailableAppointments(startsAt, endsAt, trainerID, booked, schedule, sessionType)
+	if err != nil {
+		return []models.AvailableSlot{}, err
+	}
+
+	return mergeAvailableAppointments(availableAppointments), nil
+}
+
+func buildAvailableAppointments(startsAt time.Time, endsAt time.Time, trainerID int64, booked []models.TimeSlot, schedule models.WorkingSchedule, sessionType models.SessionType) ([]models.Appointment, error) {
+	appointments := make([]models.Appointment, 0)
+	duration := sessionType.Duration()
+	granularity := sessionType.Granularity()
 
-		log.Printf("current unix slot: %d\n", currentTimeSlot.Unix())
-		// check if this time is a scheduled time
-		_, ok := timeSlots[currentTimeSlot.Unix()]
+	var sessionTypeID *int64
+	if sessionType.ID != 0 {
+		sessionTypeID = &sessionType.ID
+	}
 
-		// if within business hours and unscheduled
-		if startsWithinBusinessHours(currentTimeSlot, loc) && !ok {
+	// start on the first valid slot at or after startsAt
+	currentTimeSlot := startsAt.Truncate(granularity)
+	if currentTimeSlot.Before(startsAt) {
+		currentTimeSlot = currentTimeSlot.Add(granularity)
+	}
+
+	for !currentTimeSlot.Add(duration).After(endsAt) {
+		// if within the trainer's working hours and no booked appointment overlaps the session
+		if schedule.Contains(currentTimeSlot, currentTimeSlot.Add(duration)) && !isBooked(booked, currentTimeSlot, currentTimeSlot.Add(duration)) {
 			appointments = append(appointments,
 				models.Appointment{
-					TrainerID: trainerID,
-					StartsAt:  currentTimeSlot,
-					EndsAt:    currentTimeSlot.Add(30 * time.Minute),
+					TrainerID:     trainerID,
+					StartsAt:      currentTimeSlot,
+					EndsAt:        currentTimeSlot.Add(duration),
+					SessionTypeID: sessionTypeID,
 				})
 		}
 
-		currentTimeSlot = currentTimeSlot.Add(30 * time.Minute)
+		currentTimeSlot = currentTimeSlot.Add(granularity)
 	}
 
 	return appointments, nil
 
 }
 
+// buildAnyTrainerAvailability finds the time slots open with any active trainer, each with its own working hours, time off and bookings
+// everything is loaded for all the trainers at once, so the number of queries doesn't grow with the number of trainers
+func (a *V1AppointmentsController) buildAnyTrainerAvailability(ctx context.Context, startsAt time.Time, endsAt time.Time, sessionType models.SessionType) ([]models.AvailableSlot, error) {
+	trainers, err := a.trRepo.ListTrainers(ctx, true)
+	if err != nil {
+		return []models.AvailableSlot{}, err
+	}
+
+	// one query for every trainer's bookings instead of one per trainer
+	booked, err := a.repo.GetBookedTimeSlots(ctx, 0, startsAt, endsAt)
+	if err != nil {
+		return []models.AvailableSlot{}, err
+	}
+
+	bookedByTrainer := make(map[int64][]models.TimeSlot)
+	for _, b := range booked {
+		bookedByTrainer[b.TrainerID] = append(bookedByTrainer[b.TrainerID], b)
+	}
+
+	trainerIDs := make([]int64, 0, len(trainers))
+	for _, trainer := range trainers {
+		trainerIDs = append(trainerIDs, trainer.ID)
+	}
+
+	// working hours and time off are loaded for every trainer at once too
+	hours, err := a.whRepo.ListWorkingHoursForTrainers(ctx, trainerIDs)
+	if err != nil {
+		return []models.AvailableSlot{}, err
+	}
+
+	hoursByTrainer := make(map[int64][]models.WorkingHours)
+	for _, h := range hours {
+		hoursByTrainer[h.TrainerID] = append(hoursByTrainer[h.TrainerID], h)
+	}
+
+	timeOff, err := a.toRepo.ListTimeOffForTrainers(ctx, trainerIDs, startsAt, endsAt)
+	if err != nil {
+		return []models.AvailableSlot{}, err
+	}
+
+	gymTimeOff := make([]models.TimeOff, 0)
+	timeOffByTrainer := make(map[int64][]models.TimeOff)
+	for _, t := range timeOff {
+		if t.TrainerID == nil {
+			gymTimeOff = append(gymTimeOff, t)
+			continue
+		}
+
+		timeOffByTrainer[*t.TrainerID] = append(timeOffByTrainer[*t.TrainerID], t)
+	}
+
+	available := make([]models.Appointment, 0)
+	for _, trainer := range trainers {
+		schedule, err := models.NewWorkingSchedule(trainer.ID, hoursByTrainer[trainer.ID])
+		if err != nil {
+			return []models.AvailableSlot{}, err
+		}
+
+		schedule = schedule.WithTimeOff(gymTimeOff).WithTimeOff(timeOffByTrainer[trainer.ID])
+
+		trainerAvailable, err := buildAvailableAppointments(startsAt, endsAt, trainer.ID, bookedByTrainer[trainer.ID], schedule, sessionType)
+		if err != nil {
+			return []models.AvailableSlot{}, err
+		}
+
+		available = append(available, trainerAvailable...)
+	}
+
+	return mergeAvailableAppointments(available), nil
+}
+
+// mergeAvailableAppointments groups available appointments for different trainers by time slot, earliest first
+func mergeAvailableAppointments(appointments []models.Appointment) []models.AvailableSlot {
+	slotsByStart := make(map[int64]*models.AvailableSlot)
+	for _, appt := range appointments {
+		slot, ok := slotsByStart[appt.StartsAt.Unix()]
+		if !ok {
+			slot = &models.AvailableSlot{
+				StartsAt:      appt.StartsAt,
+				EndsAt:        appt.EndsAt,
+				SessionTypeID: appt.SessionTypeID,
+				TrainerIDs:    make([]int64, 0, 1),
+			}
+			slotsByStart[appt.StartsAt.Unix()] = slot
+		}
+
+		slot.TrainerIDs = append(slot.TrainerIDs, appt.TrainerID)
+	}
+
+	slots := make([]models.AvailableSlot, 0, len(slotsByStart))
+	for _, slot := range slotsByStart {
+		sort.Slice(slot.TrainerIDs, func(i, j int) bool { return slot.TrainerIDs[i] < slot.TrainerIDs[j] })
+		slots = append(slots, *slot)
+	}
+
+	sort.Slice(slots, func(i, j int) bool { return slots[i].StartsAt.Before(slots[j].StartsAt) })
+	return slots
+}
+
+// isBooked checks if any booked time slot overlaps the time range
+func isBooked(booked []models.TimeSlot, startsAt time.Time, endsAt time.Time) bool {
+	for _, b := range booked {
+		if b.Overlaps(startsAt, endsAt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getAvailabilitySessionType gets the session type to find availability for from the session_type_id or duration (in minutes) params
+// without either, availability is for the default 30-minute session
+func (a *V1AppointmentsController) getAvailabilitySessionType(ctx context.Context, queryParams url.Values) (models.SessionType, error) {
+	if sessionTypeIDStr := queryParams.Get("session_type_id"); sessionTypeIDStr != "" {
+		sessionTypeID, err := strconv.ParseInt(sessionTypeIDStr, 10, 64)
+		if err != nil {
+			return models.SessionType{}, err
+		}
+
+		return a.getSessionType(ctx, sessionTypeID)
+	}
+
+	if durationStr := queryParams.Get("duration"); durationStr != "" {
+		duration, err := strconv.Atoi(durationStr)
+		if err != nil {
+			return models.SessionType{}, err
+		}
+
+		if duration <= 0 || duration > 8*60 {
+			return models.SessionType{}, errors.New("invalid duration, must be between 1 minute and 8 hours")
+		}
+
+		return models.SessionType{
+			Name:                   "custom",
+			DurationMinutes:        duration,
+			SlotGranularityMinutes: models.DefaultSessionType.SlotGranularityMinutes,
+		}, nil
+	}
+
+	return models.DefaultSessionType, nil
+}
+
 func getTrainerID(queryParams url.Values) (int64, error) {
 	trainerIDStr := queryParams.Get("trainer_id")
 	if trainerIDStr == "" {
@@ -225,6 +918,139 @@ func getTrainerID(queryParams url.Values) (int64, error) {
 	return trainerID, err
 }
 
+func getUserID(queryParams url.Values) (int64, error) {
+	userIDStr := queryParams.Get("user_id")
+	if userIDStr == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(userIDStr, 10, 64)
+}
+
+func getCancelScope(queryParams url.Values) (string, error) {
+	scope := queryParams.Get("scope")
+	if scope == "" {
+		return cancelScopeThis, nil
+	}
+
+	if scope != cancelScopeThis && scope != cancelScopeFollowing {
+		return "", errors.Errorf("unknown scope %s", scope)
+	}
+
+	return scope, nil
+}
+
+func getStatus(queryParams url.Values) (models.AppointmentStatus, error) {
+	statusStr := queryParams.Get("status")
+	if statusStr == "" {
+		return models.AppointmentStatusActive, nil
+	}
+
+	status := models.AppointmentStatus(statusStr)
+	if !status.Valid() {
+		return "", errors.Errorf("unknown status %s", statusStr)
+	}
+
+	return status, nil
+}
+
+func getSort(queryParams url.Values) (models.AppointmentsSort, error) {
+	sortStr := queryParams.Get("sort")
+	if sortStr == "" {
+		return models.AppointmentsSortStartsAt, nil
+	}
+
+	sort := models.AppointmentsSort(sortStr)
+	if !sort.Valid() {
+		return "", errors.Errorf("unknown sort %s", sortStr)
+	}
+
+	return sort, nil
+}
+
+func getLimit(queryParams url.Values) (int, error) {
+	limitStr := queryParams.Get("limit")
+	if limitStr == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if limit <= 0 || limit > maxPageLimit {
+		return 0, errors.Errorf("limit must be between 1 and %d", maxPageLimit)
+	}
+
+	return limit, nil
+}
+
+// getCursor decodes the cursor param, a cursor is only valid for the sort it was made with
+func getCursor(queryParams url.Values, sort models.AppointmentsSort) (*models.AppointmentsCursor, error) {
+	cursorStr := queryParams.Get("cursor")
+	if cursorStr == "" {
+		return nil, nil
+	}
+
+	cursor, err := models.DecodeAppointmentsCursor(cursorStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor.Sort != sort {
+		return nil, errors.Errorf("cursor is for sort %s, not %s", cursor.Sort, sort)
+	}
+
+	return &cursor, nil
+}
+
+// getAfter parses the after param, without it the search starts now
+func getAfter(queryParams url.Values) (time.Time, error) {
+	afterStr := queryParams.Get("after")
+	if afterStr == "" {
+		return time.Now().UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, afterStr)
+}
+
+func getNextAvailableCount(queryParams url.Values) (int, error) {
+	countStr := queryParams.Get("count")
+	if countStr == "" {
+		return defaultNextAvailableCount, nil
+	}
+
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if count <= 0 || count > maxNextAvailableCount {
+		return 0, errors.Errorf("count must be between 1 and %d", maxNextAvailableCount)
+	}
+
+	return count, nil
+}
+
+func getHorizonDays(queryParams url.Values) (int, error) {
+	horizonStr := queryParams.Get("horizon_days")
+	if horizonStr == "" {
+		return defaultHorizonDays, nil
+	}
+
+	horizonDays, err := strconv.Atoi(horizonStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if horizonDays <= 0 || horizonDays > maxHorizonDays {
+		return 0, errors.Errorf("horizon_days must be between 1 and %d", maxHorizonDays)
+	}
+
+	return horizonDays, nil
+}
+
 func getTimeRange(queryParams url.Values) (time.Time, time.Time, error) {
 	startsAtStr := queryParams.Get("starts_at")
 	var startsAt time.Time
@@ -3,7 +3,10 @@ package controllers
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/samuelmahr/appt-scheduling/internal/configuration"
 	"github.com/samuelmahr/appt-scheduling/internal/models"
 	"github.com/samuelmahr/appt-scheduling/internal/repo"
@@ -44,6 +47,10 @@ func TestV1Appointments_CreateAppointment(t *testing.T) {
 		ctx     context.Context
 		request []byte
 		aRepo   repo.MockAppointments
+		whRepo  repo.MockWorkingHours
+		toRepo  repo.MockTimeOff
+		stRepo  repo.MockSessionTypes
+		trRepo  repo.MockTrainers
 	}
 
 	tests := []struct {
@@ -51,6 +58,7 @@ func TestV1Appointments_CreateAppointment(t *testing.T) {
 		args     args
 		response int
 		errMsg   string
+		errType  string
 	}{
 		{
 			name: "success within business hours",
@@ -72,6 +80,9 @@ func TestV1Appointments_CreateAppointment(t *testing.T) {
 						CreatedAt: time.Now(),
 						UpdatedAt: time.Now(),
 					}},
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+				},
 			},
 			response: http.StatusCreated,
 		},
@@ -131,6 +142,237 @@ func TestV1Appointments_CreateAppointment(t *testing.T) {
 			response: http.StatusBadRequest,
 			errMsg:   "bad request payload, check times",
 		},
+		{
+			name: "success on trainer's saturday shift",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"starts_at": "2022-03-19T14:00:00Z",
+					"ends_at": "2022-03-19T14:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{
+					CreateAppointmentsResponse: models.Appointment{
+						ID:        1,
+						TrainerID: 1,
+						UserID:    1,
+						StartsAt:  time.Date(2022, 03, 19, 14, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 19, 14, 30, 0, 0, time.UTC),
+					}},
+				whRepo: repo.MockWorkingHours{
+					ListWorkingHoursResponse: []models.WorkingHours{
+						{TrainerID: 1, Weekday: time.Saturday, StartTime: "09:00", EndTime: "12:00", Timezone: "America/New_York"},
+					}},
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+				},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail outside trainer's working hours",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{},
+				whRepo: repo.MockWorkingHours{
+					ListWorkingHoursResponse: []models.WorkingHours{
+						{TrainerID: 1, Weekday: time.Saturday, StartTime: "09:00", EndTime: "12:00", Timezone: "America/New_York"},
+					}},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "fail during trainer time off",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{},
+				toRepo: repo.MockTimeOff{
+					ListTimeOffForTrainerResponse: []models.TimeOff{
+						{
+							ID:       1,
+							StartsAt: time.Date(2022, 03, 17, 0, 0, 0, 0, time.UTC),
+							EndsAt:   time.Date(2022, 03, 18, 0, 0, 0, 0, time.UTC),
+							Reason:   "holiday",
+						},
+					}},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "fail not starting on a slot",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"starts_at": "2022-03-17T19:10:00Z",
+					"ends_at": "2022-03-17T19:40:00Z"
+				}`),
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "success 60 minute session",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"session_type_id": 3,
+					"starts_at": "2022-03-17T19:30:00Z",
+					"ends_at": "2022-03-17T20:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{
+					CreateAppointmentsResponse: models.Appointment{
+						ID:        1,
+						TrainerID: 1,
+						UserID:    1,
+						StartsAt:  time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 17, 20, 30, 0, 0, time.UTC),
+					}},
+				stRepo: repo.MockSessionTypes{
+					GetSessionTypeResponse: models.SessionType{ID: 3, Name: "60 minute session", DurationMinutes: 60, SlotGranularityMinutes: 30},
+				},
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+				},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail duration doesn't match session type",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"session_type_id": 3,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{},
+				stRepo: repo.MockSessionTypes{
+					GetSessionTypeResponse: models.SessionType{ID: 3, Name: "60 minute session", DurationMinutes: 60, SlotGranularityMinutes: 30},
+				},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "fail unknown session type",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"session_type_id": 42,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{},
+				stRepo: repo.MockSessionTypes{
+					GetSessionTypeErr: errors.Wrap(sql.ErrNoRows, "error getting session type"),
+				},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, unknown session type",
+		},
+		{
+			name: "fail time slot already booked",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{
+					CreateAppointmentsErr: repo.SlotTakenError{
+						TrainerID: 1,
+						StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					},
+				},
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+				},
+			},
+			response: http.StatusConflict,
+			errMsg:   "time slot is already booked",
+			errType:  "slot_taken",
+		},
+		{
+			name: "fail unknown trainer",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z"
+				}`),
+				trRepo: repo.MockTrainers{
+					GetTrainerErr: errors.Wrap(sql.ErrNoRows, "error getting trainer"),
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer can't be booked",
+		},
+		{
+			name: "fail inactive trainer",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z"
+				}`),
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: false},
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer can't be booked",
+		},
+		{
+			name: "fail unknown user",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"user_id": 1,
+					"trainer_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{
+					CreateAppointmentsErr: repo.ErrUnknownUser,
+				},
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer or user can't be booked",
+		},
 	}
 
 	endpoint := "/appointments"
@@ -138,7 +380,7 @@ func TestV1Appointments_CreateAppointment(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			aRepo = &tt.args.aRepo
 
-			appointmentsController = NewV1AppointmentsController(config, aRepo)
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
 
 			getHandler := http.HandlerFunc(appointmentsController.CreateAppointment)
 
@@ -152,9 +394,18 @@ func TestV1Appointments_CreateAppointment(t *testing.T) {
 			assert.Equal(t, tt.response, response.Code)
 
 			if tt.response != http.StatusCreated {
-				resp := make(map[string]string)
+				resp := make(map[string]interface{})
 				err = json.Unmarshal(response.Body.Bytes(), &resp)
 				assert.Equal(t, tt.errMsg, resp["error"])
+
+				if tt.errType != "" {
+					assert.Equal(t, tt.errType, resp["type"])
+					assert.Equal(t, map[string]interface{}{
+						"trainer_id": float64(1),
+						"starts_at":  "2022-03-17T19:00:00Z",
+						"ends_at":    "2022-03-17T19:30:00Z",
+					}, resp["details"])
+				}
 			}
 		})
 	}
@@ -162,16 +413,22 @@ func TestV1Appointments_CreateAppointment(t *testing.T) {
 
 func TestV1Appointments_ListScheduledAppointments(t *testing.T) {
 	type args struct {
-		ctx   context.Context
-		query url.Values
-		aRepo repo.MockAppointments
+		ctx    context.Context
+		query  url.Values
+		aRepo  repo.MockAppointments
+		whRepo repo.MockWorkingHours
+		toRepo repo.MockTimeOff
+		stRepo repo.MockSessionTypes
+		trRepo repo.MockTrainers
 	}
 
 	tests := []struct {
-		name     string
-		args     args
-		response int
-		errMsg   string
+		name           string
+		args           args
+		response       int
+		errMsg         string
+		wantCount      int
+		wantNextCursor bool
 	}{
 		{
 			name: "happy path trainer ID",
@@ -193,7 +450,8 @@ func TestV1Appointments_ListScheduledAppointments(t *testing.T) {
 						},
 					}},
 			},
-			response: http.StatusOK,
+			response:  http.StatusOK,
+			wantCount: 1,
 		},
 		{
 			name: "happy path trainer ID and dates",
@@ -217,138 +475,275 @@ func TestV1Appointments_ListScheduledAppointments(t *testing.T) {
 						},
 					}},
 			},
-			response: http.StatusOK,
+			response:  http.StatusOK,
+			wantCount: 1,
 		},
 		{
-			name: "fail invalid date format",
+			name: "happy path canceled status",
 			args: args{
 				ctx: context.TODO(),
 				query: url.Values{
 					"trainer_id": []string{"1"},
-					"starts_at":  []string{"2022-03-1719:00:00Z"},
-					"ends_at":    []string{"2022-03-1720:00:00Z"},
+					"status":     []string{"canceled"},
 				},
-				aRepo: repo.MockAppointments{},
+				aRepo: repo.MockAppointments{
+					GetScheduledAppointmentsResponse: []models.Appointment{}},
 			},
-			response: http.StatusBadRequest,
-			errMsg:   "invalid time range values",
+			response: http.StatusOK,
 		},
-	}
-
-	endpoint := "/appointments/scheduled"
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			aRepo = &tt.args.aRepo
-
-			appointmentsController = NewV1AppointmentsController(config, aRepo)
-
-			getHandler := http.HandlerFunc(appointmentsController.ListScheduledAppointments)
-
-			req, err := http.NewRequest("GET", endpoint, nil)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			req.URL.RawQuery = tt.args.query.Encode()
-			response := httptest.NewRecorder()
-			getHandler.ServeHTTP(response, req)
-			assert.Equal(t, tt.response, response.Code)
-
-			if tt.response != http.StatusOK {
-				resp := make(map[string]string)
-				err = json.Unmarshal(response.Body.Bytes(), &resp)
-				assert.Equal(t, tt.errMsg, resp["error"])
-			}
-		})
-	}
-}
-
-func TestV1Appointments_ListAvailableAppointments(t *testing.T) {
-	type args struct {
-		ctx   context.Context
-		query url.Values
-		aRepo repo.MockAppointments
-	}
-
-	tests := []struct {
-		name     string
-		args     args
-		response int
-		errMsg   string
-	}{
 		{
-			name: "error no dates",
+			name: "happy path more pages",
 			args: args{
 				ctx: context.TODO(),
 				query: url.Values{
-					"trainer_id": []string{"1"},
+					"limit": []string{"2"},
+					"sort":  []string{"-starts_at"},
 				},
 				aRepo: repo.MockAppointments{
 					GetScheduledAppointmentsResponse: []models.Appointment{
-						{
-							ID:        1,
-							TrainerID: 1,
-							UserID:    1,
-							StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
-							EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
-							CreatedAt: time.Now(),
-							UpdatedAt: time.Now(),
-						},
+						{ID: 3, TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 21, 0, 0, 0, time.UTC)},
+						{ID: 2, TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC)},
+						{ID: 1, TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)},
 					}},
 			},
-			errMsg:   "invalid, time range is required",
-			response: http.StatusBadRequest,
+			response:       http.StatusOK,
+			wantCount:      2,
+			wantNextCursor: true,
 		},
 		{
-			name: "happy path trainer ID and dates, no scheduled appts",
+			name: "happy path last page",
 			args: args{
 				ctx: context.TODO(),
 				query: url.Values{
-					"trainer_id": []string{"1"},
-					"starts_at":  []string{"2022-03-17T19:00:00Z"},
-					"ends_at":    []string{"2022-03-17T20:00:00Z"},
+					"limit":  []string{"2"},
+					"cursor": []string{models.AppointmentsCursor{Sort: models.AppointmentsSortStartsAt, Value: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC), ID: 2}.Encode()},
 				},
 				aRepo: repo.MockAppointments{
 					GetScheduledAppointmentsResponse: []models.Appointment{
-						{
-							TrainerID: 1,
-							StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
-							EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
-						},
+						{ID: 3, TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 21, 0, 0, 0, time.UTC)},
 					}},
 			},
-			response: http.StatusOK,
+			response:  http.StatusOK,
+			wantCount: 1,
 		},
 		{
-			name: "fail invalid date format",
+			name: "happy path user ID",
 			args: args{
 				ctx: context.TODO(),
 				query: url.Values{
-					"trainer_id": []string{"1"},
-					"starts_at":  []string{"2022-03-1719:00:00Z"},
-					"ends_at":    []string{"2022-03-1720:00:00Z"},
+					"user_id": []string{"1"},
 				},
-				aRepo: repo.MockAppointments{},
+				aRepo: repo.MockAppointments{
+					GetScheduledAppointmentsResponse: []models.Appointment{
+						{ID: 1, TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)},
+					}},
 			},
-			response: http.StatusBadRequest,
-			errMsg:   "invalid time range values",
+			response:  http.StatusOK,
+			wantCount: 1,
 		},
-	}
-
-	endpoint := "/appointments/available"
+		{
+			name: "fail invalid user ID",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"user_id": []string{"abc"},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid user ID",
+		},
+		{
+			name: "fail invalid sort",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"sort": []string{"user_id"},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid sort",
+		},
+		{
+			name: "fail limit too large",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"limit": []string{"1000"},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid limit",
+		},
+		{
+			name: "fail cursor for another sort",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"sort":   []string{"created_at"},
+					"cursor": []string{models.AppointmentsCursor{Sort: models.AppointmentsSortStartsAt, Value: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC), ID: 2}.Encode()},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid cursor",
+		},
+		{
+			name: "fail garbled cursor",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"cursor": []string{"not-a-cursor"},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid cursor",
+		},
+		{
+			name: "fail invalid date format",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					"starts_at":  []string{"2022-03-1719:00:00Z"},
+					"ends_at":    []string{"2022-03-1720:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid time range values",
+		},
+		{
+			name: "fail invalid status",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					"status":     []string{"deleted"},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid status",
+		},
+	}
+
+	endpoint := "/appointments/scheduled"
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			aRepo = &tt.args.aRepo
 
-			appointmentsController = NewV1AppointmentsController(config, aRepo)
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
 
-			getHandler := http.HandlerFunc(appointmentsController.ListAvailableAppointments)
+			getHandler := http.HandlerFunc(appointmentsController.ListScheduledAppointments)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.URL.RawQuery = tt.args.query.Encode()
+			response := httptest.NewRecorder()
+			getHandler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			page := models.AppointmentsPage{}
+			err = json.Unmarshal(response.Body.Bytes(), &page)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, page.Appointments, tt.wantCount)
+			assert.Equal(t, tt.wantNextCursor, page.NextCursor != "")
+			if tt.wantNextCursor {
+				cursor, err := models.DecodeAppointmentsCursor(page.NextCursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				assert.Equal(t, page.Appointments[tt.wantCount-1].ID, cursor.ID)
+			}
+		})
+	}
+}
+
+func TestV1Appointments_ListUserAppointments(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		id     string
+		query  url.Values
+		aRepo  repo.MockAppointments
+		whRepo repo.MockWorkingHours
+		toRepo repo.MockTimeOff
+		stRepo repo.MockSessionTypes
+		trRepo repo.MockTrainers
+	}
+
+	tests := []struct {
+		name      string
+		args      args
+		response  int
+		errMsg    string
+		wantCount int
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				query: url.Values{
+					"starts_at": []string{"2022-03-17T00:00:00Z"},
+					"ends_at":   []string{"2022-03-24T00:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{
+					GetScheduledAppointmentsResponse: []models.Appointment{
+						{ID: 1, TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)},
+						{ID: 2, TrainerID: 2, UserID: 1, StartsAt: time.Date(2022, 03, 18, 19, 0, 0, 0, time.UTC)},
+					}},
+			},
+			response:  http.StatusOK,
+			wantCount: 2,
+		},
+		{
+			name: "fail invalid status",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				query: url.Values{
+					"status": []string{"deleted"},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid status",
+		},
+	}
+
+	endpoint := "/users/{id}/appointments"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
+
+			getHandler := http.HandlerFunc(appointmentsController.ListUserAppointments)
 
 			req, err := http.NewRequest("GET", endpoint, nil)
 			if err != nil {
 				t.Fatal(err)
 			}
 
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
 			req.URL.RawQuery = tt.args.query.Encode()
 			response := httptest.NewRecorder()
 			getHandler.ServeHTTP(response, req)
@@ -358,7 +753,1222 @@ func TestV1Appointments_ListAvailableAppointments(t *testing.T) {
 				resp := make(map[string]string)
 				err = json.Unmarshal(response.Body.Bytes(), &resp)
 				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			page := models.AppointmentsPage{}
+			err = json.Unmarshal(response.Body.Bytes(), &page)
+			if err != nil {
+				t.Fatal(err)
 			}
+
+			assert.Len(t, page.Appointments, tt.wantCount)
 		})
 	}
 }
+
+func TestV1Appointments_ListAvailableAppointments(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		query  url.Values
+		aRepo  repo.MockAppointments
+		whRepo repo.MockWorkingHours
+		toRepo repo.MockTimeOff
+		stRepo repo.MockSessionTypes
+		trRepo repo.MockTrainers
+	}
+
+	trainer2 := int64(2)
+
+	tests := []struct {
+		name      string
+		args      args
+		response  int
+		errMsg    string
+		wantSlots []models.AvailableSlot
+	}{
+		{
+			name: "error no dates",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+				},
+				aRepo: repo.MockAppointments{
+					GetScheduledAppointmentsResponse: []models.Appointment{
+						{
+							ID:        1,
+							TrainerID: 1,
+							UserID:    1,
+							StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+							CreatedAt: time.Now(),
+							UpdatedAt: time.Now(),
+						},
+					}},
+			},
+			errMsg:   "invalid, time range is required",
+			response: http.StatusBadRequest,
+		},
+		{
+			name: "happy path trainer ID and dates, no scheduled appts",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					"starts_at":  []string{"2022-03-17T19:00:00Z"},
+					"ends_at":    []string{"2022-03-17T20:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{GetBookedTimeSlotsResponse: []models.TimeSlot{}},
+			},
+			response: http.StatusOK,
+			wantSlots: []models.AvailableSlot{
+				{
+					StartsAt:   time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+					EndsAt:     time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					TrainerIDs: []int64{1},
+				},
+				{
+					StartsAt:   time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					EndsAt:     time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+					TrainerIDs: []int64{1},
+				},
+			},
+		},
+		{
+			name: "happy path trainer ID skips booked slots",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					"starts_at":  []string{"2022-03-17T19:00:00Z"},
+					"ends_at":    []string{"2022-03-17T20:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{
+					GetBookedTimeSlotsResponse: []models.TimeSlot{
+						{
+							TrainerID: 1,
+							StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+						},
+					}},
+			},
+			response: http.StatusOK,
+			wantSlots: []models.AvailableSlot{
+				{
+					StartsAt:   time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					EndsAt:     time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+					TrainerIDs: []int64{1},
+				},
+			},
+		},
+		{
+			name: "fail getting booked time slots for a trainer",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					"starts_at":  []string{"2022-03-17T19:00:00Z"},
+					"ends_at":    []string{"2022-03-17T20:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{GetBookedTimeSlotsErr: errors.New("connection refused")},
+			},
+			response: http.StatusInternalServerError,
+			errMsg:   "something went wrong",
+		},
+		{
+			name: "happy path any trainer",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"starts_at": []string{"2022-03-17T19:00:00Z"},
+					"ends_at":   []string{"2022-03-17T20:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{
+					GetBookedTimeSlotsResponse: []models.TimeSlot{
+						{
+							TrainerID: 1,
+							StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+						},
+					}},
+				trRepo: repo.MockTrainers{
+					ListTrainersResponse: []models.Trainer{
+						{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+						{ID: 2, Name: "Trainer 2", Email: "trainer2@example.com", Active: true},
+					}},
+			},
+			response: http.StatusOK,
+			wantSlots: []models.AvailableSlot{
+				{
+					StartsAt:   time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+					EndsAt:     time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					TrainerIDs: []int64{2},
+				},
+				{
+					StartsAt:   time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					EndsAt:     time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+					TrainerIDs: []int64{1, 2},
+				},
+			},
+		},
+		{
+			name: "happy path any trainer with time off",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"starts_at": []string{"2022-03-17T19:00:00Z"},
+					"ends_at":   []string{"2022-03-17T20:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{
+					GetBookedTimeSlotsResponse: []models.TimeSlot{
+						{
+							TrainerID: 1,
+							StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+						},
+					}},
+				toRepo: repo.MockTimeOff{
+					ListTimeOffForTrainersResponse: []models.TimeOff{
+						{
+							ID:        1,
+							TrainerID: &trainer2,
+							StartsAt:  time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+						},
+					}},
+				trRepo: repo.MockTrainers{
+					ListTrainersResponse: []models.Trainer{
+						{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+						{ID: 2, Name: "Trainer 2", Email: "trainer2@example.com", Active: true},
+					}},
+			},
+			response: http.StatusOK,
+			wantSlots: []models.AvailableSlot{
+				{
+					StartsAt:   time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+					EndsAt:     time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					TrainerIDs: []int64{2},
+				},
+				{
+					StartsAt:   time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					EndsAt:     time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+					TrainerIDs: []int64{1},
+				},
+			},
+		},
+		{
+			name: "fail listing working hours for any trainer",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"starts_at": []string{"2022-03-17T19:00:00Z"},
+					"ends_at":   []string{"2022-03-17T20:00:00Z"},
+				},
+				aRepo:  repo.MockAppointments{},
+				whRepo: repo.MockWorkingHours{ListWorkingHoursForTrainersErr: errors.New("connection refused")},
+				trRepo: repo.MockTrainers{
+					ListTrainersResponse: []models.Trainer{
+						{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+					}},
+			},
+			response: http.StatusInternalServerError,
+			errMsg:   "something went wrong",
+		},
+		{
+			name: "fail listing trainers",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"starts_at": []string{"2022-03-17T19:00:00Z"},
+					"ends_at":   []string{"2022-03-17T20:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{},
+				trRepo: repo.MockTrainers{
+					ListTrainersErr: errors.New("connection refused"),
+				},
+			},
+			response: http.StatusInternalServerError,
+			errMsg:   "something went wrong",
+		},
+		{
+			name: "fail invalid date format",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					"starts_at":  []string{"2022-03-1719:00:00Z"},
+					"ends_at":    []string{"2022-03-1720:00:00Z"},
+				},
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid time range values",
+		},
+	}
+
+	endpoint := "/appointments/available"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
+
+			getHandler := http.HandlerFunc(appointmentsController.ListAvailableAppointments)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.URL.RawQuery = tt.args.query.Encode()
+			response := httptest.NewRecorder()
+			getHandler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			if tt.wantSlots == nil {
+				return
+			}
+
+			slots := make([]models.AvailableSlot, 0)
+			err = json.Unmarshal(response.Body.Bytes(), &slots)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, slots, len(tt.wantSlots))
+			for i := range slots {
+				if i >= len(tt.wantSlots) {
+					break
+				}
+
+				assert.True(t, tt.wantSlots[i].StartsAt.Equal(slots[i].StartsAt), "starts at %s", slots[i].StartsAt)
+				assert.True(t, tt.wantSlots[i].EndsAt.Equal(slots[i].EndsAt), "ends at %s", slots[i].EndsAt)
+				assert.Equal(t, tt.wantSlots[i].TrainerIDs, slots[i].TrainerIDs)
+			}
+		})
+	}
+}
+
+func TestV1Appointments_ListNextAvailableAppointments(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		query  url.Values
+		aRepo  repo.MockAppointments
+		whRepo repo.MockWorkingHours
+		toRepo repo.MockTimeOff
+		stRepo repo.MockSessionTypes
+		trRepo repo.MockTrainers
+	}
+
+	trainer := models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true}
+
+	tests := []struct {
+		name       string
+		args       args
+		response   int
+		errMsg     string
+		wantStarts []time.Time
+	}{
+		{
+			name: "happy path skips the weekend and booked slots",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					// 3/18/2022 is a friday, 23:30 UTC is 4:30pm pacific
+					"after": []string{"2022-03-18T23:30:00Z"},
+					"count": []string{"3"},
+				},
+				aRepo: repo.MockAppointments{
+					GetBookedTimeSlotsResponse: []models.TimeSlot{
+						{
+							TrainerID: 1,
+							StartsAt:  time.Date(2022, 03, 21, 15, 0, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 21, 15, 30, 0, 0, time.UTC),
+						},
+					}},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusOK,
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 18, 23, 30, 0, 0, time.UTC),
+				time.Date(2022, 03, 21, 15, 30, 0, 0, time.UTC),
+				time.Date(2022, 03, 21, 16, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "horizon stops the search",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id":   []string{"1"},
+					"after":        []string{"2022-03-18T23:30:00Z"},
+					"count":        []string{"3"},
+					"horizon_days": []string{"1"},
+				},
+				aRepo:  repo.MockAppointments{GetBookedTimeSlotsResponse: []models.TimeSlot{}},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusOK,
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 18, 23, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "happy path any trainer",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"after": []string{"2022-03-18T23:30:00Z"},
+					"count": []string{"1"},
+				},
+				aRepo: repo.MockAppointments{GetBookedTimeSlotsResponse: []models.TimeSlot{}},
+				trRepo: repo.MockTrainers{
+					ListTrainersResponse: []models.Trainer{trainer},
+				},
+			},
+			response: http.StatusOK,
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 18, 23, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "fail inactive trainer",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"3"},
+				},
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 3, Name: "Trainer 3", Email: "trainer3@example.com", Active: false},
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer can't be booked",
+		},
+		{
+			name: "fail count too high",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					"count":      []string{"51"},
+				},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid count value",
+		},
+		{
+			name: "fail horizon too far",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"horizon_days": []string{"365"},
+				},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid horizon_days value",
+		},
+		{
+			name: "fail invalid after format",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"after": []string{"2022-03-18 23:30"},
+				},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid after value",
+		},
+	}
+
+	endpoint := "/appointments/next-available"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
+
+			getHandler := http.HandlerFunc(appointmentsController.ListNextAvailableAppointments)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.URL.RawQuery = tt.args.query.Encode()
+			response := httptest.NewRecorder()
+			getHandler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			slots := make([]models.AvailableSlot, 0)
+			err = json.Unmarshal(response.Body.Bytes(), &slots)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotStarts := make([]time.Time, 0)
+			for _, slot := range slots {
+				assert.Equal(t, []int64{1}, slot.TrainerIDs)
+				gotStarts = append(gotStarts, slot.StartsAt)
+			}
+
+			assert.Equal(t, tt.wantStarts, gotStarts)
+		})
+	}
+}
+
+func TestV1Appointments_GetAppointment(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		id     string
+		aRepo  repo.MockAppointments
+		whRepo repo.MockWorkingHours
+		toRepo repo.MockTimeOff
+		stRepo repo.MockSessionTypes
+		trRepo repo.MockTrainers
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					GetAppointmentResponse: models.Appointment{
+						ID:        1,
+						TrainerID: 1,
+						UserID:    1,
+						StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					}},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail invalid id",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "abc",
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid appointment ID",
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					GetAppointmentErr: errors.Wrap(sql.ErrNoRows, "error getting appointment"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/appointments/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
+
+			handler := http.HandlerFunc(appointmentsController.GetAppointment)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1Appointments_CancelAppointment(t *testing.T) {
+	canceledAt := time.Date(2022, 03, 16, 12, 0, 0, 0, time.UTC)
+
+	type args struct {
+		ctx    context.Context
+		id     string
+		scope  string
+		aRepo  repo.MockAppointments
+		whRepo repo.MockWorkingHours
+		toRepo repo.MockTimeOff
+		stRepo repo.MockSessionTypes
+		trRepo repo.MockTrainers
+		// ifMatch defaults to `"1"` unless withoutIfMatch is set
+		ifMatch        string
+		withoutIfMatch bool
+		wlRepo         repo.MockWaitlist
+	}
+
+	canceledSlot := models.TimeSlot{
+		TrainerID: 1,
+		StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+		errType  string
+		// wantOffered is the time slots offered to the waitlist
+		wantOffered []models.TimeSlot
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					CancelAppointmentResponse: models.Appointment{
+						ID:         1,
+						TrainerID:  1,
+						UserID:     1,
+						StartsAt:   time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+						EndsAt:     time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+						CanceledAt: &canceledAt,
+					}},
+			},
+			response:    http.StatusOK,
+			wantOffered: []models.TimeSlot{canceledSlot},
+		},
+		{
+			name: "happy path offered to the waitlist",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					CancelAppointmentResponse: models.Appointment{
+						ID:         1,
+						TrainerID:  1,
+						UserID:     1,
+						StartsAt:   time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+						EndsAt:     time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+						CanceledAt: &canceledAt,
+					}},
+				wlRepo: repo.MockWaitlist{
+					OfferFreedSlotResponse: []models.WaitlistOffer{
+						{
+							Entry: models.WaitlistEntry{ID: 1, TrainerID: 1, UserID: 2, Status: models.WaitlistOffered},
+							Hold:  models.Hold{ID: 1, TrainerID: 1, UserID: 2},
+						},
+					}},
+			},
+			response:    http.StatusOK,
+			wantOffered: []models.TimeSlot{canceledSlot},
+		},
+		{
+			name: "happy path offering to the waitlist fails",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					CancelAppointmentResponse: models.Appointment{
+						ID:         1,
+						TrainerID:  1,
+						UserID:     1,
+						StartsAt:   time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+						EndsAt:     time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+						CanceledAt: &canceledAt,
+					}},
+				wlRepo: repo.MockWaitlist{OfferFreedSlotErr: errors.New("connection reset")},
+			},
+			response:    http.StatusOK,
+			wantOffered: []models.TimeSlot{canceledSlot},
+		},
+		{
+			name: "fail invalid id",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "abc",
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid appointment ID",
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					CancelAppointmentErr: errors.Wrap(sql.ErrNoRows, "error canceling appointment"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+		{
+			name: "fail already canceled",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					CancelAppointmentErr: repo.ErrAlreadyCanceled,
+				},
+			},
+			response: http.StatusConflict,
+			errMsg:   "appointment already canceled",
+			errType:  "already_canceled",
+		},
+		{
+			name: "happy path this and following",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "1",
+				scope: "following",
+				aRepo: repo.MockAppointments{
+					CancelFollowingAppointmentsResponse: []models.Appointment{
+						{
+							ID:         1,
+							TrainerID:  1,
+							UserID:     1,
+							StartsAt:   time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+							EndsAt:     time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+							CanceledAt: &canceledAt,
+						},
+						{
+							ID:         2,
+							TrainerID:  1,
+							UserID:     1,
+							StartsAt:   time.Date(2022, 03, 24, 19, 0, 0, 0, time.UTC),
+							EndsAt:     time.Date(2022, 03, 24, 19, 30, 0, 0, time.UTC),
+							CanceledAt: &canceledAt,
+						},
+					}},
+			},
+			response: http.StatusOK,
+			wantOffered: []models.TimeSlot{
+				canceledSlot,
+				{
+					TrainerID: 1,
+					StartsAt:  time.Date(2022, 03, 24, 19, 0, 0, 0, time.UTC),
+					EndsAt:    time.Date(2022, 03, 24, 19, 30, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "fail this and following not in a series",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "1",
+				scope: "following",
+				aRepo: repo.MockAppointments{
+					CancelFollowingAppointmentsErr: repo.ErrNotInSeries,
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "appointment is not part of a series",
+			errType:  "not_in_series",
+		},
+		{
+			name: "fail unknown scope",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "1",
+				scope: "all",
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid scope",
+		},
+		{
+			name: "fail without If-Match",
+			args: args{
+				ctx:            context.TODO(),
+				id:             "1",
+				withoutIfMatch: true,
+			},
+			response: http.StatusPreconditionRequired,
+			errMsg:   "If-Match header is required",
+		},
+		{
+			name: "fail invalid If-Match",
+			args: args{
+				ctx:     context.TODO(),
+				id:      "1",
+				ifMatch: "1",
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid If-Match header",
+		},
+		{
+			name: "fail changed since read",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				aRepo: repo.MockAppointments{
+					CancelAppointmentErr: repo.ErrStaleVersion,
+				},
+			},
+			response: http.StatusPreconditionFailed,
+			errMsg:   "appointment was changed, get it again",
+			errType:  "stale_version",
+		},
+		{
+			name: "fail this and following changed since read",
+			args: args{
+				ctx:     context.TODO(),
+				id:      "1",
+				scope:   "following",
+				ifMatch: `W/"2"`,
+				aRepo: repo.MockAppointments{
+					CancelFollowingAppointmentsErr: repo.ErrStaleVersion,
+				},
+			},
+			response: http.StatusPreconditionFailed,
+			errMsg:   "appointment was changed, get it again",
+			errType:  "stale_version",
+		},
+	}
+
+	endpoint := "/appointments/{id}/cancel"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &tt.args.wlRepo)
+
+			handler := http.HandlerFunc(appointmentsController.CancelAppointment)
+
+			req, err := http.NewRequest("POST", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.args.scope != "" {
+				req.URL.RawQuery = url.Values{"scope": []string{tt.args.scope}}.Encode()
+			}
+
+			if !tt.args.withoutIfMatch {
+				ifMatch := tt.args.ifMatch
+				if ifMatch == "" {
+					ifMatch = `"1"`
+				}
+
+				req.Header.Set("If-Match", ifMatch)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			// the freed time is offered to the waitlist whether or not the offer works
+			assert.Equal(t, tt.wantOffered, tt.args.wlRepo.Offered)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				assert.Equal(t, tt.errType, resp["type"])
+			}
+		})
+	}
+}
+
+func TestV1Appointments_RescheduleAppointment(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		id      string
+		request []byte
+		aRepo   repo.MockAppointments
+		whRepo  repo.MockWorkingHours
+		toRepo  repo.MockTimeOff
+		stRepo  repo.MockSessionTypes
+		trRepo  repo.MockTrainers
+		// ifMatch defaults to `"1"` unless withoutIfMatch is set
+		ifMatch        string
+		withoutIfMatch bool
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+		errType  string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"starts_at": "2022-03-17T20:00:00Z",
+					"ends_at": "2022-03-17T20:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{
+					RescheduleAppointmentResponse: models.Appointment{
+						ID:        1,
+						TrainerID: 1,
+						UserID:    1,
+						StartsAt:  time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 17, 20, 30, 0, 0, time.UTC),
+					}},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail outside business hours",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"starts_at": "2022-03-17T08:00:00Z",
+					"ends_at": "2022-03-17T08:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "fail slot taken",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"trainer_id": 2,
+					"starts_at": "2022-03-17T20:00:00Z",
+					"ends_at": "2022-03-17T20:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{
+					RescheduleAppointmentErr: repo.SlotTakenError{
+						TrainerID: 2,
+						StartsAt:  time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 17, 20, 30, 0, 0, time.UTC),
+					},
+				},
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true},
+				},
+			},
+			response: http.StatusConflict,
+			errMsg:   "time slot is already booked",
+			errType:  "slot_taken",
+		},
+		{
+			name: "fail inactive trainer",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"trainer_id": 2,
+					"starts_at": "2022-03-17T20:00:00Z",
+					"ends_at": "2022-03-17T20:30:00Z"
+				}`),
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 2, Name: "Trainer 2", Email: "trainer2@example.com", Active: false},
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer can't be booked",
+			errType:  "inactive_trainer",
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"starts_at": "2022-03-17T20:00:00Z",
+					"ends_at": "2022-03-17T20:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{
+					RescheduleAppointmentErr: errors.Wrap(sql.ErrNoRows, "error rescheduling appointment"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+		{
+			name: "fail without If-Match",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"starts_at": "2022-03-17T20:00:00Z",
+					"ends_at": "2022-03-17T20:30:00Z"
+				}`),
+				withoutIfMatch: true,
+			},
+			response: http.StatusPreconditionRequired,
+			errMsg:   "If-Match header is required",
+		},
+		{
+			name: "fail changed since read",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"starts_at": "2022-03-17T20:00:00Z",
+					"ends_at": "2022-03-17T20:30:00Z"
+				}`),
+				aRepo: repo.MockAppointments{
+					RescheduleAppointmentErr: repo.ErrStaleVersion,
+				},
+			},
+			response: http.StatusPreconditionFailed,
+			errMsg:   "appointment was changed, get it again",
+			errType:  "stale_version",
+		},
+	}
+
+	endpoint := "/appointments/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &tt.args.whRepo, &tt.args.toRepo, &tt.args.stRepo, &tt.args.trRepo, &repo.MockWaitlist{})
+
+			handler := http.HandlerFunc(appointmentsController.RescheduleAppointment)
+
+			req, err := http.NewRequest("PATCH", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.args.withoutIfMatch {
+				ifMatch := tt.args.ifMatch
+				if ifMatch == "" {
+					ifMatch = `"1"`
+				}
+
+				req.Header.Set("If-Match", ifMatch)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				assert.Equal(t, tt.errType, resp["type"])
+			}
+		})
+	}
+}
+
+func Test_buildAvailableAppointments(t *testing.T) {
+	type args struct {
+		startsAt    time.Time
+		endsAt      time.Time
+		booked      []models.TimeSlot
+		hours       []models.WorkingHours
+		timeOff     []models.TimeOff
+		sessionType models.SessionType
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		wantStarts []time.Time
+	}{
+		{
+			name: "default business hours",
+			args: args{
+				// 3/17/2022 is a thursday, 00:00 to 02:00 UTC is 5pm to 7pm pacific
+				startsAt: time.Date(2022, 03, 17, 23, 0, 0, 0, time.UTC),
+				endsAt:   time.Date(2022, 03, 18, 1, 0, 0, 0, time.UTC),
+				booked:   []models.TimeSlot{},
+			},
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 17, 23, 0, 0, 0, time.UTC),
+				time.Date(2022, 03, 17, 23, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "trainer working hours skips scheduled slot",
+			args: args{
+				startsAt: time.Date(2022, 03, 19, 13, 0, 0, 0, time.UTC),
+				endsAt:   time.Date(2022, 03, 19, 17, 0, 0, 0, time.UTC),
+				booked: []models.TimeSlot{
+					{StartsAt: time.Date(2022, 03, 19, 14, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 19, 14, 30, 0, 0, time.UTC)},
+				},
+				hours: []models.WorkingHours{
+					{TrainerID: 1, Weekday: time.Saturday, StartTime: "09:00", EndTime: "11:00", Timezone: "America/New_York"},
+				},
+			},
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 19, 13, 0, 0, 0, time.UTC),
+				time.Date(2022, 03, 19, 13, 30, 0, 0, time.UTC),
+				time.Date(2022, 03, 19, 14, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "time off blocks overlapping slots",
+			args: args{
+				startsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+				endsAt:   time.Date(2022, 03, 17, 22, 0, 0, 0, time.UTC),
+				booked:   []models.TimeSlot{},
+				timeOff: []models.TimeOff{
+					{
+						StartsAt: time.Date(2022, 03, 17, 20, 15, 0, 0, time.UTC),
+						EndsAt:   time.Date(2022, 03, 17, 21, 30, 0, 0, time.UTC),
+					},
+				},
+			},
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 17, 21, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "imported calendar busy times block like time off",
+			args: args{
+				startsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+				endsAt:   time.Date(2022, 03, 17, 22, 0, 0, 0, time.UTC),
+				booked:   []models.TimeSlot{},
+				timeOff: []models.TimeOff{
+					{
+						StartsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+						EndsAt:   time.Date(2022, 03, 17, 20, 30, 0, 0, time.UTC),
+						Source:   models.TimeOffSourceManual,
+					},
+					{
+						StartsAt: time.Date(2022, 03, 17, 21, 0, 0, 0, time.UTC),
+						EndsAt:   time.Date(2022, 03, 17, 21, 10, 0, 0, time.UTC),
+						Source:   models.TimeOffSourceICS,
+					},
+				},
+			},
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 17, 20, 30, 0, 0, time.UTC),
+				time.Date(2022, 03, 17, 21, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "60 minute session needs consecutive free half hours",
+			args: args{
+				startsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+				endsAt:   time.Date(2022, 03, 17, 23, 0, 0, 0, time.UTC),
+				booked: []models.TimeSlot{
+					{StartsAt: time.Date(2022, 03, 17, 21, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 21, 30, 0, 0, time.UTC)},
+				},
+				sessionType: models.SessionType{ID: 3, DurationMinutes: 60, SlotGranularityMinutes: 30},
+			},
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+				time.Date(2022, 03, 17, 21, 30, 0, 0, time.UTC),
+				time.Date(2022, 03, 17, 22, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "longer booking overlapping slots it doesn't start on",
+			args: args{
+				startsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+				endsAt:   time.Date(2022, 03, 17, 22, 0, 0, 0, time.UTC),
+				booked: []models.TimeSlot{
+					{StartsAt: time.Date(2022, 03, 17, 20, 15, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 21, 0, 0, 0, time.UTC)},
+				},
+			},
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 17, 21, 0, 0, 0, time.UTC),
+				time.Date(2022, 03, 17, 21, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "starts on the next slot",
+			args: args{
+				startsAt:    time.Date(2022, 03, 17, 20, 5, 0, 0, time.UTC),
+				endsAt:      time.Date(2022, 03, 17, 21, 0, 0, 0, time.UTC),
+				booked:      []models.TimeSlot{},
+				sessionType: models.SessionType{ID: 2, DurationMinutes: 45, SlotGranularityMinutes: 15},
+			},
+			wantStarts: []time.Time{
+				time.Date(2022, 03, 17, 20, 15, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := models.NewWorkingSchedule(1, tt.args.hours)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			schedule = schedule.WithTimeOff(tt.args.timeOff)
+			sessionType := tt.args.sessionType
+			if sessionType.DurationMinutes == 0 {
+				sessionType = models.DefaultSessionType
+			}
+
+			got, err := buildAvailableAppointments(tt.args.startsAt, tt.args.endsAt, 1, tt.args.booked, schedule, sessionType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotStarts := make([]time.Time, 0)
+			for _, a := range got {
+				gotStarts = append(gotStarts, a.StartsAt)
+			}
+
+			assert.Equal(t, tt.wantStarts, gotStarts)
+		})
+	}
+}
+
+func Test_mergeAvailableAppointments(t *testing.T) {
+	appointments := []models.Appointment{
+		{TrainerID: 2, StartsAt: time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC)},
+		{TrainerID: 2, StartsAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC)},
+		{TrainerID: 1, StartsAt: time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC)},
+	}
+
+	want := []models.AvailableSlot{
+		{
+			StartsAt:   time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+			EndsAt:     time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+			TrainerIDs: []int64{2},
+		},
+		{
+			StartsAt:   time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+			EndsAt:     time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+			TrainerIDs: []int64{1, 2},
+		},
+	}
+
+	assert.Equal(t, want, mergeAvailableAppointments(appointments))
+	assert.Equal(t, []models.AvailableSlot{}, mergeAvailableAppointments([]models.Appointment{}))
+}
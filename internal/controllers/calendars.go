@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/ical"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// a feed has the appointments from calendarFeedPast ago up to calendarFeedFuture from now, calendar apps fetch the
+// whole feed every time they refresh it
+const (
+	calendarFeedPast   = 90 * 24 * time.Hour
+	calendarFeedFuture = 365 * 24 * time.Hour
+)
+
+// calendarUIDDomain makes appointment UIDs unique across calendars, RFC 5545 suggests the domain of the creator
+const calendarUIDDomain = "appt-scheduling"
+
+// errInvalidCalendarToken is returned for a feed requested without its current token
+var errInvalidCalendarToken = errors.New("invalid calendar token")
+
+type V1CalendarsController struct {
+	config *configuration.AppConfig
+	repo   repo.CalendarsRepository
+	aRepo  repo.AppointmentsRepository
+}
+
+func NewV1CalendarsController(c *configuration.AppConfig, calRepo repo.CalendarsRepository, aRepo repo.AppointmentsRepository) V1CalendarsController {
+	return V1CalendarsController{
+		config: c,
+		repo:   calRepo,
+		aRepo:  aRepo,
+	}
+}
+
+func (cc *V1CalendarsController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/trainers/{id:[0-9]+}/calendar-token").Name("CreateTrainerCalendarFeed").Handler(http.HandlerFunc(cc.CreateTrainerCalendarFeed)).Methods(http.MethodPost)
+	v1.Path("/trainers/{id:[0-9]+}/calendar.ics").Name("GetTrainerCalendar").Handler(http.HandlerFunc(cc.GetTrainerCalendar)).Methods(http.MethodGet)
+	v1.Path("/users/{id:[0-9]+}/calendar-token").Name("CreateUserCalendarFeed").Handler(http.HandlerFunc(cc.CreateUserCalendarFeed)).Methods(http.MethodPost)
+	v1.Path("/users/{id:[0-9]+}/calendar.ics").Name("GetUserCalendar").Handler(http.HandlerFunc(cc.GetUserCalendar)).Methods(http.MethodGet)
+}
+
+func (cc *V1CalendarsController) CreateTrainerCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	cc.createCalendarFeed(w, r, models.CalendarOwnerTrainer)
+}
+
+func (cc *V1CalendarsController) CreateUserCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	cc.createCalendarFeed(w, r, models.CalendarOwnerUser)
+}
+
+func (cc *V1CalendarsController) GetTrainerCalendar(w http.ResponseWriter, r *http.Request) {
+	cc.getCalendar(w, r, models.CalendarOwnerTrainer)
+}
+
+func (cc *V1CalendarsController) GetUserCalendar(w http.ResponseWriter, r *http.Request) {
+	cc.getCalendar(w, r, models.CalendarOwnerUser)
+}
+
+// createCalendarFeed generates a new token for the owner's feed and responds with its URL, URLs with the old token stop working
+func (cc *V1CalendarsController) createCalendarFeed(w http.ResponseWriter, r *http.Request, owner models.CalendarOwner) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, fmt.Sprintf("invalid %s ID", owner), err)
+		return
+	}
+
+	token, err := generateSecret()
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	err = cc.repo.SetCalendarToken(ctx, owner, id, token)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, models.CalendarFeed{URL: calendarFeedURL(r, owner, id, token)})
+	return
+}
+
+// getCalendar responds with the owner's appointments as an iCalendar feed. calendar apps can't send auth headers, so the
+// feed is checked against the token in its URL instead
+func (cc *V1CalendarsController) getCalendar(w http.ResponseWriter, r *http.Request, owner models.CalendarOwner) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, fmt.Sprintf("invalid %s ID", owner), err)
+		return
+	}
+
+	token, err := cc.repo.GetCalendarToken(ctx, owner, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	// there's no feed until its URL is created, an empty token never matches
+	given := r.URL.Query().Get("token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		respondError(ctx, w, http.StatusForbidden, "invalid calendar token", errInvalidCalendarToken)
+		return
+	}
+
+	now := time.Now().UTC()
+	filter := models.AppointmentsFilter{
+		StartsAt: now.Add(-calendarFeedPast),
+		EndsAt:   now.Add(calendarFeedFuture),
+		// canceled appointments are included so calendar apps remove them
+		Status: models.AppointmentStatusAll,
+	}
+
+	name := fmt.Sprintf("Trainer %d appointments", id)
+	filter.TrainerID = id
+	if owner == models.CalendarOwnerUser {
+		name = fmt.Sprintf("User %d appointments", id)
+		filter.TrainerID, filter.UserID = 0, id
+	}
+
+	appointments, err := cc.aRepo.GetScheduledAppointments(ctx, filter)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	calendar := ical.Calendar{Name: name, Events: make([]ical.Event, 0, len(appointments))}
+	for _, a := range appointments {
+		calendar.Events = append(calendar.Events, appointmentEvent(a))
+	}
+
+	var b bytes.Buffer
+	err = calendar.Encode(&b)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "error generating response", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b.Bytes())
+	return
+}
+
+// appointmentEvent is the appointment as a VEVENT, its UID is based on the appointment ID so it's the same every time
+// the feed is fetched and a rescheduled or canceled appointment updates the event instead of adding another one
+func appointmentEvent(a models.Appointment) ical.Event {
+	event := ical.Event{
+		UID:         fmt.Sprintf("appointment-%d@%s", a.ID, calendarUIDDomain),
+		Stamp:       a.UpdatedAt,
+		StartsAt:    a.StartsAt,
+		EndsAt:      a.EndsAt,
+		Summary:     "Training session",
+		Description: fmt.Sprintf("Appointment %d with trainer %d for user %d", a.ID, a.TrainerID, a.UserID),
+		Status:      ical.StatusConfirmed,
+		// versions start at 1, sequences start at 0
+		Sequence: int(a.Version - 1),
+	}
+
+	if a.CanceledAt != nil {
+		event.Status = ical.StatusCancelled
+	}
+
+	return event
+}
+
+// calendarFeedURL is the absolute URL of the owner's feed, X-Forwarded-Proto has the scheme the client used behind a proxy
+func calendarFeedURL(r *http.Request, owner models.CalendarOwner, id int64, token string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     r.Host,
+		Path:     fmt.Sprintf("/v1/%ss/%d/calendar.ics", owner, id),
+		RawQuery: url.Values{"token": []string{token}}.Encode(),
+	}
+
+	return u.String()
+}
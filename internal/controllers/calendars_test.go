@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestV1Calendars_CreateCalendarFeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		owner    models.CalendarOwner
+		id       string
+		proto    string
+		calRepo  repo.MockCalendars
+		response int
+		wantURL  string
+		errMsg   string
+	}{
+		{
+			name:     "happy path trainer",
+			owner:    models.CalendarOwnerTrainer,
+			id:       "1",
+			response: http.StatusCreated,
+			wantURL:  "http://api.example.com/v1/trainers/1/calendar.ics",
+		},
+		{
+			name:     "happy path user behind a proxy",
+			owner:    models.CalendarOwnerUser,
+			id:       "2",
+			proto:    "https",
+			response: http.StatusCreated,
+			wantURL:  "https://api.example.com/v1/users/2/calendar.ics",
+		},
+		{
+			name:  "fail not found",
+			owner: models.CalendarOwnerTrainer,
+			id:    "42",
+			calRepo: repo.MockCalendars{
+				SetCalendarTokenErr: errors.Wrap(sql.ErrNoRows, "error setting calendar token"),
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calController := NewV1CalendarsController(config, &tt.calRepo, &repo.MockAppointments{})
+
+			handler := http.HandlerFunc(calController.CreateTrainerCalendarFeed)
+			if tt.owner == models.CalendarOwnerUser {
+				handler = calController.CreateUserCalendarFeed
+			}
+
+			req, err := http.NewRequest("POST", "http://api.example.com/v1/"+string(tt.owner)+"s/"+tt.id+"/calendar-token", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			var feed models.CalendarFeed
+			err = json.Unmarshal(response.Body.Bytes(), &feed)
+			assert.NoError(t, err)
+
+			// the token saved is the one in the URL
+			assert.Len(t, tt.calRepo.SetToken, 64)
+			assert.Equal(t, tt.wantURL+"?"+url.Values{"token": []string{tt.calRepo.SetToken}}.Encode(), feed.URL)
+		})
+	}
+}
+
+func TestV1Calendars_GetCalendar(t *testing.T) {
+	canceledAt := time.Date(2022, 03, 16, 12, 0, 0, 0, time.UTC)
+	appointments := []models.Appointment{
+		{
+			ID:        1,
+			TrainerID: 1,
+			UserID:    2,
+			StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2022, 03, 15, 12, 0, 0, 0, time.UTC),
+			Version:   1,
+		},
+		{
+			ID:         2,
+			TrainerID:  1,
+			UserID:     3,
+			StartsAt:   time.Date(2022, 03, 18, 19, 0, 0, 0, time.UTC),
+			EndsAt:     time.Date(2022, 03, 18, 19, 30, 0, 0, time.UTC),
+			UpdatedAt:  canceledAt,
+			CanceledAt: &canceledAt,
+			Version:    2,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		owner     models.CalendarOwner
+		id        string
+		token     string
+		calRepo   repo.MockCalendars
+		aRepo     repo.MockAppointments
+		response  int
+		errMsg    string
+		wantLines []string
+	}{
+		{
+			name:  "happy path trainer",
+			owner: models.CalendarOwnerTrainer,
+			id:    "1",
+			token: "secret",
+			calRepo: repo.MockCalendars{
+				GetCalendarTokenResponse: "secret",
+			},
+			aRepo: repo.MockAppointments{
+				GetScheduledAppointmentsResponse: appointments,
+			},
+			response: http.StatusOK,
+			wantLines: []string{
+				"X-WR-CALNAME:Trainer 1 appointments",
+				"UID:appointment-1@appt-scheduling\r\nDTSTAMP:20220315T120000Z\r\nDTSTART:20220317T190000Z\r\nDTEND:20220317T193000Z",
+				"STATUS:CONFIRMED\r\nSEQUENCE:0",
+				"UID:appointment-2@appt-scheduling",
+				"STATUS:CANCELLED\r\nSEQUENCE:1",
+			},
+		},
+		{
+			name:  "happy path user without appointments",
+			owner: models.CalendarOwnerUser,
+			id:    "2",
+			token: "secret",
+			calRepo: repo.MockCalendars{
+				GetCalendarTokenResponse: "secret",
+			},
+			response: http.StatusOK,
+			wantLines: []string{
+				"X-WR-CALNAME:User 2 appointments",
+			},
+		},
+		{
+			name:  "fail wrong token",
+			owner: models.CalendarOwnerTrainer,
+			id:    "1",
+			token: "guess",
+			calRepo: repo.MockCalendars{
+				GetCalendarTokenResponse: "secret",
+			},
+			response: http.StatusForbidden,
+			errMsg:   "invalid calendar token",
+		},
+		{
+			name:  "fail missing token",
+			owner: models.CalendarOwnerTrainer,
+			id:    "1",
+			calRepo: repo.MockCalendars{
+				GetCalendarTokenResponse: "secret",
+			},
+			response: http.StatusForbidden,
+			errMsg:   "invalid calendar token",
+		},
+		{
+			name:     "fail feed not created",
+			owner:    models.CalendarOwnerUser,
+			id:       "1",
+			response: http.StatusForbidden,
+			errMsg:   "invalid calendar token",
+		},
+		{
+			name:  "fail not found",
+			owner: models.CalendarOwnerUser,
+			id:    "42",
+			token: "secret",
+			calRepo: repo.MockCalendars{
+				GetCalendarTokenErr: errors.Wrap(sql.ErrNoRows, "error getting calendar token"),
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+		{
+			name:  "fail getting appointments",
+			owner: models.CalendarOwnerTrainer,
+			id:    "1",
+			token: "secret",
+			calRepo: repo.MockCalendars{
+				GetCalendarTokenResponse: "secret",
+			},
+			aRepo: repo.MockAppointments{
+				GetScheduledAppointmentsErr: errors.New("connection refused"),
+			},
+			response: http.StatusInternalServerError,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calController := NewV1CalendarsController(config, &tt.calRepo, &tt.aRepo)
+
+			handler := http.HandlerFunc(calController.GetTrainerCalendar)
+			if tt.owner == models.CalendarOwnerUser {
+				handler = calController.GetUserCalendar
+			}
+
+			endpoint := "/" + string(tt.owner) + "s/" + tt.id + "/calendar.ics"
+			if tt.token != "" {
+				endpoint += "?token=" + tt.token
+			}
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			assert.Equal(t, "text/calendar; charset=utf-8", response.Header().Get("Content-Type"))
+
+			body := response.Body.String()
+			assert.Contains(t, body, "BEGIN:VCALENDAR\r\n")
+			for _, line := range tt.wantLines {
+				assert.Contains(t, body, line+"\r\n")
+			}
+		})
+	}
+}
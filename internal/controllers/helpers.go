@@ -2,17 +2,29 @@ package controllers
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
+// errIfMatchRequired is returned when changing an appointment without an If-Match header
+var errIfMatchRequired = errors.New("If-Match header with the appointment's ETag is required")
+
 type errorTyper interface {
 	ErrorType() string
 }
 
+type errorDetailer interface {
+	ErrorDetails() map[string]interface{}
+}
+
 func respondError(ctx context.Context, w http.ResponseWriter, status int, message string, causer error) {
 	w.Header().Set("Content-Type", "application/json")
 	resp := map[string]interface{}{
@@ -27,10 +39,14 @@ func respondError(ctx context.Context, w http.ResponseWriter, status int, messag
 		).Error("oops")
 	}
 
-	if typer, ok := causer.(errorTyper); ok {
+	if typer, ok := errors.Cause(causer).(errorTyper); ok {
 		resp["type"] = typer.ErrorType()
 	}
 
+	if detailer, ok := errors.Cause(causer).(errorDetailer); ok {
+		resp["details"] = detailer.ErrorDetails()
+	}
+
 	if errors.Cause(causer) == sql.ErrNoRows {
 		w.WriteHeader(http.StatusNotFound)
 	} else {
@@ -51,3 +67,50 @@ func respondModel(ctx context.Context, w http.ResponseWriter, status int, model
 	w.WriteHeader(status)
 	_, _ = w.Write(b)
 }
+
+// getPathID parses an int64 ID from the route variable with the given name
+func getPathID(r *http.Request, name string) (int64, error) {
+	idStr, ok := mux.Vars(r)[name]
+	if !ok {
+		return 0, errors.Errorf("missing %s in path", name)
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s in path", name)
+	}
+
+	return id, nil
+}
+
+// getIfMatchVersion parses the version from the If-Match header, an ETag from reading the appointment ex. "3"
+// returns errIfMatchRequired when the header is missing
+func getIfMatchVersion(r *http.Request) (int64, error) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return 0, errIfMatchRequired
+	}
+
+	// weak comparison is fine here, versions are the same either way
+	tag := strings.TrimPrefix(strings.TrimSpace(ifMatch), "W/")
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, errors.Errorf("invalid If-Match %s", ifMatch)
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, errors.Errorf("invalid If-Match %s", ifMatch)
+	}
+
+	return version, nil
+}
+
+// generateSecret is a random 32 byte secret, hex encoded, for webhook secrets and calendar tokens
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "error generating secret")
+	}
+
+	return hex.EncodeToString(b), nil
+}
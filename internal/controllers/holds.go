@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+	"time"
+)
+
+type V1HoldsController struct {
+	config *configuration.AppConfig
+	repo   repo.HoldsRepository
+	// appointments is used for the same checks as booking an appointment, and to offer released holds to the waitlist
+	appointments V1AppointmentsController
+}
+
+func NewV1HoldsController(c *configuration.AppConfig, hRepo repo.HoldsRepository, appointments V1AppointmentsController) V1HoldsController {
+	return V1HoldsController{
+		config:       c,
+		repo:         hRepo,
+		appointments: appointments,
+	}
+}
+
+func (h *V1HoldsController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/holds").Name("CreateHold").Handler(http.HandlerFunc(h.CreateHold)).Methods(http.MethodPost)
+	v1.Path("/holds/{id:[0-9]+}").Name("GetHold").Handler(http.HandlerFunc(h.GetHold)).Methods(http.MethodGet)
+	v1.Path("/holds/{id:[0-9]+}").Name("ReleaseHold").Handler(http.HandlerFunc(h.ReleaseHold)).Methods(http.MethodDelete)
+	v1.Path("/holds/{id:[0-9]+}/confirm").Name("ConfirmHold").Handler(http.HandlerFunc(h.ConfirmHold)).Methods(http.MethodPost)
+}
+
+// CreateHold reserves a time slot for the user, it is checked the same way as booking an appointment
+// while the hold is open nobody else can hold or book the time slot
+func (h *V1HoldsController) CreateHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	newHold := models.HoldCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&newHold)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	ttl, err := h.getTTL(newHold.TTLSeconds)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check ttl_seconds", err)
+		return
+	}
+
+	sessionType, err := h.appointments.getSessionType(ctx, newHold.SessionTypeID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			// not passing err along, respondError would turn sql.ErrNoRows into a 404
+			respondError(ctx, w, http.StatusBadRequest, "bad request payload, unknown session type", errors.Errorf("unknown session type %d", newHold.SessionTypeID))
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	schedule, err := h.appointments.getWorkingSchedule(ctx, newHold.TrainerID, newHold.StartsAt, newHold.EndsAt)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	err = validateRequest(newHold.AppointmentCreateRequest(), schedule, sessionType)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check times", err)
+		return
+	}
+
+	err = h.appointments.checkTrainer(ctx, newHold.TrainerID)
+	if err != nil {
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer can't be booked", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	hold, err := h.repo.CreateHold(ctx, newHold, ttl)
+	if err != nil {
+		if errors.As(err, &repo.SlotTakenError{}) {
+			respondError(ctx, w, http.StatusConflict, "time slot is already booked", err)
+			return
+		}
+
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer or user can't be booked", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, hold)
+	return
+}
+
+func (h *V1HoldsController) GetHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid hold ID", err)
+		return
+	}
+
+	hold, err := h.repo.GetHold(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, hold)
+	return
+}
+
+// ReleaseHold gives the time slot back before the hold expires, it is offered to the waitlist
+func (h *V1HoldsController) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid hold ID", err)
+		return
+	}
+
+	hold, err := h.repo.ReleaseHold(ctx, id)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrHoldNotOpen {
+			respondError(ctx, w, http.StatusConflict, "hold already released or confirmed", err)
+			return
+		}
+
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	h.appointments.offerFreedSlot(ctx, hold.TrainerID, hold.StartsAt, hold.EndsAt)
+
+	respondModel(ctx, w, http.StatusOK, hold)
+	return
+}
+
+// ConfirmHold books the held time slot as an appointment for the hold's user
+func (h *V1HoldsController) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid hold ID", err)
+		return
+	}
+
+	appointment, err := h.repo.ConfirmHold(ctx, id)
+	if err != nil {
+		switch errors.Cause(err) {
+		case repo.ErrHoldNotOpen:
+			respondError(ctx, w, http.StatusConflict, "hold already released or confirmed", err)
+			return
+		case repo.ErrHoldExpired:
+			respondError(ctx, w, http.StatusConflict, "hold has expired", err)
+			return
+		}
+
+		if errors.As(err, &repo.SlotTakenError{}) {
+			respondError(ctx, w, http.StatusConflict, "time slot is already booked", err)
+			return
+		}
+
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer or user can't be booked", err)
+			return
+		}
+
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, appointment)
+	return
+}
+
+// getTTL gets how long the hold lasts, the configured default when ttlSeconds is 0
+func (h *V1HoldsController) getTTL(ttlSeconds int) (time.Duration, error) {
+	if ttlSeconds == 0 {
+		return time.Duration(h.config.HoldTTLSeconds) * time.Second, nil
+	}
+
+	if ttlSeconds < 0 || ttlSeconds > h.config.MaxHoldTTLSeconds {
+		return 0, errors.Errorf("ttl_seconds must be between 1 and %d", h.config.MaxHoldTTLSeconds)
+	}
+
+	return time.Duration(ttlSeconds) * time.Second, nil
+}
@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestV1Holds_CreateHold(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		request []byte
+		hRepo   repo.MockHolds
+		trRepo  repo.MockTrainers
+	}
+
+	trainer := models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true}
+
+	// 3/17/2022 is a thursday, 19:00 UTC is noon pacific
+	holdRequest := []byte(`{
+		"trainer_id": 1,
+		"user_id": 1,
+		"starts_at": "2022-03-17T19:00:00Z",
+		"ends_at": "2022-03-17T19:30:00Z"
+	}`)
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+		errType  string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx:     context.TODO(),
+				request: holdRequest,
+				hRepo: repo.MockHolds{
+					CreateHoldResponse: models.Hold{ID: 1, TrainerID: 1, UserID: 1, ExpiresAt: time.Now().Add(10 * time.Minute)},
+				},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail already held or booked",
+			args: args{
+				ctx:     context.TODO(),
+				request: holdRequest,
+				hRepo: repo.MockHolds{
+					CreateHoldErr: repo.SlotTakenError{
+						TrainerID: 1,
+						StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+					},
+				},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusConflict,
+			errMsg:   "time slot is already booked",
+			errType:  "slot_taken",
+		},
+		{
+			name: "fail ttl too long",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"user_id": 1,
+					"starts_at": "2022-03-17T19:00:00Z",
+					"ends_at": "2022-03-17T19:30:00Z",
+					"ttl_seconds": 86400
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check ttl_seconds",
+		},
+		{
+			name: "fail outside working hours",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"user_id": 1,
+					"starts_at": "2022-03-17T05:00:00Z",
+					"ends_at": "2022-03-17T05:30:00Z"
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "fail inactive trainer",
+			args: args{
+				ctx:     context.TODO(),
+				request: holdRequest,
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: false},
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer can't be booked",
+			errType:  "inactive_trainer",
+		},
+		{
+			name: "fail unknown user",
+			args: args{
+				ctx:     context.TODO(),
+				request: holdRequest,
+				hRepo:   repo.MockHolds{CreateHoldErr: repo.ErrUnknownUser},
+				trRepo:  repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer or user can't be booked",
+			errType:  "unknown_user",
+		},
+	}
+
+	endpoint := "/holds"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holdsController := NewV1HoldsController(config, &tt.args.hRepo, NewV1AppointmentsController(config, &repo.MockAppointments{}, &repo.MockWorkingHours{}, &repo.MockTimeOff{}, &repo.MockSessionTypes{}, &tt.args.trRepo, &repo.MockWaitlist{}))
+
+			handler := http.HandlerFunc(holdsController.CreateHold)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]interface{})
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				if tt.errType != "" {
+					assert.Equal(t, tt.errType, resp["type"])
+				}
+			}
+		})
+	}
+}
+
+func TestV1Holds_ConfirmHold(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		id    string
+		hRepo repo.MockHolds
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+		errType  string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				hRepo: repo.MockHolds{
+					ConfirmHoldResponse: models.Appointment{ID: 1, TrainerID: 1, UserID: 1},
+				},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail expired",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "1",
+				hRepo: repo.MockHolds{ConfirmHoldErr: repo.ErrHoldExpired},
+			},
+			response: http.StatusConflict,
+			errMsg:   "hold has expired",
+			errType:  "hold_expired",
+		},
+		{
+			name: "fail already confirmed",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "1",
+				hRepo: repo.MockHolds{ConfirmHoldErr: repo.ErrHoldNotOpen},
+			},
+			response: http.StatusConflict,
+			errMsg:   "hold already released or confirmed",
+			errType:  "hold_not_open",
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "100",
+				hRepo: repo.MockHolds{ConfirmHoldErr: errors.Wrap(sql.ErrNoRows, "error confirming hold")},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/holds/{id}/confirm"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holdsController := NewV1HoldsController(config, &tt.args.hRepo, NewV1AppointmentsController(config, &repo.MockAppointments{}, &repo.MockWorkingHours{}, &repo.MockTimeOff{}, &repo.MockSessionTypes{}, &repo.MockTrainers{}, &repo.MockWaitlist{}))
+
+			handler := http.HandlerFunc(holdsController.ConfirmHold)
+
+			req, err := http.NewRequest("POST", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]interface{})
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				if tt.errType != "" {
+					assert.Equal(t, tt.errType, resp["type"])
+				}
+			}
+		})
+	}
+}
+
+func TestV1Holds_ReleaseHold(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		id    string
+		hRepo repo.MockHolds
+	}
+
+	releasedAt := time.Now()
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				hRepo: repo.MockHolds{
+					ReleaseHoldResponse: models.Hold{ID: 1, TrainerID: 1, UserID: 1, ReleasedAt: &releasedAt},
+				},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail already released",
+			args: args{
+				ctx:   context.TODO(),
+				id:    "1",
+				hRepo: repo.MockHolds{ReleaseHoldErr: repo.ErrHoldNotOpen},
+			},
+			response: http.StatusConflict,
+			errMsg:   "hold already released or confirmed",
+		},
+	}
+
+	endpoint := "/holds/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holdsController := NewV1HoldsController(config, &tt.args.hRepo, NewV1AppointmentsController(config, &repo.MockAppointments{}, &repo.MockWorkingHours{}, &repo.MockTimeOff{}, &repo.MockSessionTypes{}, &repo.MockTrainers{}, &repo.MockWaitlist{}))
+
+			handler := http.HandlerFunc(holdsController.ReleaseHold)
+
+			req, err := http.NewRequest("DELETE", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]interface{})
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+const (
+	// idempotencyKeyHeader is the header clients send so retrying a request doesn't repeat it
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader is set on responses replayed for a retry
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// responseCapture is a http.ResponseWriter keeping the response, so it can be saved for retries before it is sent
+type responseCapture struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseCapture() *responseCapture {
+	return &responseCapture{header: http.Header{}, status: http.StatusOK}
+}
+
+func (c *responseCapture) Header() http.Header {
+	return c.header
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	return c.body.Write(b)
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+}
+
+// idempotent handles the request once per Idempotency-Key. a retry with the same key and body gets the saved response,
+// a retry with a different body is a 422. 5xx responses aren't saved, so the request can be retried
+func (a *V1AppointmentsController) idempotent(w http.ResponseWriter, r *http.Request, key string, handler http.HandlerFunc) {
+	ctx := r.Context()
+
+	if len(key) > maxIdempotencyKeyLength {
+		respondError(ctx, w, http.StatusBadRequest, "invalid Idempotency-Key", errors.Errorf("Idempotency-Key can be at most %d characters", maxIdempotencyKeyLength))
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	hash := sha256.Sum256(body)
+	endpoint := r.Method + " " + r.URL.Path
+
+	saved, err := a.repo.ClaimIdempotencyKey(ctx, endpoint, key, hex.EncodeToString(hash[:]))
+	if err != nil {
+		switch errors.Cause(err) {
+		case repo.ErrIdempotencyKeyReused:
+			respondError(ctx, w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request", err)
+			return
+		case repo.ErrIdempotencyKeyInProgress:
+			respondError(ctx, w, http.StatusConflict, "request with the Idempotency-Key is in progress, try again", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	if saved.ResponseStatus != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(idempotentReplayedHeader, "true")
+		w.WriteHeader(*saved.ResponseStatus)
+		_, _ = w.Write(saved.ResponseBody)
+		return
+	}
+
+	capture := newResponseCapture()
+	handler(capture, r)
+
+	if capture.status >= http.StatusInternalServerError {
+		err = a.repo.ReleaseIdempotencyKey(ctx, endpoint, key)
+	} else {
+		err = a.repo.SaveIdempotentResponse(ctx, endpoint, key, capture.status, capture.body.Bytes())
+	}
+
+	// the request was already handled, so the response is sent either way
+	if err != nil {
+		log.Printf("error finishing idempotency key %s: %v\n", key, err)
+	}
+
+	for name, values := range capture.header {
+		w.Header()[name] = values
+	}
+
+	w.WriteHeader(capture.status)
+	_, _ = w.Write(capture.body.Bytes())
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestV1Appointments_CreateAppointmentIdempotency(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		key    string
+		aRepo  repo.MockAppointments
+		trRepo repo.MockTrainers
+	}
+
+	trainer := models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true}
+	created := models.Appointment{
+		ID:        1,
+		TrainerID: 1,
+		UserID:    1,
+		StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+	}
+
+	replayedStatus := http.StatusCreated
+	replayedBody := []byte(`{"id":7,"trainer_id":1,"user_id":1,"starts_at":"2022-03-17T19:00:00Z","ends_at":"2022-03-17T19:30:00Z"}`)
+
+	tests := []struct {
+		name         string
+		args         args
+		response     int
+		errMsg       string
+		errType      string
+		wantID       int64
+		wantReplayed bool
+	}{
+		{
+			name: "happy path first request",
+			args: args{
+				ctx: context.TODO(),
+				key: "b8d3d4c2-first",
+				aRepo: repo.MockAppointments{
+					ClaimIdempotencyKeyResponse: models.IdempotencyKey{Key: "b8d3d4c2-first"},
+					CreateAppointmentsResponse:  created,
+				},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusCreated,
+			wantID:   1,
+		},
+		{
+			name: "happy path retry replays the first response",
+			args: args{
+				ctx: context.TODO(),
+				key: "b8d3d4c2-retry",
+				aRepo: repo.MockAppointments{
+					ClaimIdempotencyKeyResponse: models.IdempotencyKey{
+						Key:            "b8d3d4c2-retry",
+						ResponseStatus: &replayedStatus,
+						ResponseBody:   replayedBody,
+					},
+					// booking again would fail, the replay doesn't book
+					CreateAppointmentsErr: repo.SlotTakenError{TrainerID: 1},
+				},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response:     http.StatusCreated,
+			wantID:       7,
+			wantReplayed: true,
+		},
+		{
+			name: "happy path saving the response fails",
+			args: args{
+				ctx: context.TODO(),
+				key: "b8d3d4c2-save",
+				aRepo: repo.MockAppointments{
+					ClaimIdempotencyKeyResponse: models.IdempotencyKey{Key: "b8d3d4c2-save"},
+					CreateAppointmentsResponse:  created,
+					SaveIdempotentResponseErr:   errors.New("connection reset"),
+				},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusCreated,
+			wantID:   1,
+		},
+		{
+			name: "fail key reused with a different body",
+			args: args{
+				ctx:   context.TODO(),
+				key:   "b8d3d4c2-reused",
+				aRepo: repo.MockAppointments{ClaimIdempotencyKeyErr: repo.ErrIdempotencyKeyReused},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "Idempotency-Key was already used for a different request",
+			errType:  "idempotency_key_reused",
+		},
+		{
+			name: "fail first request in progress",
+			args: args{
+				ctx:   context.TODO(),
+				key:   "b8d3d4c2-in-progress",
+				aRepo: repo.MockAppointments{ClaimIdempotencyKeyErr: repo.ErrIdempotencyKeyInProgress},
+			},
+			response: http.StatusConflict,
+			errMsg:   "request with the Idempotency-Key is in progress, try again",
+			errType:  "idempotency_key_in_progress",
+		},
+		{
+			name: "fail key too long",
+			args: args{
+				ctx: context.TODO(),
+				key: strings.Repeat("a", 256),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid Idempotency-Key",
+		},
+	}
+
+	request := []byte(`{
+		"user_id": 1,
+		"trainer_id": 1,
+		"starts_at": "2022-03-17T19:00:00Z",
+		"ends_at": "2022-03-17T19:30:00Z"
+	}`)
+
+	endpoint := "/appointments"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRepo = &tt.args.aRepo
+
+			appointmentsController = NewV1AppointmentsController(config, aRepo, &repo.MockWorkingHours{}, &repo.MockTimeOff{}, &repo.MockSessionTypes{}, &tt.args.trRepo, &repo.MockWaitlist{})
+
+			handler := http.HandlerFunc(appointmentsController.CreateAppointment)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Idempotency-Key", tt.args.key)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				assert.Equal(t, tt.errType, resp["type"])
+				return
+			}
+
+			appointment := models.Appointment{}
+			err = json.Unmarshal(response.Body.Bytes(), &appointment)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.wantID, appointment.ID)
+			assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantReplayed, response.Header().Get("Idempotent-Replayed") == "true")
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+)
+
+type V1SessionTypesController struct {
+	config *configuration.AppConfig
+	repo   repo.SessionTypesRepository
+}
+
+func NewV1SessionTypesController(c *configuration.AppConfig, stRepo repo.SessionTypesRepository) V1SessionTypesController {
+	return V1SessionTypesController{
+		config: c,
+		repo:   stRepo,
+	}
+}
+
+func (st *V1SessionTypesController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/session-types").Name("ListSessionTypes").Handler(http.HandlerFunc(st.ListSessionTypes)).Methods(http.MethodGet)
+	v1.Path("/session-types").Name("CreateSessionType").Handler(http.HandlerFunc(st.CreateSessionType)).Methods(http.MethodPost)
+	v1.Path("/session-types/{id:[0-9]+}").Name("GetSessionType").Handler(http.HandlerFunc(st.GetSessionType)).Methods(http.MethodGet)
+}
+
+func (st *V1SessionTypesController) ListSessionTypes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sessionTypes, err := st.repo.ListSessionTypes(ctx)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, sessionTypes)
+	return
+}
+
+func (st *V1SessionTypesController) GetSessionType(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid session type ID", err)
+		return
+	}
+
+	sessionType, err := st.repo.GetSessionType(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, sessionType)
+	return
+}
+
+func (st *V1SessionTypesController) CreateSessionType(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	newSessionType := models.SessionTypeCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&newSessionType)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = newSessionType.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check duration and granularity", err)
+		return
+	}
+
+	sessionType, err := st.repo.CreateSessionType(ctx, newSessionType)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, sessionType)
+	return
+}
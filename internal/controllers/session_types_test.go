@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestV1SessionTypes_CreateSessionType(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		request []byte
+		stRepo  repo.MockSessionTypes
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"name": "45 minute session",
+					"duration_minutes": 45,
+					"slot_granularity_minutes": 15
+				}`),
+				stRepo: repo.MockSessionTypes{
+					CreateSessionTypeResponse: models.SessionType{
+						ID:                     5,
+						Name:                   "45 minute session",
+						DurationMinutes:        45,
+						SlotGranularityMinutes: 15,
+					}},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail granularity doesn't divide an hour",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"name": "45 minute session",
+					"duration_minutes": 45,
+					"slot_granularity_minutes": 45
+				}`),
+				stRepo: repo.MockSessionTypes{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check duration and granularity",
+		},
+		{
+			name: "fail missing duration",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"name": "no duration",
+					"slot_granularity_minutes": 30
+				}`),
+				stRepo: repo.MockSessionTypes{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check duration and granularity",
+		},
+		{
+			name: "fail missing name",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"duration_minutes": 60,
+					"slot_granularity_minutes": 30
+				}`),
+				stRepo: repo.MockSessionTypes{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check duration and granularity",
+		},
+	}
+
+	endpoint := "/session-types"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stController := NewV1SessionTypesController(config, &tt.args.stRepo)
+
+			handler := http.HandlerFunc(stController.CreateSessionType)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1SessionTypes_GetSessionType(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		id     string
+		stRepo repo.MockSessionTypes
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				stRepo: repo.MockSessionTypes{
+					GetSessionTypeResponse: models.SessionType{
+						ID:                     1,
+						Name:                   "30 minute session",
+						DurationMinutes:        30,
+						SlotGranularityMinutes: 30,
+					}},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "100",
+				stRepo: repo.MockSessionTypes{
+					GetSessionTypeErr: errors.Wrap(sql.ErrNoRows, "error getting session type"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/session-types/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stController := NewV1SessionTypesController(config, &tt.args.stRepo)
+
+			handler := http.HandlerFunc(stController.GetSessionType)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
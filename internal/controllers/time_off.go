@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/ical"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxCalendarImportBytes is the largest calendar that can be imported, exports of a few years of events fit easily
+const maxCalendarImportBytes = 2 << 20
+
+// importedTimeOffReason is the reason on imported time off, event summaries are personal so they aren't kept
+const importedTimeOffReason = "busy (imported calendar)"
+
+// importRecurrenceDays is how far ahead recurring events are imported, importing the calendar again later moves it forward
+// occurrences that already ended aren't imported
+const importRecurrenceDays = 365
+
+// errCalendarTooLarge is returned for a calendar over maxCalendarImportBytes
+var errCalendarTooLarge = errors.New("calendar is too large")
+
+type V1TimeOffController struct {
+	config *configuration.AppConfig
+	repo   repo.TimeOffRepository
+}
+
+func NewV1TimeOffController(c *configuration.AppConfig, toRepo repo.TimeOffRepository) V1TimeOffController {
+	return V1TimeOffController{
+		config: c,
+		repo:   toRepo,
+	}
+}
+
+func (to *V1TimeOffController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/time-off").Name("ListTimeOff").Handler(http.HandlerFunc(to.ListTimeOff)).Methods(http.MethodGet)
+	v1.Path("/time-off").Name("CreateTimeOff").Handler(http.HandlerFunc(to.CreateTimeOff)).Methods(http.MethodPost)
+	v1.Path("/time-off/{id:[0-9]+}").Name("DeleteTimeOff").Handler(http.HandlerFunc(to.DeleteTimeOff)).Methods(http.MethodDelete)
+	v1.Path("/trainers/{id:[0-9]+}/time-off/import").Name("ImportTrainerTimeOff").Handler(http.HandlerFunc(to.ImportTrainerTimeOff)).Methods(http.MethodPut)
+}
+
+func (to *V1TimeOffController) CreateTimeOff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	newTimeOff := models.TimeOffCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&newTimeOff)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = validateTimeOffRequest(newTimeOff)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check times", err)
+		return
+	}
+
+	timeOff, err := to.repo.CreateTimeOff(ctx, newTimeOff)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, timeOff)
+	return
+}
+
+func validateTimeOffRequest(timeOff models.TimeOffCreateRequest) error {
+	if timeOff.TrainerID != nil && *timeOff.TrainerID <= 0 {
+		return errors.New("invalid trainer Id")
+	}
+
+	if timeOff.StartsAt.IsZero() || timeOff.EndsAt.IsZero() {
+		return errors.New("starts_at and ends_at are required")
+	}
+
+	if !timeOff.StartsAt.Before(timeOff.EndsAt) {
+		return errors.New("starts_at must be before ends_at")
+	}
+
+	return nil
+}
+
+func (to *V1TimeOffController) ListTimeOff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	queryParams := r.URL.Query()
+
+	trainerID, err := getTrainerID(queryParams)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	startsAt, endsAt, err := getTimeRange(queryParams)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid time range values", err)
+		return
+	}
+
+	timeOff, err := to.repo.ListTimeOff(ctx, models.TimeOffFilter{
+		TrainerID: trainerID,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+	})
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, timeOff)
+	return
+}
+
+func (to *V1TimeOffController) DeleteTimeOff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid time off ID", err)
+		return
+	}
+
+	err = to.repo.DeleteTimeOff(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
+
+// ImportTrainerTimeOff replaces the trainer's imported time off with the busy times in an .ics calendar, its events,
+// the next importRecurrenceDays of its recurring events and its free/busy periods. the calendar is the request body,
+// or the file field of a multipart form when uploaded from a browser
+// time off that wasn't imported is kept, and importing the same calendar again ends up with the same time off
+func (to *V1TimeOffController) ImportTrainerTimeOff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	loc, err := getTimezone(r.URL.Query())
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid timezone", err)
+		return
+	}
+
+	calendar, err := readCalendarUpload(w, r)
+	if err != nil {
+		if errors.Cause(err) == errCalendarTooLarge {
+			respondError(ctx, w, http.StatusRequestEntityTooLarge, "calendar is too large", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, expected an .ics calendar", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	busy, err := ical.ParseBusy(bytes.NewReader(calendar), loc, now, now.AddDate(0, 0, importRecurrenceDays))
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid calendar, "+err.Error(), err)
+		return
+	}
+
+	timeOff := make([]models.TimeOffCreateRequest, 0, len(busy))
+	for _, b := range busy {
+		timeOff = append(timeOff, models.TimeOffCreateRequest{
+			TrainerID: &id,
+			StartsAt:  b.StartsAt,
+			EndsAt:    b.EndsAt,
+			Reason:    importedTimeOffReason,
+		})
+	}
+
+	imported, err := to.repo.ReplaceImportedTimeOff(ctx, id, timeOff)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, imported)
+	return
+}
+
+// readCalendarUpload reads the calendar from the file field of a multipart form, or the whole body otherwise
+func readCalendarUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	var body io.Reader = r.Body
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		// the form's boundaries and other fields get some room on top of the calendar
+		r.Body = http.MaxBytesReader(w, r.Body, 2*maxCalendarImportBytes)
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading calendar upload")
+		}
+		defer file.Close()
+
+		body = file
+	}
+
+	// read one byte past the limit to tell a calendar that's exactly the limit from one that's over it
+	calendar, err := io.ReadAll(io.LimitReader(body, maxCalendarImportBytes+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading calendar upload")
+	}
+
+	if len(calendar) > maxCalendarImportBytes {
+		return nil, errCalendarTooLarge
+	}
+
+	return calendar, nil
+}
+
+// getTimezone reads the timezone param, imported dates and times without a timezone are in it. defaults to models.DefaultTimezone
+func getTimezone(queryParams url.Values) (*time.Location, error) {
+	timezone := queryParams.Get("timezone")
+	if timezone == "" {
+		timezone = models.DefaultTimezone
+	}
+
+	return time.LoadLocation(timezone)
+}
@@ -0,0 +1,433 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestV1TimeOff_CreateTimeOff(t *testing.T) {
+	trainerID := int64(1)
+
+	type args struct {
+		ctx     context.Context
+		request []byte
+		toRepo  repo.MockTimeOff
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path trainer time off",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"starts_at": "2022-03-21T00:00:00-07:00",
+					"ends_at": "2022-03-26T00:00:00-07:00",
+					"reason": "vacation"
+				}`),
+				toRepo: repo.MockTimeOff{
+					CreateTimeOffResponse: models.TimeOff{
+						ID:        1,
+						TrainerID: &trainerID,
+						StartsAt:  time.Date(2022, 03, 21, 7, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 26, 7, 0, 0, 0, time.UTC),
+						Reason:    "vacation",
+					}},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "happy path gym-wide holiday",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"starts_at": "2022-07-04T00:00:00-07:00",
+					"ends_at": "2022-07-05T00:00:00-07:00",
+					"reason": "independence day"
+				}`),
+				toRepo: repo.MockTimeOff{
+					CreateTimeOffResponse: models.TimeOff{
+						ID:       2,
+						StartsAt: time.Date(2022, 07, 4, 7, 0, 0, 0, time.UTC),
+						EndsAt:   time.Date(2022, 07, 5, 7, 0, 0, 0, time.UTC),
+						Reason:   "independence day",
+					}},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail ends before starts",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"starts_at": "2022-03-26T00:00:00-07:00",
+					"ends_at": "2022-03-21T00:00:00-07:00"
+				}`),
+				toRepo: repo.MockTimeOff{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "fail missing times",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1
+				}`),
+				toRepo: repo.MockTimeOff{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+	}
+
+	endpoint := "/time-off"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toController := NewV1TimeOffController(config, &tt.args.toRepo)
+
+			handler := http.HandlerFunc(toController.CreateTimeOff)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1TimeOff_ListTimeOff(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		query  url.Values
+		toRepo repo.MockTimeOff
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"1"},
+					"starts_at":  []string{"2022-03-01T00:00:00Z"},
+					"ends_at":    []string{"2022-04-01T00:00:00Z"},
+				},
+				toRepo: repo.MockTimeOff{
+					ListTimeOffResponse: []models.TimeOff{}},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail invalid trainer id",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"trainer_id": []string{"abc"},
+				},
+				toRepo: repo.MockTimeOff{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid trainer ID",
+		},
+	}
+
+	endpoint := "/time-off"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toController := NewV1TimeOffController(config, &tt.args.toRepo)
+
+			handler := http.HandlerFunc(toController.ListTimeOff)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.URL.RawQuery = tt.args.query.Encode()
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1TimeOff_DeleteTimeOff(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		id     string
+		toRepo repo.MockTimeOff
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx:    context.TODO(),
+				id:     "1",
+				toRepo: repo.MockTimeOff{},
+			},
+			response: http.StatusNoContent,
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				toRepo: repo.MockTimeOff{
+					DeleteTimeOffErr: errors.Wrap(sql.ErrNoRows, "error deleting time off"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/time-off/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toController := NewV1TimeOffController(config, &tt.args.toRepo)
+
+			handler := http.HandlerFunc(toController.DeleteTimeOff)
+
+			req, err := http.NewRequest("DELETE", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusNoContent {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1TimeOff_ImportTrainerTimeOff(t *testing.T) {
+	calendar, err := os.ReadFile("../ical/testdata/busy.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a multipart form like a browser upload, with the calendar in the file field
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", "busy.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = part.Write(calendar)
+	_ = writer.Close()
+
+	var missingFile bytes.Buffer
+	missingWriter := multipart.NewWriter(&missingFile)
+	_ = missingWriter.WriteField("name", "busy.ics")
+	_ = missingWriter.Close()
+
+	type args struct {
+		id          string
+		query       string
+		contentType string
+		request     []byte
+		toRepo      repo.MockTimeOff
+	}
+
+	tests := []struct {
+		name      string
+		args      args
+		response  int
+		errMsg    string
+		wantCount int
+		// wantLunch is when the floating lunch event starts, it's in the timezone param
+		wantLunch time.Time
+	}{
+		{
+			name: "happy path",
+			args: args{
+				id:          "1",
+				contentType: "text/calendar",
+				request:     calendar,
+			},
+			response:  http.StatusOK,
+			wantCount: 7,
+			wantLunch: time.Date(2022, 03, 19, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "happy path upload with timezone",
+			args: args{
+				id:          "1",
+				query:       "?timezone=America/New_York",
+				contentType: writer.FormDataContentType(),
+				request:     form.Bytes(),
+			},
+			response:  http.StatusOK,
+			wantCount: 7,
+			wantLunch: time.Date(2022, 03, 19, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "happy path empty calendar clears the import",
+			args: args{
+				id:      "1",
+				request: []byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nEND:VCALENDAR\r\n"),
+			},
+			response:  http.StatusOK,
+			wantCount: 0,
+		},
+		{
+			name: "fail invalid timezone",
+			args: args{
+				id:      "1",
+				query:   "?timezone=Mars/Olympus_Mons",
+				request: calendar,
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid timezone",
+		},
+		{
+			name: "fail not a calendar",
+			args: args{
+				id:      "1",
+				request: []byte(`{"starts_at": "2022-03-21T00:00:00-07:00"}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid calendar, not an iCalendar file, it has to start with BEGIN:VCALENDAR",
+		},
+		{
+			name: "fail unsupported recurring event",
+			args: args{
+				id:      "1",
+				request: []byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nDTSTART:20220317T190000Z\r\nRRULE:FREQ=HOURLY\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid calendar, line 5: unsupported RRULE FREQ=HOURLY",
+		},
+		{
+			name: "fail upload without a file",
+			args: args{
+				id:          "1",
+				contentType: missingWriter.FormDataContentType(),
+				request:     missingFile.Bytes(),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, expected an .ics calendar",
+		},
+		{
+			name: "fail too large",
+			args: args{
+				id:      "1",
+				request: bytes.Repeat([]byte("X"), maxCalendarImportBytes+1),
+			},
+			response: http.StatusRequestEntityTooLarge,
+			errMsg:   "calendar is too large",
+		},
+		{
+			name: "fail unknown trainer",
+			args: args{
+				id:      "42",
+				request: calendar,
+				toRepo: repo.MockTimeOff{
+					ReplaceImportedTimeOffErr: errors.Wrap(sql.ErrNoRows, "error importing time off"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/trainers/{id}/time-off/import"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toController := NewV1TimeOffController(config, &tt.args.toRepo)
+
+			handler := http.HandlerFunc(toController.ImportTrainerTimeOff)
+
+			req, err := http.NewRequest("PUT", endpoint+tt.args.query, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.args.contentType != "" {
+				req.Header.Set("Content-Type", tt.args.contentType)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			// the repo gets every busy time as the trainer's time off, without the event details
+			if !assert.Len(t, tt.args.toRepo.Imported, tt.wantCount) || tt.wantCount == 0 {
+				return
+			}
+
+			for _, to := range tt.args.toRepo.Imported {
+				assert.Equal(t, int64(1), *to.TrainerID)
+				assert.Equal(t, importedTimeOffReason, to.Reason)
+			}
+
+			lunch := tt.args.toRepo.Imported[2]
+			assert.True(t, tt.wantLunch.Equal(lunch.StartsAt), "lunch starts at %s, want %s", lunch.StartsAt, tt.wantLunch)
+		})
+	}
+}
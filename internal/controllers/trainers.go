@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type V1TrainersController struct {
+	config *configuration.AppConfig
+	repo   repo.TrainersRepository
+}
+
+func NewV1TrainersController(c *configuration.AppConfig, trRepo repo.TrainersRepository) V1TrainersController {
+	return V1TrainersController{
+		config: c,
+		repo:   trRepo,
+	}
+}
+
+func (tc *V1TrainersController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/trainers").Name("ListTrainers").Handler(http.HandlerFunc(tc.ListTrainers)).Methods(http.MethodGet)
+	v1.Path("/trainers").Name("CreateTrainer").Handler(http.HandlerFunc(tc.CreateTrainer)).Methods(http.MethodPost)
+	v1.Path("/trainers/{id:[0-9]+}").Name("GetTrainer").Handler(http.HandlerFunc(tc.GetTrainer)).Methods(http.MethodGet)
+	v1.Path("/trainers/{id:[0-9]+}").Name("UpdateTrainer").Handler(http.HandlerFunc(tc.UpdateTrainer)).Methods(http.MethodPut)
+	v1.Path("/trainers/{id:[0-9]+}").Name("DeactivateTrainer").Handler(http.HandlerFunc(tc.DeactivateTrainer)).Methods(http.MethodDelete)
+}
+
+func (tc *V1TrainersController) ListTrainers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	activeOnly, err := getActiveOnly(r.URL.Query())
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid active value", err)
+		return
+	}
+
+	trainers, err := tc.repo.ListTrainers(ctx, activeOnly)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, trainers)
+	return
+}
+
+func (tc *V1TrainersController) GetTrainer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	trainer, err := tc.repo.GetTrainer(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, trainer)
+	return
+}
+
+func (tc *V1TrainersController) CreateTrainer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	newTrainer := models.TrainerRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&newTrainer)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = newTrainer.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check name and email", err)
+		return
+	}
+
+	trainer, err := tc.repo.CreateTrainer(ctx, newTrainer)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrEmailTaken {
+			respondError(ctx, w, http.StatusConflict, "email is already in use", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, trainer)
+	return
+}
+
+func (tc *V1TrainersController) UpdateTrainer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	trainerRequest := models.TrainerRequest{}
+	err = json.NewDecoder(r.Body).Decode(&trainerRequest)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = trainerRequest.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check name and email", err)
+		return
+	}
+
+	trainer, err := tc.repo.UpdateTrainer(ctx, id, trainerRequest)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrEmailTaken {
+			respondError(ctx, w, http.StatusConflict, "email is already in use", err)
+			return
+		}
+
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, trainer)
+	return
+}
+
+// DeactivateTrainer soft deletes the trainer, appointments reference trainers so they are never removed
+func (tc *V1TrainersController) DeactivateTrainer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	trainer, err := tc.repo.DeactivateTrainer(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, trainer)
+	return
+}
+
+// getActiveOnly reads the active param, without it inactive records are included
+func getActiveOnly(queryParams url.Values) (bool, error) {
+	activeStr := queryParams.Get("active")
+	if activeStr == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(activeStr)
+}
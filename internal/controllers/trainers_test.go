@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestV1Trainers_CreateTrainer(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		request []byte
+		trRepo  repo.MockTrainers
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"name": "Jane Doe",
+					"email": "jane@example.com"
+				}`),
+				trRepo: repo.MockTrainers{
+					CreateTrainerResponse: models.Trainer{ID: 1, Name: "Jane Doe", Email: "jane@example.com", Active: true},
+				},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail missing name",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"email": "jane@example.com"
+				}`),
+				trRepo: repo.MockTrainers{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check name and email",
+		},
+		{
+			name: "fail invalid email",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"name": "Jane Doe",
+					"email": "jane"
+				}`),
+				trRepo: repo.MockTrainers{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check name and email",
+		},
+		{
+			name: "fail email taken",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"name": "Jane Doe",
+					"email": "jane@example.com"
+				}`),
+				trRepo: repo.MockTrainers{
+					CreateTrainerErr: repo.ErrEmailTaken,
+				},
+			},
+			response: http.StatusConflict,
+			errMsg:   "email is already in use",
+		},
+	}
+
+	endpoint := "/trainers"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trController := NewV1TrainersController(config, &tt.args.trRepo)
+
+			handler := http.HandlerFunc(trController.CreateTrainer)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1Trainers_ListTrainers(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		query  url.Values
+		trRepo repo.MockTrainers
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path active only",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"active": []string{"true"},
+				},
+				trRepo: repo.MockTrainers{
+					ListTrainersResponse: []models.Trainer{{ID: 1, Name: "Jane Doe", Email: "jane@example.com", Active: true}},
+				},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail invalid active",
+			args: args{
+				ctx: context.TODO(),
+				query: url.Values{
+					"active": []string{"sometimes"},
+				},
+				trRepo: repo.MockTrainers{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid active value",
+		},
+	}
+
+	endpoint := "/trainers"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trController := NewV1TrainersController(config, &tt.args.trRepo)
+
+			handler := http.HandlerFunc(trController.ListTrainers)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.URL.RawQuery = tt.args.query.Encode()
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1Trainers_DeactivateTrainer(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		id     string
+		trRepo repo.MockTrainers
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				trRepo: repo.MockTrainers{
+					DeactivateTrainerResponse: models.Trainer{ID: 1, Name: "Jane Doe", Email: "jane@example.com", Active: false},
+				},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "100",
+				trRepo: repo.MockTrainers{
+					DeactivateTrainerErr: errors.Wrap(sql.ErrNoRows, "error deactivating trainer"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/trainers/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trController := NewV1TrainersController(config, &tt.args.trRepo)
+
+			handler := http.HandlerFunc(trController.DeactivateTrainer)
+
+			req, err := http.NewRequest("DELETE", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			trainer := models.Trainer{}
+			err = json.Unmarshal(response.Body.Bytes(), &trainer)
+			assert.False(t, trainer.Active)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+)
+
+type V1UsersController struct {
+	config *configuration.AppConfig
+	repo   repo.UsersRepository
+}
+
+func NewV1UsersController(c *configuration.AppConfig, usRepo repo.UsersRepository) V1UsersController {
+	return V1UsersController{
+		config: c,
+		repo:   usRepo,
+	}
+}
+
+func (uc *V1UsersController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/users").Name("ListUsers").Handler(http.HandlerFunc(uc.ListUsers)).Methods(http.MethodGet)
+	v1.Path("/users").Name("CreateUser").Handler(http.HandlerFunc(uc.CreateUser)).Methods(http.MethodPost)
+	v1.Path("/users/{id:[0-9]+}").Name("GetUser").Handler(http.HandlerFunc(uc.GetUser)).Methods(http.MethodGet)
+	v1.Path("/users/{id:[0-9]+}").Name("UpdateUser").Handler(http.HandlerFunc(uc.UpdateUser)).Methods(http.MethodPut)
+	v1.Path("/users/{id:[0-9]+}").Name("DeactivateUser").Handler(http.HandlerFunc(uc.DeactivateUser)).Methods(http.MethodDelete)
+}
+
+func (uc *V1UsersController) ListUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	activeOnly, err := getActiveOnly(r.URL.Query())
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid active value", err)
+		return
+	}
+
+	users, err := uc.repo.ListUsers(ctx, activeOnly)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, users)
+	return
+}
+
+func (uc *V1UsersController) GetUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	user, err := uc.repo.GetUser(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, user)
+	return
+}
+
+func (uc *V1UsersController) CreateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	newUser := models.UserRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&newUser)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = newUser.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check name and email", err)
+		return
+	}
+
+	user, err := uc.repo.CreateUser(ctx, newUser)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrEmailTaken {
+			respondError(ctx, w, http.StatusConflict, "email is already in use", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, user)
+	return
+}
+
+func (uc *V1UsersController) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	userRequest := models.UserRequest{}
+	err = json.NewDecoder(r.Body).Decode(&userRequest)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = userRequest.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check name and email", err)
+		return
+	}
+
+	user, err := uc.repo.UpdateUser(ctx, id, userRequest)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrEmailTaken {
+			respondError(ctx, w, http.StatusConflict, "email is already in use", err)
+			return
+		}
+
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, user)
+	return
+}
+
+// DeactivateUser soft deletes the user, appointments reference users so they are never removed
+func (uc *V1UsersController) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	user, err := uc.repo.DeactivateUser(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, user)
+	return
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestV1Users_UpdateUser(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		id      string
+		request []byte
+		usRepo  repo.MockUsers
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"name": "John Doe",
+					"email": "john@example.com",
+					"active": false
+				}`),
+				usRepo: repo.MockUsers{
+					UpdateUserResponse: models.User{ID: 1, Name: "John Doe", Email: "john@example.com", Active: false},
+				},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail invalid email",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"name": "John Doe",
+					"email": "@"
+				}`),
+				usRepo: repo.MockUsers{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check name and email",
+		},
+		{
+			name: "fail email taken",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				request: []byte(`{
+					"name": "John Doe",
+					"email": "john@example.com"
+				}`),
+				usRepo: repo.MockUsers{
+					UpdateUserErr: repo.ErrEmailTaken,
+				},
+			},
+			response: http.StatusConflict,
+			errMsg:   "email is already in use",
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "100",
+				request: []byte(`{
+					"name": "John Doe",
+					"email": "john@example.com"
+				}`),
+				usRepo: repo.MockUsers{
+					UpdateUserErr: errors.Wrap(sql.ErrNoRows, "error updating user"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/users/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usController := NewV1UsersController(config, &tt.args.usRepo)
+
+			handler := http.HandlerFunc(usController.UpdateUser)
+
+			req, err := http.NewRequest("PUT", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1Users_GetUser(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		id     string
+		usRepo repo.MockUsers
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				usRepo: repo.MockUsers{
+					GetUserResponse: models.User{ID: 1, Name: "John Doe", Email: "john@example.com", Active: true},
+				},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx: context.TODO(),
+				id:  "100",
+				usRepo: repo.MockUsers{
+					GetUserErr: errors.Wrap(sql.ErrNoRows, "error getting user"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/users/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usController := NewV1UsersController(config, &tt.args.usRepo)
+
+			handler := http.HandlerFunc(usController.GetUser)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+)
+
+type V1WaitlistController struct {
+	config *configuration.AppConfig
+	repo   repo.WaitlistRepository
+	// appointments is used for the same checks as booking an appointment
+	appointments V1AppointmentsController
+}
+
+func NewV1WaitlistController(c *configuration.AppConfig, wlRepo repo.WaitlistRepository, appointments V1AppointmentsController) V1WaitlistController {
+	return V1WaitlistController{
+		config:       c,
+		repo:         wlRepo,
+		appointments: appointments,
+	}
+}
+
+func (wl *V1WaitlistController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/waitlist").Name("JoinWaitlist").Handler(http.HandlerFunc(wl.JoinWaitlist)).Methods(http.MethodPost)
+	v1.Path("/waitlist/{id:[0-9]+}").Name("GetWaitlistEntry").Handler(http.HandlerFunc(wl.GetWaitlistEntry)).Methods(http.MethodGet)
+	v1.Path("/waitlist/{id:[0-9]+}").Name("LeaveWaitlist").Handler(http.HandlerFunc(wl.LeaveWaitlist)).Methods(http.MethodDelete)
+}
+
+// JoinWaitlist adds the user to the waitlist for a booked time slot, it is checked the same way as booking an appointment
+// when the time slot frees up it is offered to the user waiting the longest as a hold
+func (wl *V1WaitlistController) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	newEntry := models.WaitlistCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&newEntry)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	sessionType, err := wl.appointments.getSessionType(ctx, newEntry.SessionTypeID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			// not passing err along, respondError would turn sql.ErrNoRows into a 404
+			respondError(ctx, w, http.StatusBadRequest, "bad request payload, unknown session type", errors.Errorf("unknown session type %d", newEntry.SessionTypeID))
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	schedule, err := wl.appointments.getWorkingSchedule(ctx, newEntry.TrainerID, newEntry.StartsAt, newEntry.EndsAt)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	err = validateRequest(newEntry.AppointmentCreateRequest(), schedule, sessionType)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check times", err)
+		return
+	}
+
+	err = wl.appointments.checkTrainer(ctx, newEntry.TrainerID)
+	if err != nil {
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer can't be booked", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	booked, err := wl.appointments.repo.GetBookedTimeSlots(ctx, newEntry.TrainerID, newEntry.StartsAt, newEntry.EndsAt)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	if !isBooked(booked, newEntry.StartsAt, newEntry.EndsAt) {
+		respondError(ctx, w, http.StatusUnprocessableEntity, "time slot is available, book it instead", errors.New("time slot is not booked"))
+		return
+	}
+
+	entry, err := wl.repo.JoinWaitlist(ctx, newEntry)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrAlreadyWaitlisted {
+			respondError(ctx, w, http.StatusConflict, "user is already on the waitlist", err)
+			return
+		}
+
+		if isUnprocessableParticipant(err) {
+			respondError(ctx, w, http.StatusUnprocessableEntity, "trainer or user can't be booked", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, entry)
+	return
+}
+
+func (wl *V1WaitlistController) GetWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid waitlist ID", err)
+		return
+	}
+
+	entry, err := wl.repo.GetWaitlistEntry(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, entry)
+	return
+}
+
+func (wl *V1WaitlistController) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid waitlist ID", err)
+		return
+	}
+
+	entry, err := wl.repo.LeaveWaitlist(ctx, id)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrNotWaiting {
+			respondError(ctx, w, http.StatusConflict, "waitlist entry is not waiting", err)
+			return
+		}
+
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, entry)
+	return
+}
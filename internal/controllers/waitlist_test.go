@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestV1Waitlist_JoinWaitlist(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		request []byte
+		wlRepo  repo.MockWaitlist
+		aRepo   repo.MockAppointments
+		trRepo  repo.MockTrainers
+	}
+
+	trainer := models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: true}
+
+	// 3/17/2022 is a thursday, 19:00 UTC is noon pacific
+	waitlistRequest := []byte(`{
+		"trainer_id": 1,
+		"user_id": 2,
+		"starts_at": "2022-03-17T19:00:00Z",
+		"ends_at": "2022-03-17T19:30:00Z"
+	}`)
+
+	booked := []models.TimeSlot{
+		{
+			TrainerID: 1,
+			StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+		errType  string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx:     context.TODO(),
+				request: waitlistRequest,
+				wlRepo: repo.MockWaitlist{
+					JoinWaitlistResponse: models.WaitlistEntry{ID: 1, TrainerID: 1, UserID: 2, Status: models.WaitlistWaiting},
+				},
+				aRepo:  repo.MockAppointments{GetBookedTimeSlotsResponse: booked},
+				trRepo: repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail time slot is available",
+			args: args{
+				ctx:     context.TODO(),
+				request: waitlistRequest,
+				aRepo:   repo.MockAppointments{GetBookedTimeSlotsResponse: []models.TimeSlot{}},
+				trRepo:  repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "time slot is available, book it instead",
+		},
+		{
+			name: "fail already waiting",
+			args: args{
+				ctx:     context.TODO(),
+				request: waitlistRequest,
+				wlRepo:  repo.MockWaitlist{JoinWaitlistErr: repo.ErrAlreadyWaitlisted},
+				aRepo:   repo.MockAppointments{GetBookedTimeSlotsResponse: booked},
+				trRepo:  repo.MockTrainers{GetTrainerResponse: trainer},
+			},
+			response: http.StatusConflict,
+			errMsg:   "user is already on the waitlist",
+			errType:  "already_waitlisted",
+		},
+		{
+			name: "fail outside working hours",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"trainer_id": 1,
+					"user_id": 2,
+					"starts_at": "2022-03-17T05:00:00Z",
+					"ends_at": "2022-03-17T05:30:00Z"
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check times",
+		},
+		{
+			name: "fail inactive trainer",
+			args: args{
+				ctx:     context.TODO(),
+				request: waitlistRequest,
+				trRepo: repo.MockTrainers{
+					GetTrainerResponse: models.Trainer{ID: 1, Name: "Trainer 1", Email: "trainer1@example.com", Active: false},
+				},
+			},
+			response: http.StatusUnprocessableEntity,
+			errMsg:   "trainer can't be booked",
+			errType:  "inactive_trainer",
+		},
+	}
+
+	endpoint := "/waitlist"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appointments := NewV1AppointmentsController(config, &tt.args.aRepo, &repo.MockWorkingHours{}, &repo.MockTimeOff{}, &repo.MockSessionTypes{}, &tt.args.trRepo, &tt.args.wlRepo)
+			waitlistController := NewV1WaitlistController(config, &tt.args.wlRepo, appointments)
+
+			handler := http.HandlerFunc(waitlistController.JoinWaitlist)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]interface{})
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				if tt.errType != "" {
+					assert.Equal(t, tt.errType, resp["type"])
+				}
+			}
+		})
+	}
+}
+
+func TestV1Waitlist_LeaveWaitlist(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		id     string
+		wlRepo repo.MockWaitlist
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				id:  "1",
+				wlRepo: repo.MockWaitlist{
+					LeaveWaitlistResponse: models.WaitlistEntry{ID: 1, TrainerID: 1, UserID: 2, Status: models.WaitlistRemoved},
+				},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail already offered",
+			args: args{
+				ctx:    context.TODO(),
+				id:     "1",
+				wlRepo: repo.MockWaitlist{LeaveWaitlistErr: repo.ErrNotWaiting},
+			},
+			response: http.StatusConflict,
+			errMsg:   "waitlist entry is not waiting",
+		},
+	}
+
+	endpoint := "/waitlist/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appointments := NewV1AppointmentsController(config, &repo.MockAppointments{}, &repo.MockWorkingHours{}, &repo.MockTimeOff{}, &repo.MockSessionTypes{}, &repo.MockTrainers{}, &tt.args.wlRepo)
+			waitlistController := NewV1WaitlistController(config, &tt.args.wlRepo, appointments)
+
+			handler := http.HandlerFunc(waitlistController.LeaveWaitlist)
+
+			req, err := http.NewRequest("DELETE", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]interface{})
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+)
+
+type V1WebhooksController struct {
+	config *configuration.AppConfig
+	repo   repo.WebhooksRepository
+}
+
+func NewV1WebhooksController(c *configuration.AppConfig, wbRepo repo.WebhooksRepository) V1WebhooksController {
+	return V1WebhooksController{
+		config: c,
+		repo:   wbRepo,
+	}
+}
+
+func (wc *V1WebhooksController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/webhooks").Name("CreateWebhook").Handler(http.HandlerFunc(wc.CreateWebhook)).Methods(http.MethodPost)
+	v1.Path("/webhooks/{id:[0-9]+}").Name("GetWebhook").Handler(http.HandlerFunc(wc.GetWebhook)).Methods(http.MethodGet)
+	v1.Path("/webhooks/{id:[0-9]+}").Name("DeactivateWebhook").Handler(http.HandlerFunc(wc.DeactivateWebhook)).Methods(http.MethodDelete)
+	v1.Path("/webhooks/{id:[0-9]+}/deliveries").Name("ListWebhookDeliveries").Handler(http.HandlerFunc(wc.ListWebhookDeliveries)).Methods(http.MethodGet)
+}
+
+// CreateWebhook subscribes the url to the event types, the response is the only time the secret is returned
+func (wc *V1WebhooksController) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	newWebhook := models.WebhookCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&newWebhook)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = newWebhook.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check url, secret and event_types", err)
+		return
+	}
+
+	for _, eventType := range newWebhook.EventTypes {
+		if !events.IsType(eventType) {
+			respondError(ctx, w, http.StatusBadRequest, "bad request payload, unknown event type "+eventType, errors.Errorf("unknown event type %s", eventType))
+			return
+		}
+	}
+
+	if newWebhook.Secret == "" {
+		newWebhook.Secret, err = generateSecret()
+		if err != nil {
+			respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+			return
+		}
+	}
+
+	webhook, err := wc.repo.CreateWebhook(ctx, newWebhook)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, webhook)
+	return
+}
+
+func (wc *V1WebhooksController) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid webhook ID", err)
+		return
+	}
+
+	webhook, err := wc.repo.GetWebhook(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	webhook.Secret = ""
+	respondModel(ctx, w, http.StatusOK, webhook)
+	return
+}
+
+// DeactivateWebhook stops deliveries to the webhook, it is kept for its delivery history
+func (wc *V1WebhooksController) DeactivateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid webhook ID", err)
+		return
+	}
+
+	webhook, err := wc.repo.DeactivateWebhook(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	webhook.Secret = ""
+	respondModel(ctx, w, http.StatusOK, webhook)
+	return
+}
+
+// ListWebhookDeliveries gets the webhook's latest deliveries, newest first, with every attempt at each of them
+func (wc *V1WebhooksController) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid webhook ID", err)
+		return
+	}
+
+	limit, err := getLimit(r.URL.Query())
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid limit", err)
+		return
+	}
+
+	// an unknown webhook is a 404 instead of an empty history
+	_, err = wc.repo.GetWebhook(ctx, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	deliveries, err := wc.repo.ListWebhookDeliveries(ctx, id, limit)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, deliveries)
+	return
+}
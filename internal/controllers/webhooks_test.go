@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestV1Webhooks_CreateWebhook(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		request []byte
+		wbRepo  repo.MockWebhooks
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"url": "https://partner.example.com/hooks",
+					"secret": "0123456789abcdef",
+					"event_types": ["appointment.created", "appointment.canceled"]
+				}`),
+				wbRepo: repo.MockWebhooks{
+					CreateWebhookResponse: models.Webhook{ID: 1, URL: "https://partner.example.com/hooks", Secret: "0123456789abcdef", EventTypes: []string{"appointment.created", "appointment.canceled"}, Active: true},
+				},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "happy path generated secret",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"url": "http://localhost:9000/hooks",
+					"event_types": ["waitlist.offered"]
+				}`),
+				wbRepo: repo.MockWebhooks{
+					CreateWebhookResponse: models.Webhook{ID: 1, URL: "http://localhost:9000/hooks", Secret: "generated", EventTypes: []string{"waitlist.offered"}, Active: true},
+				},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail invalid url",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"url": "ftp://partner.example.com/hooks",
+					"event_types": ["appointment.created"]
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check url, secret and event_types",
+		},
+		{
+			name: "fail short secret",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"url": "https://partner.example.com/hooks",
+					"secret": "short",
+					"event_types": ["appointment.created"]
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check url, secret and event_types",
+		},
+		{
+			name: "fail missing event types",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"url": "https://partner.example.com/hooks"
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check url, secret and event_types",
+		},
+		{
+			name: "fail unknown event type",
+			args: args{
+				ctx: context.TODO(),
+				request: []byte(`{
+					"url": "https://partner.example.com/hooks",
+					"event_types": ["appointment.created", "appointment.deleted"]
+				}`),
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, unknown event type appointment.deleted",
+		},
+	}
+
+	endpoint := "/webhooks"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wbController := NewV1WebhooksController(config, &tt.args.wbRepo)
+
+			handler := http.HandlerFunc(wbController.CreateWebhook)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			// the secret is only returned when the webhook is created
+			var webhook models.Webhook
+			err = json.Unmarshal(response.Body.Bytes(), &webhook)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, webhook.Secret)
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := generateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, first, 64)
+
+	second, err := generateSecret()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestV1Webhooks_GetWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		wbRepo   repo.MockWebhooks
+		response int
+	}{
+		{
+			name: "happy path hides the secret",
+			id:   "1",
+			wbRepo: repo.MockWebhooks{
+				GetWebhookResponse: models.Webhook{ID: 1, URL: "https://partner.example.com/hooks", Secret: "0123456789abcdef", EventTypes: []string{"appointment.created"}, Active: true},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name:     "fail not found",
+			id:       "42",
+			wbRepo:   repo.MockWebhooks{GetWebhookErr: errors.Wrap(sql.ErrNoRows, "error getting webhook")},
+			response: http.StatusNotFound,
+		},
+	}
+
+	endpoint := "/webhooks/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wbController := NewV1WebhooksController(config, &tt.wbRepo)
+
+			handler := http.HandlerFunc(wbController.GetWebhook)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			resp := make(map[string]interface{})
+			err = json.Unmarshal(response.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			assert.NotContains(t, resp, "secret")
+		})
+	}
+}
+
+func TestV1Webhooks_ListWebhookDeliveries(t *testing.T) {
+	status := http.StatusInternalServerError
+
+	tests := []struct {
+		name     string
+		id       string
+		query    url.Values
+		wbRepo   repo.MockWebhooks
+		response int
+		errMsg   string
+		want     int
+	}{
+		{
+			name: "happy path",
+			id:   "1",
+			wbRepo: repo.MockWebhooks{
+				GetWebhookResponse: models.Webhook{ID: 1},
+				ListWebhookDeliveriesResponse: []models.WebhookDelivery{
+					{
+						ID:           2,
+						WebhookID:    1,
+						EventID:      5,
+						EventType:    "appointment.canceled",
+						Payload:      json.RawMessage(`{"id":5}`),
+						Status:       models.WebhookDeliveryPending,
+						AttemptCount: 1,
+						Attempts:     []models.WebhookDeliveryAttempt{{ID: 1, ResponseStatus: &status, DurationMS: 12}},
+					},
+					{
+						ID:        1,
+						WebhookID: 1,
+						EventID:   4,
+						EventType: "appointment.created",
+						Payload:   json.RawMessage(`{"id":4}`),
+						Status:    models.WebhookDeliveryDelivered,
+						Attempts:  []models.WebhookDeliveryAttempt{},
+					},
+				},
+			},
+			response: http.StatusOK,
+			want:     2,
+		},
+		{
+			name:     "fail not found",
+			id:       "42",
+			wbRepo:   repo.MockWebhooks{GetWebhookErr: errors.Wrap(sql.ErrNoRows, "error getting webhook")},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+		{
+			name:     "fail invalid limit",
+			id:       "1",
+			query:    url.Values{"limit": []string{"0"}},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid limit",
+		},
+	}
+
+	endpoint := "/webhooks/{id}/deliveries"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wbController := NewV1WebhooksController(config, &tt.wbRepo)
+
+			handler := http.HandlerFunc(wbController.ListWebhookDeliveries)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.URL.RawQuery = tt.query.Encode()
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]interface{})
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+				return
+			}
+
+			var deliveries []map[string]interface{}
+			err = json.Unmarshal(response.Body.Bytes(), &deliveries)
+			assert.NoError(t, err)
+			assert.Len(t, deliveries, tt.want)
+			assert.Equal(t, map[string]interface{}{"id": float64(5)}, deliveries[0]["payload"])
+			assert.Len(t, deliveries[0]["attempts"], 1)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/configuration"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"net/http"
+)
+
+type V1WorkingHoursController struct {
+	config *configuration.AppConfig
+	repo   repo.WorkingHoursRepository
+}
+
+func NewV1WorkingHoursController(c *configuration.AppConfig, whRepo repo.WorkingHoursRepository) V1WorkingHoursController {
+	return V1WorkingHoursController{
+		config: c,
+		repo:   whRepo,
+	}
+}
+
+func (wh *V1WorkingHoursController) RegisterRoutes(v1 *mux.Router) {
+	v1.Path("/trainers/{trainer_id:[0-9]+}/working-hours").Name("ListWorkingHours").Handler(http.HandlerFunc(wh.ListWorkingHours)).Methods(http.MethodGet)
+	v1.Path("/trainers/{trainer_id:[0-9]+}/working-hours").Name("CreateWorkingHours").Handler(http.HandlerFunc(wh.CreateWorkingHours)).Methods(http.MethodPost)
+	v1.Path("/trainers/{trainer_id:[0-9]+}/working-hours/{id:[0-9]+}").Name("UpdateWorkingHours").Handler(http.HandlerFunc(wh.UpdateWorkingHours)).Methods(http.MethodPut)
+	v1.Path("/trainers/{trainer_id:[0-9]+}/working-hours/{id:[0-9]+}").Name("DeleteWorkingHours").Handler(http.HandlerFunc(wh.DeleteWorkingHours)).Methods(http.MethodDelete)
+}
+
+func (wh *V1WorkingHoursController) ListWorkingHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	trainerID, err := getPathID(r, "trainer_id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	hours, err := wh.repo.ListWorkingHours(ctx, trainerID)
+	if err != nil {
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, hours)
+	return
+}
+
+func (wh *V1WorkingHoursController) CreateWorkingHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	trainerID, err := getPathID(r, "trainer_id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	hoursRequest := models.WorkingHoursRequest{}
+	err = json.NewDecoder(r.Body).Decode(&hoursRequest)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = hoursRequest.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check working hours", err)
+		return
+	}
+
+	hours, err := wh.repo.CreateWorkingHours(ctx, trainerID, hoursRequest)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrWorkingHoursExist {
+			respondError(ctx, w, http.StatusConflict, "working hours already exist for weekday", err)
+			return
+		}
+
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusCreated, hours)
+	return
+}
+
+func (wh *V1WorkingHoursController) UpdateWorkingHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	trainerID, err := getPathID(r, "trainer_id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid working hours ID", err)
+		return
+	}
+
+	hoursRequest := models.WorkingHoursRequest{}
+	err = json.NewDecoder(r.Body).Decode(&hoursRequest)
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload", err)
+		return
+	}
+
+	err = hoursRequest.Validate()
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "bad request payload, check working hours", err)
+		return
+	}
+
+	hours, err := wh.repo.UpdateWorkingHours(ctx, trainerID, id, hoursRequest)
+	if err != nil {
+		if errors.Cause(err) == repo.ErrWorkingHoursExist {
+			respondError(ctx, w, http.StatusConflict, "working hours already exist for weekday", err)
+			return
+		}
+
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	respondModel(ctx, w, http.StatusOK, hours)
+	return
+}
+
+func (wh *V1WorkingHoursController) DeleteWorkingHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	trainerID, err := getPathID(r, "trainer_id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid trainer ID", err)
+		return
+	}
+
+	id, err := getPathID(r, "id")
+	if err != nil {
+		respondError(ctx, w, http.StatusBadRequest, "invalid working hours ID", err)
+		return
+	}
+
+	err = wh.repo.DeleteWorkingHours(ctx, trainerID, id)
+	if err != nil {
+		// sql.ErrNoRows is turned into a 404 by respondError
+		respondError(ctx, w, http.StatusInternalServerError, "something bad happened", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/samuelmahr/appt-scheduling/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestV1WorkingHours_CreateWorkingHours(t *testing.T) {
+	type args struct {
+		ctx       context.Context
+		trainerID string
+		request   []byte
+		whRepo    repo.MockWorkingHours
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "1",
+				request: []byte(`{
+					"weekday": 6,
+					"start_time": "09:00",
+					"end_time": "13:00"
+				}`),
+				whRepo: repo.MockWorkingHours{
+					CreateWorkingHoursResponse: models.WorkingHours{
+						ID:        1,
+						TrainerID: 1,
+						Weekday:   time.Saturday,
+						StartTime: "09:00",
+						EndTime:   "13:00",
+						Timezone:  models.DefaultTimezone,
+					}},
+			},
+			response: http.StatusCreated,
+		},
+		{
+			name: "fail invalid weekday",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "1",
+				request: []byte(`{
+					"weekday": 7,
+					"start_time": "09:00",
+					"end_time": "13:00"
+				}`),
+				whRepo: repo.MockWorkingHours{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check working hours",
+		},
+		{
+			name: "fail end before start",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "1",
+				request: []byte(`{
+					"weekday": 1,
+					"start_time": "13:00",
+					"end_time": "09:00"
+				}`),
+				whRepo: repo.MockWorkingHours{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check working hours",
+		},
+		{
+			name: "fail invalid timezone",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "1",
+				request: []byte(`{
+					"weekday": 1,
+					"start_time": "09:00",
+					"end_time": "13:00",
+					"timezone": "Mars/Olympus_Mons"
+				}`),
+				whRepo: repo.MockWorkingHours{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "bad request payload, check working hours",
+		},
+		{
+			name: "fail weekday already has working hours",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "1",
+				request: []byte(`{
+					"weekday": 1,
+					"start_time": "09:00",
+					"end_time": "13:00"
+				}`),
+				whRepo: repo.MockWorkingHours{
+					CreateWorkingHoursErr: repo.ErrWorkingHoursExist,
+				},
+			},
+			response: http.StatusConflict,
+			errMsg:   "working hours already exist for weekday",
+		},
+	}
+
+	endpoint := "/trainers/{trainer_id}/working-hours"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			whController := NewV1WorkingHoursController(config, &tt.args.whRepo)
+
+			handler := http.HandlerFunc(whController.CreateWorkingHours)
+
+			req, err := http.NewRequest("POST", endpoint, bytes.NewReader(tt.args.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"trainer_id": tt.args.trainerID})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusCreated {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1WorkingHours_ListWorkingHours(t *testing.T) {
+	type args struct {
+		ctx       context.Context
+		trainerID string
+		whRepo    repo.MockWorkingHours
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "1",
+				whRepo: repo.MockWorkingHours{
+					ListWorkingHoursResponse: []models.WorkingHours{
+						{ID: 1, TrainerID: 1, Weekday: time.Saturday, StartTime: "09:00", EndTime: "13:00", Timezone: models.DefaultTimezone},
+					}},
+			},
+			response: http.StatusOK,
+		},
+		{
+			name: "fail invalid trainer id",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "abc",
+				whRepo:    repo.MockWorkingHours{},
+			},
+			response: http.StatusBadRequest,
+			errMsg:   "invalid trainer ID",
+		},
+	}
+
+	endpoint := "/trainers/{trainer_id}/working-hours"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			whController := NewV1WorkingHoursController(config, &tt.args.whRepo)
+
+			handler := http.HandlerFunc(whController.ListWorkingHours)
+
+			req, err := http.NewRequest("GET", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"trainer_id": tt.args.trainerID})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusOK {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
+
+func TestV1WorkingHours_DeleteWorkingHours(t *testing.T) {
+	type args struct {
+		ctx       context.Context
+		trainerID string
+		id        string
+		whRepo    repo.MockWorkingHours
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		response int
+		errMsg   string
+	}{
+		{
+			name: "happy path",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "1",
+				id:        "1",
+				whRepo:    repo.MockWorkingHours{},
+			},
+			response: http.StatusNoContent,
+		},
+		{
+			name: "fail not found",
+			args: args{
+				ctx:       context.TODO(),
+				trainerID: "1",
+				id:        "1",
+				whRepo: repo.MockWorkingHours{
+					DeleteWorkingHoursErr: errors.Wrap(sql.ErrNoRows, "error deleting working hours"),
+				},
+			},
+			response: http.StatusNotFound,
+			errMsg:   "something bad happened",
+		},
+	}
+
+	endpoint := "/trainers/{trainer_id}/working-hours/{id}"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			whController := NewV1WorkingHoursController(config, &tt.args.whRepo)
+
+			handler := http.HandlerFunc(whController.DeleteWorkingHours)
+
+			req, err := http.NewRequest("DELETE", endpoint, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"trainer_id": tt.args.trainerID, "id": tt.args.id})
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			assert.Equal(t, tt.response, response.Code)
+
+			if tt.response != http.StatusNoContent {
+				resp := make(map[string]string)
+				err = json.Unmarshal(response.Body.Bytes(), &resp)
+				assert.Equal(t, tt.errMsg, resp["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"log"
+	"time"
+)
+
+const (
+	// AppointmentCreated is emitted for every booked appointment, including ones booked in a batch, a series or from a hold
+	AppointmentCreated = "appointment.created"
+	// AppointmentRescheduled is emitted when an appointment moves to a new time slot or trainer
+	AppointmentRescheduled = "appointment.rescheduled"
+	// AppointmentCanceled is emitted for every canceled appointment
+	AppointmentCanceled = "appointment.canceled"
+	// WaitlistOffered is emitted when a freed time slot is offered to a waitlisted user
+	WaitlistOffered = "waitlist.offered"
+)
+
+// Event is something that happened other services may want to know about, Data is the event's JSON payload
+// ID is the event's outbox ID, events are delivered at least once so consumers can use it to skip duplicates
+type Event struct {
+	ID         int64       `json:"id,omitempty"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// AppointmentData is the data of appointment events, Previous is the appointment before it was rescheduled
+type AppointmentData struct {
+	Appointment models.Appointment  `json:"appointment"`
+	Previous    *models.Appointment `json:"previous,omitempty"`
+}
+
+func NewAppointmentCreatedEvent(appointment models.Appointment) Event {
+	return Event{
+		Type:       AppointmentCreated,
+		OccurredAt: time.Now().UTC(),
+		Data:       AppointmentData{Appointment: appointment},
+	}
+}
+
+func NewAppointmentRescheduledEvent(appointment models.Appointment, previous models.Appointment) Event {
+	return Event{
+		Type:       AppointmentRescheduled,
+		OccurredAt: time.Now().UTC(),
+		Data:       AppointmentData{Appointment: appointment, Previous: &previous},
+	}
+}
+
+func NewAppointmentCanceledEvent(appointment models.Appointment) Event {
+	return Event{
+		Type:       AppointmentCanceled,
+		OccurredAt: time.Now().UTC(),
+		Data:       AppointmentData{Appointment: appointment},
+	}
+}
+
+// NewWaitlistOfferedEvent is the event for an offer, with the waitlist entry and the hold the user can confirm
+func NewWaitlistOfferedEvent(offer models.WaitlistOffer) Event {
+	return Event{
+		Type:       WaitlistOffered,
+		OccurredAt: time.Now().UTC(),
+		Data:       offer,
+	}
+}
+
+// NewOutboxEvent is the event saved in the outbox, the payload is already JSON so it's published as is
+func NewOutboxEvent(e models.OutboxEvent) Event {
+	return Event{
+		ID:         e.ID,
+		Type:       e.EventType,
+		OccurredAt: e.OccurredAt,
+		Data:       json.RawMessage(e.Payload),
+	}
+}
+
+// Publisher is a sink the outbox relay publishes events to, an error means the event is retried later
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// LogPublisher writes events to the log as JSON
+type LogPublisher struct{}
+
+func NewLogPublisher() LogPublisher {
+	return LogPublisher{}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "error publishing event")
+	}
+
+	log.Printf("event: %s\n", b)
+	return nil
+}
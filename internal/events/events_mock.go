@@ -0,0 +1,20 @@
+package events
+
+import (
+	"context"
+)
+
+// MockPublisher is an implementation of Publisher that keeps the published events to check when testing
+type MockPublisher struct {
+	Published  []Event
+	PublishErr error
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, event Event) error {
+	if m.PublishErr != nil {
+		return m.PublishErr
+	}
+
+	m.Published = append(m.Published, event)
+	return nil
+}
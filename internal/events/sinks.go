@@ -0,0 +1,113 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// WriterPublisher writes events to w as JSON, one per line. the relay publishes one event at a time,
+// so it isn't safe for concurrent use
+type WriterPublisher struct {
+	w io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) WriterPublisher {
+	return WriterPublisher{w: w}
+}
+
+// NewStdoutPublisher writes events to stdout
+func NewStdoutPublisher() WriterPublisher {
+	return NewWriterPublisher(os.Stdout)
+}
+
+// NewFilePublisher appends events to the file at path, creating it if needed. the file stays open for the life of the app
+func NewFilePublisher(path string) (WriterPublisher, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return WriterPublisher{}, errors.Wrap(err, "error opening events file")
+	}
+
+	return NewWriterPublisher(f), nil
+}
+
+func (p *WriterPublisher) Publish(ctx context.Context, event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "error publishing event")
+	}
+
+	_, err = p.w.Write(append(b, '\n'))
+	if err != nil {
+		return errors.Wrap(err, "error publishing event")
+	}
+
+	return nil
+}
+
+// WebhookPublisher posts each event as JSON to url, any response other than a 2xx is an error
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookPublisher(url string, timeout time.Duration) WebhookPublisher {
+	return WebhookPublisher{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "error publishing event")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "error publishing event")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error publishing event")
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("error publishing event, webhook responded %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// MultiPublisher publishes each event to every publisher, an error from any of them fails the event. the outbox
+// retries a failed event with all of them, so ones that already got it get it again
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+func NewMultiPublisher(publishers ...Publisher) MultiPublisher {
+	return MultiPublisher{publishers: publishers}
+}
+
+func (p *MultiPublisher) Publish(ctx context.Context, event Event) error {
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriterPublisher_Publish(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewWriterPublisher(&buf)
+
+	for _, id := range []int64{1, 2} {
+		err := p.Publish(context.Background(), Event{ID: id, Type: AppointmentCreated, Data: json.RawMessage(`{"appointment":{"id":1}}`)})
+		assert.NoError(t, err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	var got map[string]interface{}
+	err := json.Unmarshal(lines[1], &got)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), got["id"])
+	assert.Equal(t, AppointmentCreated, got["type"])
+	assert.Equal(t, map[string]interface{}{"appointment": map[string]interface{}{"id": float64(1)}}, got["data"])
+}
+
+func TestWebhookPublisher_Publish(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{
+			name:   "happy path",
+			status: http.StatusOK,
+		},
+		{
+			name:   "happy path accepted",
+			status: http.StatusAccepted,
+		},
+		{
+			name:    "fail server error",
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+		{
+			name:    "fail not a 2xx",
+			status:  http.StatusNotModified,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Event
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			p := NewWebhookPublisher(srv.URL, time.Second)
+			event := Event{ID: 7, Type: AppointmentCanceled, OccurredAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC), Data: json.RawMessage(`{}`)}
+
+			err := p.Publish(context.Background(), event)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, event.ID, got.ID)
+			assert.Equal(t, event.Type, got.Type)
+			assert.Equal(t, event.OccurredAt, got.OccurredAt)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	// SignatureHeader has the HMAC-SHA256 of the timestamp and the body, as sha256=<hex>
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader is when the delivery was sent in unix seconds, it's signed so a captured delivery can't be replayed later
+	TimestampHeader = "X-Webhook-Timestamp"
+	// DeliveryHeader is the delivery ID, the same for every attempt
+	DeliveryHeader = "X-Webhook-Delivery"
+	// EventTypeHeader is the event's type
+	EventTypeHeader = "X-Webhook-Event"
+)
+
+// Types are the events webhooks can subscribe to
+var Types = []string{AppointmentCreated, AppointmentRescheduled, AppointmentCanceled, WaitlistOffered}
+
+// IsType is whether eventType is one of Types
+func IsType(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sign is the signature of a webhook delivery, an HMAC-SHA256 with the webhook's secret of "<timestamp>.<body>"
+// receivers compute the same from the timestamp header and the raw body and compare it to the signature header
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
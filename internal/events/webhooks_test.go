@@ -0,0 +1,23 @@
+package events
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":1}`)
+
+	// echo -n '1647543600.{"id":1}' | openssl dgst -sha256 -hmac 0123456789abcdef
+	assert.Equal(t, "sha256=ca6e049f339e8b109df8dd305de64758ea3732baf837296aac7e5db0e627e8ee", Sign("0123456789abcdef", 1647543600, body))
+
+	assert.NotEqual(t, Sign("0123456789abcdef", 1647543600, body), Sign("0123456789abcdef", 1647543601, body))
+	assert.NotEqual(t, Sign("0123456789abcdef", 1647543600, body), Sign("fedcba9876543210", 1647543600, body))
+}
+
+func TestIsType(t *testing.T) {
+	assert.True(t, IsType(AppointmentCreated))
+	assert.True(t, IsType(WaitlistOffered))
+	assert.False(t, IsType("appointment.deleted"))
+	assert.False(t, IsType(""))
+}
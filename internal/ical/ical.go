@@ -0,0 +1,133 @@
+package ical
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// prodID identifies this app as the calendar's creator
+const prodID = "-//appt-scheduling//calendar//EN"
+
+// dateTimeFormat is a UTC DATE-TIME, RFC 5545 section 3.3.5
+const dateTimeFormat = "20060102T150405Z"
+
+// maxLineLength is in octets, longer lines are folded onto continuation lines starting with a space
+const maxLineLength = 75
+
+// Status is an event's STATUS
+type Status string
+
+const (
+	StatusConfirmed Status = "CONFIRMED"
+	StatusCancelled Status = "CANCELLED"
+)
+
+// Calendar is an iCalendar object, RFC 5545. Name is shown by calendar apps that support X-WR-CALNAME
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT. UID has to stay the same for the event for calendar apps to update it instead of adding another one,
+// Sequence goes up every time it changes and Stamp is when it last changed
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Summary     string
+	Description string
+	Status      Status
+	Sequence    int
+}
+
+// Encode writes the calendar with CRLF line endings and long lines folded
+func (c Calendar) Encode(w io.Writer) error {
+	lw := &lineWriter{w: bufio.NewWriter(w)}
+	lw.write("BEGIN", "VCALENDAR")
+	lw.write("VERSION", "2.0")
+	lw.write("PRODID", prodID)
+	lw.write("CALSCALE", "GREGORIAN")
+	if c.Name != "" {
+		lw.write("X-WR-CALNAME", escapeText(c.Name))
+	}
+
+	for _, e := range c.Events {
+		lw.write("BEGIN", "VEVENT")
+		lw.write("UID", escapeText(e.UID))
+		lw.write("DTSTAMP", formatDateTime(e.Stamp))
+		lw.write("DTSTART", formatDateTime(e.StartsAt))
+		lw.write("DTEND", formatDateTime(e.EndsAt))
+		lw.write("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			lw.write("DESCRIPTION", escapeText(e.Description))
+		}
+
+		if e.Status != "" {
+			lw.write("STATUS", string(e.Status))
+		}
+
+		lw.write("SEQUENCE", strconv.Itoa(e.Sequence))
+		lw.write("END", "VEVENT")
+	}
+
+	lw.write("END", "VCALENDAR")
+	if lw.err != nil {
+		return errors.Wrap(lw.err, "error writing calendar")
+	}
+
+	return errors.Wrap(lw.w.Flush(), "error writing calendar")
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(dateTimeFormat)
+}
+
+// escapeText escapes a TEXT value, RFC 5545 section 3.3.11
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// lineWriter writes content lines, it keeps the first error so Encode only checks once
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) write(name string, value string) {
+	if lw.err != nil {
+		return
+	}
+
+	_, lw.err = lw.w.WriteString(fold(name+":"+value) + "\r\n")
+}
+
+// fold splits a line longer than 75 octets, RFC 5545 section 3.1. it never splits a multi-byte character
+func fold(line string) string {
+	if len(line) <= maxLineLength {
+		return line
+	}
+
+	var b strings.Builder
+	// continuation lines start with a space, which counts towards their length
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineLength - 1
+	}
+
+	b.WriteString(line)
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package ical
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Encode(t *testing.T) {
+	stamp := time.Date(2022, 03, 16, 12, 0, 0, 0, time.UTC)
+	central := time.FixedZone("CDT", -5*60*60)
+
+	tests := []struct {
+		name     string
+		calendar Calendar
+		want     []string
+	}{
+		{
+			name:     "empty calendar",
+			calendar: Calendar{},
+			want: []string{
+				"BEGIN:VCALENDAR",
+				"VERSION:2.0",
+				"PRODID:-//appt-scheduling//calendar//EN",
+				"CALSCALE:GREGORIAN",
+				"END:VCALENDAR",
+			},
+		},
+		{
+			name: "events in utc",
+			calendar: Calendar{
+				Name: "Trainer 1",
+				Events: []Event{
+					{
+						UID:      "appointment-1@appt-scheduling",
+						Stamp:    stamp,
+						StartsAt: time.Date(2022, 03, 17, 14, 0, 0, 0, central),
+						EndsAt:   time.Date(2022, 03, 17, 14, 30, 0, 0, central),
+						Summary:  "Training session",
+						Status:   StatusConfirmed,
+					},
+					{
+						UID:         "appointment-2@appt-scheduling",
+						Stamp:       stamp,
+						StartsAt:    time.Date(2022, 03, 18, 19, 0, 0, 0, time.UTC),
+						EndsAt:      time.Date(2022, 03, 18, 20, 0, 0, 0, time.UTC),
+						Summary:     "Training session",
+						Description: "legs, back; bring water\nno shoes",
+						Status:      StatusCancelled,
+						Sequence:    2,
+					},
+				},
+			},
+			want: []string{
+				"BEGIN:VCALENDAR",
+				"VERSION:2.0",
+				"PRODID:-//appt-scheduling//calendar//EN",
+				"CALSCALE:GREGORIAN",
+				"X-WR-CALNAME:Trainer 1",
+				"BEGIN:VEVENT",
+				"UID:appointment-1@appt-scheduling",
+				"DTSTAMP:20220316T120000Z",
+				"DTSTART:20220317T190000Z",
+				"DTEND:20220317T193000Z",
+				"SUMMARY:Training session",
+				"STATUS:CONFIRMED",
+				"SEQUENCE:0",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:appointment-2@appt-scheduling",
+				"DTSTAMP:20220316T120000Z",
+				"DTSTART:20220318T190000Z",
+				"DTEND:20220318T200000Z",
+				"SUMMARY:Training session",
+				`DESCRIPTION:legs\, back\; bring water\nno shoes`,
+				"STATUS:CANCELLED",
+				"SEQUENCE:2",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := tt.calendar.Encode(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, strings.Join(tt.want, "\r\n")+"\r\n", b.String())
+		})
+	}
+}
+
+func TestFold(t *testing.T) {
+	short := "SUMMARY:" + strings.Repeat("a", 67)
+	assert.Equal(t, short, fold(short))
+
+	long := "SUMMARY:" + strings.Repeat("a", 150)
+	folded := fold(long)
+	lines := strings.Split(folded, "\r\n")
+	assert.Len(t, lines, 3)
+	for i, line := range lines {
+		assert.LessOrEqual(t, len(line), maxLineLength)
+		if i > 0 {
+			assert.True(t, strings.HasPrefix(line, " "))
+		}
+	}
+
+	assert.Equal(t, long, strings.ReplaceAll(folded, "\r\n ", ""))
+
+	// é is two octets, it straddles the 75th octet and has to move to the next line whole
+	multiByte := "SUMMARY:" + strings.Repeat("a", 66) + "é"
+	assert.Equal(t, "SUMMARY:"+strings.Repeat("a", 66)+"\r\n é", fold(multiByte))
+}
@@ -0,0 +1,437 @@
+package ical
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateFormat is a DATE, used by all-day events. localDateTimeFormat is a DATE-TIME without the Z, in TZID or floating
+const (
+	dateFormat          = "20060102"
+	localDateTimeFormat = "20060102T150405"
+)
+
+// maxContentLineLength is the longest unfolded line read, calendars with huge descriptions or attachments are rejected
+const maxContentLineLength = 1024 * 1024
+
+// durationPattern is a DURATION, RFC 5545 section 3.3.6. it's either weeks or days and a time
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W|(\d+)D(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?|T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)$`)
+
+// Busy is a time range blocked by an event or a VFREEBUSY period
+type Busy struct {
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// contentLine is a property, ex. DTSTART;TZID=America/Chicago:20220317T090000, with the line it started on
+type contentLine struct {
+	number int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// event is a VEVENT's properties, with the line it ends on
+type event struct {
+	lines   []contentLine
+	endLine int
+}
+
+// ParseBusy reads the busy time ranges from a calendar's VEVENTs and VFREEBUSYs, sorted by when they start
+// dates and times without a timezone are in loc. events that are cancelled or transparent (show as free) aren't busy.
+// recurring events are expanded to their occurrences overlapping from until until, an event with a RECURRENCE-ID
+// replaces that occurrence of the recurring event with its UID
+func ParseBusy(r io.Reader, loc *time.Location, from time.Time, until time.Time) ([]Busy, error) {
+	lines, err := readContentLines(r)
+	if err != nil {
+		return []Busy{}, err
+	}
+
+	if len(lines) == 0 || lines[0].name != "BEGIN" || !strings.EqualFold(lines[0].value, "VCALENDAR") {
+		return []Busy{}, errors.New("not an iCalendar file, it has to start with BEGIN:VCALENDAR")
+	}
+
+	busy := make([]Busy, 0)
+	events := make([]event, 0)
+	components := make([]string, 0)
+	var current []contentLine
+	for _, line := range lines {
+		switch line.name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(line.value))
+			if strings.EqualFold(line.value, "VEVENT") {
+				current = make([]contentLine, 0)
+			}
+
+			continue
+		case "END":
+			if len(components) == 0 || components[len(components)-1] != strings.ToUpper(line.value) {
+				return []Busy{}, errors.Errorf("line %d: unexpected END:%s", line.number, line.value)
+			}
+
+			components = components[:len(components)-1]
+			if strings.EqualFold(line.value, "VEVENT") {
+				events = append(events, event{lines: current, endLine: line.number})
+			}
+
+			continue
+		}
+
+		if len(components) == 0 {
+			return []Busy{}, errors.Errorf("line %d: %s is outside of VCALENDAR", line.number, line.name)
+		}
+
+		// properties of components inside events, like VALARM, aren't the event's
+		switch components[len(components)-1] {
+		case "VEVENT":
+			current = append(current, line)
+		case "VFREEBUSY":
+			if line.name != "FREEBUSY" {
+				continue
+			}
+
+			periods, err := parseFreeBusy(line, loc)
+			if err != nil {
+				return []Busy{}, err
+			}
+
+			busy = append(busy, periods...)
+		}
+	}
+
+	if len(components) != 0 {
+		return []Busy{}, errors.Errorf("unexpected end of calendar, %s isn't closed", components[len(components)-1])
+	}
+
+	overridden, err := overriddenOccurrences(events, loc)
+	if err != nil {
+		return []Busy{}, err
+	}
+
+	for _, e := range events {
+		eventBusy, err := parseEvent(e, loc, from, until, overridden[e.props()["UID"].value])
+		if err != nil {
+			return []Busy{}, err
+		}
+
+		busy = append(busy, eventBusy...)
+	}
+
+	sort.SliceStable(busy, func(i, j int) bool { return busy[i].StartsAt.Before(busy[j].StartsAt) })
+	return busy, nil
+}
+
+// props gets the event's properties by name, the last one wins when there's more than one
+func (e event) props() map[string]contentLine {
+	props := make(map[string]contentLine)
+	for _, line := range e.lines {
+		props[line.name] = line
+	}
+
+	return props
+}
+
+// overriddenOccurrences gets the RECURRENCE-IDs of every UID, those occurrences are replaced by their own event
+func overriddenOccurrences(events []event, loc *time.Location) (map[string][]time.Time, error) {
+	overridden := make(map[string][]time.Time)
+	for _, e := range events {
+		props := e.props()
+		recurrenceID, ok := props["RECURRENCE-ID"]
+		if !ok {
+			continue
+		}
+
+		t, _, err := parseDateTime(recurrenceID, loc)
+		if err != nil {
+			return map[string][]time.Time{}, err
+		}
+
+		uid := props["UID"].value
+		overridden[uid] = append(overridden[uid], t)
+	}
+
+	return overridden, nil
+}
+
+// parseEvent gets the time ranges an event blocks, none when it's cancelled or transparent
+// an event without DTEND or DURATION takes up the day when it's all-day, otherwise it's a moment and doesn't block anything
+func parseEvent(e event, loc *time.Location, from time.Time, until time.Time, overridden []time.Time) ([]Busy, error) {
+	props := e.props()
+	if strings.EqualFold(props["STATUS"].value, "CANCELLED") || strings.EqualFold(props["TRANSP"].value, "TRANSPARENT") {
+		return []Busy{}, nil
+	}
+
+	dtstart, ok := props["DTSTART"]
+	if !ok {
+		return []Busy{}, errors.Errorf("line %d: event without DTSTART", e.endLine)
+	}
+
+	startsAt, allDay, err := parseDateTime(dtstart, loc)
+	if err != nil {
+		return []Busy{}, err
+	}
+
+	end, err := eventEnd(props, startsAt, allDay, loc)
+	if err != nil {
+		return []Busy{}, err
+	}
+
+	endsAt := end(startsAt)
+	if endsAt.Before(startsAt) {
+		return []Busy{}, errors.Errorf("line %d: event ends before it starts", dtstart.number)
+	}
+
+	_, hasRRule := props["RRULE"]
+	_, hasRDate := props["RDATE"]
+	if hasRRule || hasRDate {
+		return expandEvent(e, dtstart, startsAt, allDay, end, loc, from, until, overridden)
+	}
+
+	if endsAt.Equal(startsAt) {
+		return []Busy{}, nil
+	}
+
+	return []Busy{{StartsAt: startsAt, EndsAt: endsAt}}, nil
+}
+
+// eventEnd gets the end of an occurrence of the event from its start. DTEND is the exact time after DTSTART, except
+// all-day events keep being whole days, and DURATION is nominal so days keep the time of day across daylight saving
+func eventEnd(props map[string]contentLine, startsAt time.Time, allDay bool, loc *time.Location) (func(time.Time) time.Time, error) {
+	if dtend, ok := props["DTEND"]; ok {
+		endsAt, endAllDay, err := parseDateTime(dtend, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		if allDay && endAllDay {
+			days := int(math.Round(endsAt.Sub(startsAt).Hours() / 24))
+			return func(t time.Time) time.Time { return t.AddDate(0, 0, days) }, nil
+		}
+
+		length := endsAt.Sub(startsAt)
+		return func(t time.Time) time.Time { return t.Add(length) }, nil
+	}
+
+	if duration, ok := props["DURATION"]; ok {
+		if _, err := addDuration(startsAt, duration.value); err != nil {
+			return nil, errors.Wrapf(err, "line %d", duration.number)
+		}
+
+		return func(t time.Time) time.Time {
+			endsAt, _ := addDuration(t, duration.value)
+			return endsAt
+		}, nil
+	}
+
+	if allDay {
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }, nil
+	}
+
+	return func(t time.Time) time.Time { return t }, nil
+}
+
+// parseFreeBusy gets the periods of a FREEBUSY property, each one a start and an end or a duration
+// free periods are skipped, every other FBTYPE (BUSY, BUSY-TENTATIVE, BUSY-UNAVAILABLE) is busy
+func parseFreeBusy(line contentLine, loc *time.Location) ([]Busy, error) {
+	if strings.EqualFold(line.params["FBTYPE"], "FREE") {
+		return []Busy{}, nil
+	}
+
+	periods := make([]Busy, 0)
+	for _, value := range strings.Split(line.value, ",") {
+		period, err := parsePeriod(line, value, loc)
+		if err != nil {
+			return []Busy{}, err
+		}
+
+		periods = append(periods, period)
+	}
+
+	return periods, nil
+}
+
+// parsePeriod parses a PERIOD value of the line, RFC 5545 section 3.3.9. it's a start and an end or a duration
+func parsePeriod(line contentLine, period string, loc *time.Location) (Busy, error) {
+	parts := strings.SplitN(period, "/", 2)
+	if len(parts) != 2 {
+		return Busy{}, errors.Errorf("line %d: invalid period %s", line.number, period)
+	}
+
+	startsAt, _, err := parseDateTime(contentLine{number: line.number, params: line.params, value: parts[0]}, loc)
+	if err != nil {
+		return Busy{}, err
+	}
+
+	var endsAt time.Time
+	if strings.HasPrefix(parts[1], "P") || strings.HasPrefix(parts[1], "+P") {
+		endsAt, err = addDuration(startsAt, parts[1])
+		if err != nil {
+			return Busy{}, errors.Wrapf(err, "line %d", line.number)
+		}
+	} else {
+		endsAt, _, err = parseDateTime(contentLine{number: line.number, params: line.params, value: parts[1]}, loc)
+		if err != nil {
+			return Busy{}, err
+		}
+	}
+
+	if !startsAt.Before(endsAt) {
+		return Busy{}, errors.Errorf("line %d: invalid period %s, it has to end after it starts", line.number, period)
+	}
+
+	return Busy{StartsAt: startsAt, EndsAt: endsAt}, nil
+}
+
+// parseDateTime parses a DATE or DATE-TIME value, allDay is true for a DATE
+// a DATE-TIME is UTC with a trailing Z, in its TZID, or floating and in loc
+func parseDateTime(line contentLine, loc *time.Location) (time.Time, bool, error) {
+	if strings.EqualFold(line.params["VALUE"], "DATE") || len(line.value) == len(dateFormat) {
+		t, err := time.ParseInLocation(dateFormat, line.value, loc)
+		if err != nil {
+			return time.Time{}, false, errors.Errorf("line %d: invalid date %s", line.number, line.value)
+		}
+
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(line.value, "Z") {
+		t, err := time.Parse(dateTimeFormat, line.value)
+		if err != nil {
+			return time.Time{}, false, errors.Errorf("line %d: invalid date-time %s", line.number, line.value)
+		}
+
+		return t, false, nil
+	}
+
+	if tzid := line.params["TZID"]; tzid != "" {
+		// a leading / marks a globally unique TZID, the rest is the name
+		var err error
+		loc, err = time.LoadLocation(strings.TrimPrefix(tzid, "/"))
+		if err != nil {
+			return time.Time{}, false, errors.Errorf("line %d: unknown timezone %s", line.number, tzid)
+		}
+	}
+
+	t, err := time.ParseInLocation(localDateTimeFormat, line.value, loc)
+	if err != nil {
+		return time.Time{}, false, errors.Errorf("line %d: invalid date-time %s", line.number, line.value)
+	}
+
+	return t, false, nil
+}
+
+// addDuration adds a DURATION to t, days and weeks are calendar days so they keep the time of day across daylight saving
+func addDuration(t time.Time, value string) (time.Time, error) {
+	m := durationPattern.FindStringSubmatch(value)
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return time.Time{}, errors.Errorf("invalid duration %s", value)
+	}
+
+	n := func(s string) int {
+		i, _ := strconv.Atoi(s)
+		return i
+	}
+
+	days := n(m[2])*7 + n(m[3])
+	clock := time.Duration(n(m[4])+n(m[7]))*time.Hour + time.Duration(n(m[5])+n(m[8]))*time.Minute + time.Duration(n(m[6])+n(m[9]))*time.Second
+	if m[1] == "-" {
+		return t.AddDate(0, 0, -days).Add(-clock), nil
+	}
+
+	return t.AddDate(0, 0, days).Add(clock), nil
+}
+
+// readContentLines unfolds and parses every line, RFC 5545 section 3.1. LF line endings are accepted as well as CRLF
+func readContentLines(r io.Reader) ([]contentLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxContentLineLength)
+
+	unfolded := make([]contentLine, 0)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			if len(unfolded) == 0 {
+				return []contentLine{}, errors.Errorf("line %d: continuation without a line to continue", number)
+			}
+
+			unfolded[len(unfolded)-1].value += text[1:]
+			continue
+		}
+
+		if text == "" {
+			continue
+		}
+
+		unfolded = append(unfolded, contentLine{number: number, value: text})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return []contentLine{}, errors.Wrap(err, "error reading calendar")
+	}
+
+	lines := make([]contentLine, 0, len(unfolded))
+	for _, u := range unfolded {
+		line, err := parseContentLine(u.number, u.value)
+		if err != nil {
+			return []contentLine{}, err
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// parseContentLine splits name;param=value;param="quoted:value":value, colons and semicolons in quotes are part of the param
+func parseContentLine(number int, text string) (contentLine, error) {
+	parts := make([]string, 0)
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ';':
+			if !inQuotes {
+				parts = append(parts, text[start:i])
+				start = i + 1
+			}
+		case ':':
+			if !inQuotes {
+				parts = append(parts, text[start:i])
+				line := contentLine{
+					number: number,
+					name:   strings.ToUpper(parts[0]),
+					params: make(map[string]string),
+					value:  text[i+1:],
+				}
+
+				for _, param := range parts[1:] {
+					kv := strings.SplitN(param, "=", 2)
+					if len(kv) != 2 {
+						return contentLine{}, errors.Errorf("line %d: invalid parameter %s", number, param)
+					}
+
+					line.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+				}
+
+				if line.name == "" {
+					return contentLine{}, errors.Errorf("line %d: missing property name", number)
+				}
+
+				return line, nil
+			}
+		}
+	}
+
+	return contentLine{}, errors.Errorf("line %d: missing : between property name and value", number)
+}
@@ -0,0 +1,326 @@
+package ical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recurring events are expanded over 2022
+var (
+	windowFrom  = time.Date(2022, 01, 01, 0, 0, 0, 0, time.UTC)
+	windowUntil = time.Date(2023, 01, 01, 0, 0, 0, 0, time.UTC)
+)
+
+func TestParseBusy_File(t *testing.T) {
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open("testdata/busy.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	busy, err := ParseBusy(f, pacific, windowFrom, windowUntil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Busy{
+		// dentist, in its TZID
+		{StartsAt: time.Date(2022, 03, 17, 14, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 15, 30, 0, 0, time.UTC)},
+		// long run, with a duration
+		{StartsAt: time.Date(2022, 03, 18, 15, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 18, 16, 15, 0, 0, time.UTC)},
+		// lunch, floating so it's pacific
+		{StartsAt: time.Date(2022, 03, 19, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 19, 20, 0, 0, 0, time.UTC)},
+		// conference, all day in pacific
+		{StartsAt: time.Date(2022, 03, 21, 7, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 23, 7, 0, 0, 0, time.UTC)},
+		// free/busy periods, the free one is skipped
+		{StartsAt: time.Date(2022, 03, 24, 16, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 24, 17, 0, 0, 0, time.UTC)},
+		{StartsAt: time.Date(2022, 03, 25, 16, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 25, 16, 30, 0, 0, time.UTC)},
+		{StartsAt: time.Date(2022, 03, 25, 20, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 25, 21, 0, 0, 0, time.UTC)},
+	}
+
+	if assert.Len(t, busy, len(want)) {
+		for i := range want {
+			assert.True(t, want[i].StartsAt.Equal(busy[i].StartsAt), "%d starts at %s, want %s", i, busy[i].StartsAt, want[i].StartsAt)
+			assert.True(t, want[i].EndsAt.Equal(busy[i].EndsAt), "%d ends at %s, want %s", i, busy[i].EndsAt, want[i].EndsAt)
+		}
+	}
+}
+
+func TestParseBusy(t *testing.T) {
+	calendar := func(lines ...string) string {
+		return strings.Join(append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...), "END:VCALENDAR"), "\n")
+	}
+
+	tests := []struct {
+		name     string
+		calendar string
+		want     []Busy
+		errMsg   string
+	}{
+		{
+			name:     "empty calendar",
+			calendar: calendar(),
+			want:     []Busy{},
+		},
+		{
+			name: "all-day event without an end",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART;VALUE=DATE:20220317",
+				"END:VEVENT",
+			),
+			want: []Busy{
+				{StartsAt: time.Date(2022, 03, 17, 0, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 18, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "event without an end doesn't block anything",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART:20220317T190000Z",
+				"END:VEVENT",
+			),
+			want: []Busy{},
+		},
+		{
+			name: "day durations keep the time of day",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART;TZID=America/Los_Angeles:20220312T090000",
+				"DURATION:P1DT1H",
+				"END:VEVENT",
+			),
+			want: []Busy{
+				// daylight saving starts on the 13th, 9am the next day is only 23 hours later
+				{StartsAt: time.Date(2022, 03, 12, 17, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 13, 17, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "quoted params",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				`DTSTART;TZID="America/Chicago";X-NOTE="a;b:c":20220317T090000`,
+				"DURATION:PT30M",
+				"END:VEVENT",
+			),
+			want: []Busy{
+				{StartsAt: time.Date(2022, 03, 17, 14, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 14, 30, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "weekly RRULE",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART:20220317T190000Z",
+				"DTEND:20220317T193000Z",
+				"RRULE:FREQ=WEEKLY;COUNT=3",
+				"END:VEVENT",
+			),
+			want: []Busy{
+				{StartsAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 03, 24, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 24, 19, 30, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 03, 31, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 31, 19, 30, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "weekly RRULE on days of the week keeps the time of day across daylight saving",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				// 3/8/2022 is a tuesday, daylight saving starts on the 13th
+				"DTSTART;TZID=America/Los_Angeles:20220308T090000",
+				"DTEND;TZID=America/Los_Angeles:20220308T100000",
+				"RRULE:FREQ=WEEKLY;BYDAY=TU,TH;UNTIL=20220317",
+				"EXDATE;TZID=America/Los_Angeles:20220310T090000",
+				"END:VEVENT",
+			),
+			want: []Busy{
+				{StartsAt: time.Date(2022, 03, 8, 17, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 8, 18, 0, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 03, 15, 16, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 15, 17, 0, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 03, 17, 16, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "recurring event with a moved occurrence, a cancelled one and an extra date",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"UID:weekly@example.com",
+				"DTSTART:20220317T190000Z",
+				"DURATION:PT30M",
+				"RRULE:FREQ=WEEKLY;COUNT=4",
+				"RDATE:20220401T190000Z",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:weekly@example.com",
+				"RECURRENCE-ID:20220324T190000Z",
+				"DTSTART:20220324T210000Z",
+				"DURATION:PT30M",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:weekly@example.com",
+				"RECURRENCE-ID:20220331T190000Z",
+				"DTSTART:20220331T190000Z",
+				"STATUS:CANCELLED",
+				"END:VEVENT",
+			),
+			want: []Busy{
+				{StartsAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 03, 24, 21, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 24, 21, 30, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 04, 1, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 04, 1, 19, 30, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 04, 7, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 04, 7, 19, 30, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "only occurrences in the window",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART:20211230T190000Z",
+				"DTEND:20211230T193000Z",
+				"RRULE:FREQ=DAILY;COUNT=5",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"DTSTART;VALUE=DATE:20221031",
+				"RRULE:FREQ=MONTHLY",
+				"END:VEVENT",
+			),
+			want: []Busy{
+				{StartsAt: time.Date(2022, 01, 1, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 01, 1, 19, 30, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 01, 2, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 01, 2, 19, 30, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 01, 3, 19, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 01, 3, 19, 30, 0, 0, time.UTC)},
+				// monthly on the 31st skips november, and january is past the window
+				{StartsAt: time.Date(2022, 10, 31, 0, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)},
+				{StartsAt: time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC), EndsAt: time.Date(2023, 01, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "fail unsupported RRULE part",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART:20220317T190000Z",
+				"DTEND:20220317T193000Z",
+				"RRULE:FREQ=MONTHLY;BYDAY=TH;BYSETPOS=3",
+				"END:VEVENT",
+			),
+			errMsg: "line 6: unsupported RRULE part BYSETPOS",
+		},
+		{
+			name: "fail unsupported RRULE BYDAY position",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART:20220317T190000Z",
+				"DTEND:20220317T193000Z",
+				"RRULE:FREQ=MONTHLY;BYDAY=3TH",
+				"END:VEVENT",
+			),
+			errMsg: "line 6: unsupported RRULE BYDAY=3TH, only days of the week are supported",
+		},
+		{
+			name: "fail RRULE with COUNT and UNTIL",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART:20220317T190000Z",
+				"DTEND:20220317T193000Z",
+				"RRULE:FREQ=WEEKLY;COUNT=3;UNTIL=20220401T000000Z",
+				"END:VEVENT",
+			),
+			errMsg: "line 6: RRULE can't have both COUNT and UNTIL",
+		},
+		{
+			name:     "fail not a calendar",
+			calendar: "BEGIN:VCARD\nEND:VCARD",
+			errMsg:   "not an iCalendar file, it has to start with BEGIN:VCALENDAR",
+		},
+		{
+			name: "fail event without a start",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTEND:20220317T190000Z",
+				"END:VEVENT",
+			),
+			errMsg: "line 5: event without DTSTART",
+		},
+		{
+			name: "fail unknown timezone",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART;TZID=Eastern Standard Time:20220317T090000",
+				"DTEND;TZID=Eastern Standard Time:20220317T100000",
+				"END:VEVENT",
+			),
+			errMsg: "line 4: unknown timezone Eastern Standard Time",
+		},
+		{
+			name: "fail invalid duration",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART:20220317T190000Z",
+				"DURATION:PT",
+				"END:VEVENT",
+			),
+			errMsg: "line 5: invalid duration PT",
+		},
+		{
+			name: "fail ends before it starts",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART:20220317T190000Z",
+				"DTEND:20220317T180000Z",
+				"END:VEVENT",
+			),
+			errMsg: "line 4: event ends before it starts",
+		},
+		{
+			name: "fail unclosed event",
+			calendar: strings.Join([]string{
+				"BEGIN:VCALENDAR",
+				"BEGIN:VEVENT",
+				"DTSTART:20220317T190000Z",
+			}, "\n"),
+			errMsg: "unexpected end of calendar, VEVENT isn't closed",
+		},
+		{
+			name: "fail mismatched end",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"END:VFREEBUSY",
+			),
+			errMsg: "line 4: unexpected END:VFREEBUSY",
+		},
+		{
+			name: "fail missing value",
+			calendar: calendar(
+				"BEGIN:VEVENT",
+				"DTSTART",
+				"END:VEVENT",
+			),
+			errMsg: "line 4: missing : between property name and value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			busy, err := ParseBusy(strings.NewReader(tt.calendar), time.UTC, windowFrom, windowUntil)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, len(tt.want), len(busy))
+			for i := range tt.want {
+				assert.True(t, tt.want[i].StartsAt.Equal(busy[i].StartsAt), "starts at %s, want %s", busy[i].StartsAt, tt.want[i].StartsAt)
+				assert.True(t, tt.want[i].EndsAt.Equal(busy[i].EndsAt), "ends at %s, want %s", busy[i].EndsAt, tt.want[i].EndsAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package ical
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrencePeriods is how many days, weeks, months or years a RRULE is expanded over before giving up,
+// ex. a daily event since 1970 is around 20000 days
+const maxRecurrencePeriods = 100000
+
+// weekdays are the days of the week in BYDAY and WKST
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// recurrence is a RRULE, RFC 5545 section 3.3.10. only what calendar apps write for events repeating every so many days,
+// weeks, months or years is supported, ex. every tuesday and thursday until june. anything else is rejected instead of
+// being expanded wrong
+type recurrence struct {
+	line      int
+	freq      string
+	interval  int
+	count     int
+	until     time.Time
+	byDay     map[time.Weekday]bool
+	weekStart time.Weekday
+}
+
+// parseRecurrence parses a RRULE, UNTIL is in DTSTART's timezone unless it's UTC
+func parseRecurrence(line contentLine, dtstart contentLine, allDay bool, loc *time.Location) (recurrence, error) {
+	r := recurrence{line: line.number, interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(line.value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return recurrence{}, errors.Errorf("line %d: invalid RRULE part %s", line.number, part)
+		}
+
+		name, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+		switch name {
+		case "FREQ":
+			if value != "DAILY" && value != "WEEKLY" && value != "MONTHLY" && value != "YEARLY" {
+				return recurrence{}, errors.Errorf("line %d: unsupported RRULE FREQ=%s", line.number, value)
+			}
+
+			r.freq = value
+		case "INTERVAL", "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return recurrence{}, errors.Errorf("line %d: invalid RRULE %s=%s", line.number, name, value)
+			}
+
+			if name == "INTERVAL" {
+				r.interval = n
+			} else {
+				r.count = n
+			}
+		case "UNTIL":
+			until, untilAllDay, err := parseDateTime(contentLine{number: line.number, params: map[string]string{"TZID": dtstart.params["TZID"]}, value: value}, loc)
+			if err != nil {
+				return recurrence{}, err
+			}
+
+			// UNTIL is inclusive, a date includes every occurrence that day
+			if untilAllDay && !allDay {
+				until = until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+
+			r.until = until
+		case "BYDAY":
+			r.byDay = make(map[time.Weekday]bool)
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return recurrence{}, errors.Errorf("line %d: unsupported RRULE BYDAY=%s, only days of the week are supported", line.number, value)
+				}
+
+				r.byDay[weekday] = true
+			}
+		case "WKST":
+			weekday, ok := weekdays[value]
+			if !ok {
+				return recurrence{}, errors.Errorf("line %d: invalid RRULE WKST=%s", line.number, value)
+			}
+
+			r.weekStart = weekday
+		default:
+			return recurrence{}, errors.Errorf("line %d: unsupported RRULE part %s", line.number, name)
+		}
+	}
+
+	if r.freq == "" {
+		return recurrence{}, errors.Errorf("line %d: RRULE without FREQ", line.number)
+	}
+
+	if r.byDay != nil && r.freq != "DAILY" && r.freq != "WEEKLY" {
+		return recurrence{}, errors.Errorf("line %d: unsupported RRULE BYDAY with FREQ=%s", line.number, r.freq)
+	}
+
+	if r.count > 0 && !r.until.IsZero() {
+		return recurrence{}, errors.Errorf("line %d: RRULE can't have both COUNT and UNTIL", line.number)
+	}
+
+	return r, nil
+}
+
+// expand gets the starts of the occurrences before horizon in order, DTSTART is always the first
+func (r recurrence) expand(dtstart time.Time, horizon time.Time) ([]time.Time, error) {
+	starts := make([]time.Time, 0)
+	add := func(t time.Time) bool {
+		if !t.Before(horizon) || (!r.until.IsZero() && t.After(r.until)) {
+			return false
+		}
+
+		starts = append(starts, t)
+		return r.count == 0 || len(starts) < r.count
+	}
+
+	if !add(dtstart) {
+		return starts, nil
+	}
+
+	for i := 0; i < maxRecurrencePeriods; i++ {
+		periodStart, occurrences := r.period(dtstart, i)
+		if !periodStart.Before(horizon) || (!r.until.IsZero() && periodStart.After(r.until)) {
+			return starts, nil
+		}
+
+		for _, t := range occurrences {
+			if !t.After(dtstart) {
+				continue
+			}
+
+			if !add(t) {
+				return starts, nil
+			}
+		}
+	}
+
+	return []time.Time{}, errors.Errorf("line %d: RRULE repeats too many times", r.line)
+}
+
+// period gets the start of the i-th period of the rule from DTSTART's, and the occurrences in it. days keep DTSTART's
+// time of day across daylight saving, and months or years without DTSTART's day, ex. the 31st, are skipped
+func (r recurrence) period(dtstart time.Time, i int) (time.Time, []time.Time) {
+	n := i * r.interval
+	switch r.freq {
+	case "DAILY":
+		t := dtstart.AddDate(0, 0, n)
+		if r.byDay != nil && !r.byDay[t.Weekday()] {
+			return t, nil
+		}
+
+		return t, []time.Time{t}
+	case "WEEKLY":
+		offset := (int(dtstart.Weekday()) - int(r.weekStart) + 7) % 7
+		weekStart := dtstart.AddDate(0, 0, 7*n-offset)
+		if r.byDay == nil {
+			return weekStart, []time.Time{dtstart.AddDate(0, 0, 7*n)}
+		}
+
+		occurrences := make([]time.Time, 0)
+		for d := 0; d < 7; d++ {
+			t := weekStart.AddDate(0, 0, d)
+			if r.byDay[t.Weekday()] {
+				occurrences = append(occurrences, t)
+			}
+		}
+
+		return weekStart, occurrences
+	case "MONTHLY":
+		month := dtstart.Month() + time.Month(n)
+		first := time.Date(dtstart.Year(), month, 1, 0, 0, 0, 0, dtstart.Location())
+		t := time.Date(dtstart.Year(), month, dtstart.Day(), dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+		if t.Day() != dtstart.Day() {
+			return first, nil
+		}
+
+		return first, []time.Time{t}
+	default:
+		first := time.Date(dtstart.Year()+n, time.January, 1, 0, 0, 0, 0, dtstart.Location())
+		t := time.Date(dtstart.Year()+n, dtstart.Month(), dtstart.Day(), dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+		if t.Month() != dtstart.Month() {
+			return first, nil
+		}
+
+		return first, []time.Time{t}
+	}
+}
+
+// expandEvent gets the occurrences of a recurring event overlapping from until until. they're its RRULE's and RDATEs,
+// less its EXDATEs and the occurrences another event with a RECURRENCE-ID replaced
+func expandEvent(e event, dtstart contentLine, startsAt time.Time, allDay bool, end func(time.Time) time.Time, loc *time.Location, from time.Time, until time.Time, overridden []time.Time) ([]Busy, error) {
+	starts := []time.Time{startsAt}
+	if rrule, ok := e.props()["RRULE"]; ok {
+		r, err := parseRecurrence(rrule, dtstart, allDay, loc)
+		if err != nil {
+			return []Busy{}, err
+		}
+
+		starts, err = r.expand(startsAt, until)
+		if err != nil {
+			return []Busy{}, err
+		}
+	}
+
+	occurrences := make([]Busy, 0, len(starts))
+	for _, t := range starts {
+		occurrences = append(occurrences, Busy{StartsAt: t, EndsAt: end(t)})
+	}
+
+	excluded := append([]time.Time{}, overridden...)
+	for _, line := range e.lines {
+		if line.name != "RDATE" && line.name != "EXDATE" {
+			continue
+		}
+
+		for _, value := range strings.Split(line.value, ",") {
+			if line.name == "RDATE" && strings.EqualFold(line.params["VALUE"], "PERIOD") {
+				period, err := parsePeriod(line, value, loc)
+				if err != nil {
+					return []Busy{}, err
+				}
+
+				occurrences = append(occurrences, period)
+				continue
+			}
+
+			t, _, err := parseDateTime(contentLine{number: line.number, params: line.params, value: value}, loc)
+			if err != nil {
+				return []Busy{}, err
+			}
+
+			if line.name == "RDATE" {
+				occurrences = append(occurrences, Busy{StartsAt: t, EndsAt: end(t)})
+			} else {
+				excluded = append(excluded, t)
+			}
+		}
+	}
+
+	busy := make([]Busy, 0, len(occurrences))
+	seen := make(map[int64]bool)
+	for _, o := range occurrences {
+		if seen[o.StartsAt.UnixNano()] || isExcluded(o.StartsAt, excluded) {
+			continue
+		}
+
+		seen[o.StartsAt.UnixNano()] = true
+		if !o.EndsAt.After(o.StartsAt) || !o.EndsAt.After(from) || !o.StartsAt.Before(until) {
+			continue
+		}
+
+		busy = append(busy, o)
+	}
+
+	return busy, nil
+}
+
+// isExcluded checks if the occurrence starting at t is one of the excluded ones
+func isExcluded(t time.Time, excluded []time.Time) bool {
+	for _, e := range excluded {
+		if e.Equal(t) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"github.com/pkg/errors"
+)
+
+// maxBatchSize caps how many appointments can be booked in one batch
+const maxBatchSize = 50
+
+// AppointmentBatchMode is how a batch handles appointments that can't be booked
+type AppointmentBatchMode string
+
+const (
+	// AppointmentBatchAllOrNothing books every appointment or none of them
+	AppointmentBatchAllOrNothing AppointmentBatchMode = "all_or_nothing"
+	// AppointmentBatchBestEffort books the appointments it can and reports the rest
+	AppointmentBatchBestEffort AppointmentBatchMode = "best_effort"
+)
+
+// Valid checks the mode is one of the known modes
+func (m AppointmentBatchMode) Valid() bool {
+	return m == AppointmentBatchAllOrNothing || m == AppointmentBatchBestEffort
+}
+
+// AppointmentBatchRequest models API Request Payload to book several appointments at once
+// Mode defaults to all_or_nothing
+type AppointmentBatchRequest struct {
+	Mode         AppointmentBatchMode       `json:"mode"`
+	Appointments []AppointmentCreateRequest `json:"appointments"`
+}
+
+// Validate checks the mode and batch size, and defaults the mode when it is missing
+func (r *AppointmentBatchRequest) Validate() error {
+	if r.Mode == "" {
+		r.Mode = AppointmentBatchAllOrNothing
+	}
+
+	if !r.Mode.Valid() {
+		return errors.Errorf("unknown mode %s", r.Mode)
+	}
+
+	if len(r.Appointments) == 0 || len(r.Appointments) > maxBatchSize {
+		return errors.Errorf("a batch must have between 1 and %d appointments", maxBatchSize)
+	}
+
+	return nil
+}
+
+// AppointmentBatchItemStatus is what happened to one appointment in a batch
+type AppointmentBatchItemStatus string
+
+const (
+	AppointmentBatchItemCreated AppointmentBatchItemStatus = "created"
+	AppointmentBatchItemFailed  AppointmentBatchItemStatus = "failed"
+	// AppointmentBatchItemSkipped is an appointment that could have been booked, but wasn't because another one in an all_or_nothing batch failed
+	AppointmentBatchItemSkipped AppointmentBatchItemStatus = "skipped"
+)
+
+// AppointmentBatchItemResult is the result for the appointment at Index in the request
+type AppointmentBatchItemResult struct {
+	Index       int                        `json:"index"`
+	Status      AppointmentBatchItemStatus `json:"status"`
+	Appointment *Appointment               `json:"appointment,omitempty"`
+	Error       string                     `json:"error,omitempty"`
+	ErrorType   string                     `json:"error_type,omitempty"`
+}
+
+// AppointmentBatchResult models API Response Payload for a batch, one result per appointment in request order
+type AppointmentBatchResult struct {
+	Mode    AppointmentBatchMode         `json:"mode"`
+	Results []AppointmentBatchItemResult `json:"results"`
+}
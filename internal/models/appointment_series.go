@@ -0,0 +1,145 @@
+package models
+
+import (
+	"github.com/pkg/errors"
+	"time"
+)
+
+// maxSeriesOccurrences caps how many appointments a series books at once, a year of weekly sessions
+const maxSeriesOccurrences = 52
+
+// SeriesFrequency is how often a recurring appointment repeats
+type SeriesFrequency string
+
+const (
+	SeriesFrequencyWeekly   SeriesFrequency = "weekly"
+	SeriesFrequencyBiweekly SeriesFrequency = "biweekly"
+)
+
+// Valid checks the frequency is one of the known frequencies
+func (f SeriesFrequency) Valid() bool {
+	return f == SeriesFrequencyWeekly || f == SeriesFrequencyBiweekly
+}
+
+// IntervalDays is how many days apart occurrences are
+func (f SeriesFrequency) IntervalDays() int {
+	if f == SeriesFrequencyBiweekly {
+		return 14
+	}
+
+	return 7
+}
+
+// AppointmentSeries models database table, a recurring booking that is expanded into appointments
+// the series ends after Count occurrences or on Until, only one of them is set
+type AppointmentSeries struct {
+	ID            int64           `json:"id" db:"id"`
+	TrainerID     int64           `json:"trainer_id" db:"trainer_id"`
+	UserID        int64           `json:"user_id" db:"user_id"`
+	SessionTypeID *int64          `json:"session_type_id,omitempty" db:"session_type_id"`
+	Frequency     SeriesFrequency `json:"frequency" db:"frequency"`
+	StartsAt      time.Time       `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time       `json:"ends_at" db:"ends_at"`
+	Count         *int            `json:"count,omitempty" db:"count"`
+	Until         *time.Time      `json:"until,omitempty" db:"until"`
+	CreatedAt     time.Time       `json:"-" db:"created_at"`
+}
+
+// AppointmentSeriesCreateRequest models API Request Payload to book a recurring appointment
+// StartsAt and EndsAt are the first occurrence, SkipConflicts books the free occurrences instead of rejecting the series
+type AppointmentSeriesCreateRequest struct {
+	TrainerID     int64           `json:"trainer_id"`
+	UserID        int64           `json:"user_id"`
+	StartsAt      time.Time       `json:"starts_at"`
+	EndsAt        time.Time       `json:"ends_at"`
+	SessionTypeID int64           `json:"session_type_id"`
+	Frequency     SeriesFrequency `json:"frequency"`
+	Count         int             `json:"count"`
+	Until         *time.Time      `json:"until"`
+	SkipConflicts bool            `json:"skip_conflicts"`
+}
+
+// Validate checks the participants, the frequency and that the series ends after a count or on a date, but not both
+func (r AppointmentSeriesCreateRequest) Validate() error {
+	if r.TrainerID == 0 || r.UserID == 0 {
+		return errors.New("trainer_id and user_id are required")
+	}
+
+	if !r.EndsAt.After(r.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	if !r.Frequency.Valid() {
+		return errors.Errorf("unknown frequency %s", r.Frequency)
+	}
+
+	if (r.Count == 0) == (r.Until == nil) {
+		return errors.New("one of count or until is required")
+	}
+
+	if r.Count < 0 || r.Count > maxSeriesOccurrences {
+		return errors.Errorf("count must be between 1 and %d", maxSeriesOccurrences)
+	}
+
+	if r.Until != nil && r.Until.Before(r.StartsAt) {
+		return errors.New("until must be after starts_at")
+	}
+
+	if r.Until != nil && len(r.Occurrences()) > maxSeriesOccurrences {
+		return errors.Errorf("until is too far out, a series can have at most %d occurrences", maxSeriesOccurrences)
+	}
+
+	return nil
+}
+
+// Occurrences expands the series into the time slots it books
+// occurrences keep the first one's local time in the gym's timezone, so a 5pm session stays at 5pm across daylight saving changes
+func (r AppointmentSeriesCreateRequest) Occurrences() []TimeSlot {
+	loc, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	startsAt := r.StartsAt.In(loc)
+	duration := r.EndsAt.Sub(r.StartsAt)
+
+	occurrences := make([]TimeSlot, 0)
+	for i := 0; ; i++ {
+		if r.Count > 0 && i == r.Count {
+			break
+		}
+
+		occurrenceStart := startsAt.AddDate(0, 0, i*r.Frequency.IntervalDays())
+		if r.Until != nil && occurrenceStart.After(*r.Until) {
+			break
+		}
+
+		// stops an until far in the future from expanding forever, Validate rejects the series
+		if i > maxSeriesOccurrences {
+			break
+		}
+
+		occurrences = append(occurrences, TimeSlot{
+			TrainerID: r.TrainerID,
+			StartsAt:  occurrenceStart.UTC(),
+			EndsAt:    occurrenceStart.Add(duration).UTC(),
+		})
+	}
+
+	return occurrences
+}
+
+// SeriesConflict is an occurrence of a series that can't be booked and why
+type SeriesConflict struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Type     string    `json:"type"`
+	Message  string    `json:"message"`
+}
+
+// AppointmentSeriesResult models API Response Payload for a series, Conflicts are the occurrences that were skipped
+type AppointmentSeriesResult struct {
+	Series       AppointmentSeries `json:"series"`
+	Appointments []Appointment     `json:"appointments"`
+	Conflicts    []SeriesConflict  `json:"conflicts"`
+}
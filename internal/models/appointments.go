@@ -1,26 +1,173 @@
 package models
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
 
 // Appointment models database table
+// SessionTypeID is nil for appointments using the default 30-minute session, SeriesID is nil for one-off appointments
+// Version is bumped on every change and is the appointment's ETag, it isn't returned for available appointments
 type Appointment struct {
-	ID         int64      `json:"id,omitempty" db:"id"`
-	TrainerID  int64      `json:"trainer_id" db:"trainer_id"`
-	UserID     int64      `json:"user_id,omitempty" db:"user_id"`
-	StartsAt   time.Time  `json:"starts_at" db:"starts_at"`
-	EndsAt     time.Time  `json:"ends_at" db:"ends_at"`
-	CreatedAt  time.Time  `json:"-" db:"created_at"`
-	UpdatedAt  time.Time  `json:"-" db:"updated_at"`
-	CanceledAt *time.Time `json:"canceled_at,omitempty" db:"canceled_at"`
+	ID            int64      `json:"id,omitempty" db:"id"`
+	TrainerID     int64      `json:"trainer_id" db:"trainer_id"`
+	UserID        int64      `json:"user_id,omitempty" db:"user_id"`
+	StartsAt      time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time  `json:"ends_at" db:"ends_at"`
+	SessionTypeID *int64     `json:"session_type_id,omitempty" db:"session_type_id"`
+	SeriesID      *int64     `json:"series_id,omitempty" db:"series_id"`
+	CreatedAt     time.Time  `json:"-" db:"created_at"`
+	UpdatedAt     time.Time  `json:"-" db:"updated_at"`
+	CanceledAt    *time.Time `json:"canceled_at,omitempty" db:"canceled_at"`
+	Version       int64      `json:"version,omitempty" db:"version"`
+}
+
+// ETag is the appointment's version as a strong entity tag, to send back in If-Match when changing the appointment
+func (a Appointment) ETag() string {
+	return fmt.Sprintf(`"%d"`, a.Version)
 }
 
 // AppointmentCreateRequest models API Request Payload to create an appointment
+// SessionTypeID is optional, when it is 0 the appointment is the default 30-minute session
 type AppointmentCreateRequest struct {
-	ID        int64     `json:"id" db:"id"`
+	ID            int64     `json:"id" db:"id"`
+	TrainerID     int64     `json:"trainer_id" db:"trainer_id"`
+	UserID        int64     `json:"user_id" db:"user_id"`
+	StartsAt      time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time `json:"ends_at" db:"ends_at"`
+	SessionTypeID int64     `json:"session_type_id" db:"session_type_id"`
+}
+
+// AppointmentRescheduleRequest models API Request Payload to move an appointment to another time slot
+// TrainerID is optional, when it is 0 the appointment keeps its trainer
+type AppointmentRescheduleRequest struct {
+	TrainerID int64     `json:"trainer_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// TimeSlot is the time range an appointment takes up
+type TimeSlot struct {
 	TrainerID int64     `json:"trainer_id" db:"trainer_id"`
-	UserID    int64     `json:"user_id" db:"user_id"`
 	StartsAt  time.Time `json:"starts_at" db:"starts_at"`
 	EndsAt    time.Time `json:"ends_at" db:"ends_at"`
 }
 
+// Overlaps checks if the time slot overlaps any part of the time range, touching end to start isn't overlapping
+func (t TimeSlot) Overlaps(startsAt time.Time, endsAt time.Time) bool {
+	return t.StartsAt.Before(endsAt) && startsAt.Before(t.EndsAt)
+}
+
+// AvailableSlot models a time slot that is open with at least one trainer, TrainerIDs are the trainers free for the whole slot
+type AvailableSlot struct {
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	SessionTypeID *int64    `json:"session_type_id,omitempty"`
+	TrainerIDs    []int64   `json:"trainer_ids"`
+}
+
 // TODO: refactor validation in controller func as methods on AppointmentCreateRequest struct
+
+// AppointmentStatus filters appointments on whether they have been canceled
+type AppointmentStatus string
+
+const (
+	AppointmentStatusActive   AppointmentStatus = "active"
+	AppointmentStatusCanceled AppointmentStatus = "canceled"
+	AppointmentStatusAll      AppointmentStatus = "all"
+)
+
+// Valid checks the status is one of the known statuses
+func (s AppointmentStatus) Valid() bool {
+	return s == AppointmentStatusActive || s == AppointmentStatusCanceled || s == AppointmentStatusAll
+}
+
+// AppointmentsSort is the order scheduled appointments are listed in, a leading - sorts descending
+type AppointmentsSort string
+
+const (
+	AppointmentsSortStartsAt     AppointmentsSort = "starts_at"
+	AppointmentsSortStartsAtDesc AppointmentsSort = "-starts_at"
+	AppointmentsSortCreatedAt    AppointmentsSort = "created_at"
+)
+
+// Valid checks the sort is one of the known sorts
+func (s AppointmentsSort) Valid() bool {
+	return s == AppointmentsSortStartsAt || s == AppointmentsSortStartsAtDesc || s == AppointmentsSortCreatedAt
+}
+
+// Column is the column appointments are sorted on
+func (s AppointmentsSort) Column() string {
+	return strings.TrimPrefix(string(s), "-")
+}
+
+// Descending checks if the sort is newest first
+func (s AppointmentsSort) Descending() bool {
+	return strings.HasPrefix(string(s), "-")
+}
+
+// AppointmentsFilter models the filters used when listing scheduled appointments
+// Limit of 0 returns every matching appointment, Cursor is nil for the first page
+type AppointmentsFilter struct {
+	TrainerID int64
+	UserID    int64
+	StartsAt  time.Time
+	EndsAt    time.Time
+	Status    AppointmentStatus
+	Sort      AppointmentsSort
+	Limit     int
+	Cursor    *AppointmentsCursor
+}
+
+// AppointmentsCursor is the position of the last appointment on a page, the value of the sorted column and the ID to break ties
+type AppointmentsCursor struct {
+	Sort  AppointmentsSort `json:"s"`
+	Value time.Time        `json:"v"`
+	ID    int64            `json:"id"`
+}
+
+// NewAppointmentsCursor makes a cursor positioned at the appointment
+func NewAppointmentsCursor(sort AppointmentsSort, a Appointment) AppointmentsCursor {
+	value := a.StartsAt
+	if sort.Column() == "created_at" {
+		value = a.CreatedAt
+	}
+
+	return AppointmentsCursor{Sort: sort, Value: value, ID: a.ID}
+}
+
+// Encode makes the opaque cursor string handed to clients
+func (c AppointmentsCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeAppointmentsCursor parses a cursor string made by Encode
+func DecodeAppointmentsCursor(cursor string) (AppointmentsCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return AppointmentsCursor{}, errors.Wrap(err, "invalid cursor")
+	}
+
+	var c AppointmentsCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return AppointmentsCursor{}, errors.Wrap(err, "invalid cursor")
+	}
+
+	if !c.Sort.Valid() || c.ID == 0 {
+		return AppointmentsCursor{}, errors.New("invalid cursor")
+	}
+
+	return c, nil
+}
+
+// AppointmentsPage models API Response Payload for a page of scheduled appointments
+// NextCursor is empty on the last page
+type AppointmentsPage struct {
+	Appointments []Appointment `json:"appointments"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// CalendarOwner is who a calendar feed is for, a trainer gets their bookings and a user gets their appointments
+type CalendarOwner string
+
+const (
+	CalendarOwnerTrainer CalendarOwner = "trainer"
+	CalendarOwnerUser    CalendarOwner = "user"
+)
+
+// CalendarFeed is the URL calendar apps subscribe to, it has the feed's token so it has to be kept secret
+type CalendarFeed struct {
+	URL string `json:"url"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Hold models database table, a time slot reserved for a user until it expires
+// ReleasedAt is set when the hold is released early or swept after expiring, AppointmentID when it is confirmed
+type Hold struct {
+	ID            int64      `json:"id" db:"id"`
+	TrainerID     int64      `json:"trainer_id" db:"trainer_id"`
+	UserID        int64      `json:"user_id" db:"user_id"`
+	StartsAt      time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time  `json:"ends_at" db:"ends_at"`
+	SessionTypeID *int64     `json:"session_type_id,omitempty" db:"session_type_id"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty" db:"released_at"`
+	AppointmentID *int64     `json:"appointment_id,omitempty" db:"appointment_id"`
+	CreatedAt     time.Time  `json:"-" db:"created_at"`
+}
+
+// HoldCreateRequest models API Request Payload to hold a time slot
+// TTLSeconds is optional, when it is 0 the hold lasts the configured default
+type HoldCreateRequest struct {
+	TrainerID     int64     `json:"trainer_id"`
+	UserID        int64     `json:"user_id"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	SessionTypeID int64     `json:"session_type_id"`
+	TTLSeconds    int       `json:"ttl_seconds"`
+}
+
+// AppointmentCreateRequest is the appointment the hold is for, used to validate it the same way as booking
+func (r HoldCreateRequest) AppointmentCreateRequest() AppointmentCreateRequest {
+	return AppointmentCreateRequest{
+		TrainerID:     r.TrainerID,
+		UserID:        r.UserID,
+		StartsAt:      r.StartsAt,
+		EndsAt:        r.EndsAt,
+		SessionTypeID: r.SessionTypeID,
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey models database table, the response to a request sent with an Idempotency-Key header
+// ResponseStatus is nil while the first request with the key is still being handled
+type IdempotencyKey struct {
+	Endpoint       string     `db:"endpoint"`
+	Key            string     `db:"key"`
+	RequestHash    string     `db:"request_hash"`
+	ResponseStatus *int       `db:"response_status"`
+	ResponseBody   []byte     `db:"response_body"`
+	CreatedAt      time.Time  `db:"created_at"`
+	CompletedAt    *time.Time `db:"completed_at"`
+}
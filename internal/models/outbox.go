@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxEvent models database table, an event waiting to be published. Payload is the event's JSON data
+// LastError is why the last attempt failed, PublishedAt is nil until it is published
+type OutboxEvent struct {
+	ID            int64      `db:"id"`
+	EventType     string     `db:"event_type"`
+	Payload       []byte     `db:"payload"`
+	OccurredAt    time.Time  `db:"occurred_at"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     *string    `db:"last_error"`
+	PublishedAt   *time.Time `db:"published_at"`
+}
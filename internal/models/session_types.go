@@ -0,0 +1,56 @@
+package models
+
+import (
+	"github.com/pkg/errors"
+	"time"
+)
+
+// DefaultSessionType is used for appointments without a session type, the original 30-minute session
+var DefaultSessionType = SessionType{
+	Name:                   "30 minute session",
+	DurationMinutes:        30,
+	SlotGranularityMinutes: 30,
+}
+
+// SessionType models database table, the kinds of sessions that can be booked
+type SessionType struct {
+	ID                     int64     `json:"id" db:"id"`
+	Name                   string    `json:"name" db:"name"`
+	DurationMinutes        int       `json:"duration_minutes" db:"duration_minutes"`
+	SlotGranularityMinutes int       `json:"slot_granularity_minutes" db:"slot_granularity_minutes"`
+	CreatedAt              time.Time `json:"-" db:"created_at"`
+}
+
+// Duration is how long a session is
+func (s SessionType) Duration() time.Duration {
+	return time.Duration(s.DurationMinutes) * time.Minute
+}
+
+// Granularity is how far apart session start times are, ex. every 15 minutes
+func (s SessionType) Granularity() time.Duration {
+	return time.Duration(s.SlotGranularityMinutes) * time.Minute
+}
+
+// SessionTypeCreateRequest models API Request Payload to create a session type
+type SessionTypeCreateRequest struct {
+	Name                   string `json:"name"`
+	DurationMinutes        int    `json:"duration_minutes"`
+	SlotGranularityMinutes int    `json:"slot_granularity_minutes"`
+}
+
+// Validate checks the duration and granularity, granularity has to divide an hour so start times line up every hour
+func (r SessionTypeCreateRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if r.DurationMinutes <= 0 || r.DurationMinutes > 8*60 {
+		return errors.New("invalid duration, must be between 1 minute and 8 hours")
+	}
+
+	if r.SlotGranularityMinutes <= 0 || 60%r.SlotGranularityMinutes != 0 {
+		return errors.New("invalid slot granularity, must divide an hour evenly")
+	}
+
+	return nil
+}
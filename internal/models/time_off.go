@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// TimeOffSource is where time off came from
+type TimeOffSource string
+
+const (
+	TimeOffSourceManual TimeOffSource = "manual"
+	// TimeOffSourceICS is time off imported from a trainer's calendar, each import replaces the last one
+	TimeOffSourceICS TimeOffSource = "ics"
+)
+
+// TimeOff models database table, a blackout period where a trainer can't be booked
+// TrainerID is nil for gym-wide blackouts like holidays, which apply to every trainer
+type TimeOff struct {
+	ID        int64         `json:"id" db:"id"`
+	TrainerID *int64        `json:"trainer_id" db:"trainer_id"`
+	StartsAt  time.Time     `json:"starts_at" db:"starts_at"`
+	EndsAt    time.Time     `json:"ends_at" db:"ends_at"`
+	Reason    string        `json:"reason" db:"reason"`
+	Source    TimeOffSource `json:"source" db:"source"`
+	CreatedAt time.Time     `json:"-" db:"created_at"`
+}
+
+// TimeOffCreateRequest models API Request Payload to create a blackout period, leave out trainer_id for a gym-wide blackout
+type TimeOffCreateRequest struct {
+	TrainerID *int64    `json:"trainer_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Reason    string    `json:"reason"`
+}
+
+// TimeOffFilter models the filters used when listing time off
+type TimeOffFilter struct {
+	TrainerID int64
+	StartsAt  time.Time
+	EndsAt    time.Time
+}
+
+// Overlaps checks if the time off overlaps any part of the time range
+func (t TimeOff) Overlaps(startsAt time.Time, endsAt time.Time) bool {
+	return t.StartsAt.Before(endsAt) && startsAt.Before(t.EndsAt)
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"github.com/pkg/errors"
+	"net/mail"
+	"time"
+)
+
+// Trainer models database table, inactive trainers can't be booked
+type Trainer struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email" db:"email"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+// TrainerRequest models API Request Payload to create or update a trainer
+// Active is optional, trainers are active unless it is false
+type TrainerRequest struct {
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Active *bool  `json:"active"`
+}
+
+// Validate checks the name and email, and defaults active when it is missing
+func (r *TrainerRequest) Validate() error {
+	if err := validateNameAndEmail(r.Name, r.Email); err != nil {
+		return err
+	}
+
+	if r.Active == nil {
+		active := true
+		r.Active = &active
+	}
+
+	return nil
+}
+
+func validateNameAndEmail(name string, email string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+
+	if _, err := mail.ParseAddress(email); err != nil {
+		return errors.Wrap(err, "invalid email")
+	}
+
+	return nil
+}
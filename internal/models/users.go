@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// User models database table, the clients booking appointments
+type User struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email" db:"email"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+// UserRequest models API Request Payload to create or update a user
+// Active is optional, users are active unless it is false
+type UserRequest struct {
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Active *bool  `json:"active"`
+}
+
+// Validate checks the name and email, and defaults active when it is missing
+func (r *UserRequest) Validate() error {
+	if err := validateNameAndEmail(r.Name, r.Email); err != nil {
+		return err
+	}
+
+	if r.Active == nil {
+		active := true
+		r.Active = &active
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// WaitlistStatus is where a waitlist entry is at
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting WaitlistStatus = "waiting"
+	// WaitlistOffered is an entry that was offered the time slot as a hold
+	WaitlistOffered WaitlistStatus = "offered"
+	// WaitlistRemoved is an entry the user left before it was offered
+	WaitlistRemoved WaitlistStatus = "removed"
+)
+
+// WaitlistEntry models database table, a user waiting for a booked time slot
+type WaitlistEntry struct {
+	ID            int64          `json:"id" db:"id"`
+	TrainerID     int64          `json:"trainer_id" db:"trainer_id"`
+	UserID        int64          `json:"user_id" db:"user_id"`
+	StartsAt      time.Time      `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time      `json:"ends_at" db:"ends_at"`
+	SessionTypeID *int64         `json:"session_type_id,omitempty" db:"session_type_id"`
+	Status        WaitlistStatus `json:"status" db:"status"`
+	HoldID        *int64         `json:"hold_id,omitempty" db:"hold_id"`
+	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
+}
+
+// WaitlistCreateRequest models API Request Payload to join the waitlist for a time slot
+type WaitlistCreateRequest struct {
+	TrainerID     int64     `json:"trainer_id"`
+	UserID        int64     `json:"user_id"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	SessionTypeID int64     `json:"session_type_id"`
+}
+
+// AppointmentCreateRequest is the appointment the user is waiting for, used to validate it the same way as booking
+func (r WaitlistCreateRequest) AppointmentCreateRequest() AppointmentCreateRequest {
+	return AppointmentCreateRequest{
+		TrainerID:     r.TrainerID,
+		UserID:        r.UserID,
+		StartsAt:      r.StartsAt,
+		EndsAt:        r.EndsAt,
+		SessionTypeID: r.SessionTypeID,
+	}
+}
+
+// WaitlistOffer is a freed time slot offered to the user waiting the longest, as a hold they can confirm
+type WaitlistOffer struct {
+	Entry WaitlistEntry `json:"waitlist_entry"`
+	Hold  Hold          `json:"hold"`
+}
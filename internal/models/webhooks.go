@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"net/url"
+	"time"
+)
+
+// minWebhookSecretLength keeps partners from signing with a guessable secret
+const minWebhookSecretLength = 16
+
+// Webhook models database table, a partner's subscription to events. inactive webhooks don't get deliveries
+// Secret signs the deliveries, it is only returned when the webhook is created
+type Webhook struct {
+	ID         int64          `json:"id" db:"id"`
+	URL        string         `json:"url" db:"url"`
+	Secret     string         `json:"secret,omitempty" db:"secret"`
+	EventTypes pq.StringArray `json:"event_types" db:"event_types"`
+	Active     bool           `json:"active" db:"active"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+// WebhookCreateRequest models API Request Payload to subscribe to events
+// Secret is optional, one is generated when it is empty
+type WebhookCreateRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+// Validate checks the url is http or https and the secret is long enough, event types are checked against the known events
+func (r WebhookCreateRequest) Validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return errors.Wrap(err, "invalid url")
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("invalid url %s, it has to be http or https", r.URL)
+	}
+
+	if r.Secret != "" && len(r.Secret) < minWebhookSecretLength {
+		return errors.Errorf("secret has to be at least %d characters", minWebhookSecretLength)
+	}
+
+	if len(r.EventTypes) == 0 {
+		return errors.New("event_types is required")
+	}
+
+	return nil
+}
+
+// WebhookDeliveryStatus is where a delivery is at
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryDelivered is a delivery the webhook responded to with a 2xx
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryFailed is a delivery that ran out of attempts
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery models database table, an event sent to a webhook. Payload is the event as it's posted
+// AttemptCount is how many times it was tried, Attempts has each try when getting the delivery history
+type WebhookDelivery struct {
+	ID            int64                    `json:"id" db:"id"`
+	WebhookID     int64                    `json:"webhook_id" db:"webhook_id"`
+	EventID       int64                    `json:"event_id" db:"event_id"`
+	EventType     string                   `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage          `json:"payload" db:"payload"`
+	Status        WebhookDeliveryStatus    `json:"status" db:"status"`
+	AttemptCount  int                      `json:"attempt_count" db:"attempts"`
+	NextAttemptAt time.Time                `json:"-" db:"next_attempt_at"`
+	CreatedAt     time.Time                `json:"created_at" db:"created_at"`
+	DeliveredAt   *time.Time               `json:"delivered_at,omitempty" db:"delivered_at"`
+	Attempts      []WebhookDeliveryAttempt `json:"attempts" db:"-"`
+}
+
+// WebhookDispatch is a claimed delivery with where to send it and the secret to sign it with
+type WebhookDispatch struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// WebhookDeliveryAttempt models database table, one try at a delivery
+// ResponseStatus is nil when there was no response, ex. the request timed out
+type WebhookDeliveryAttempt struct {
+	ID             int64     `json:"id" db:"id"`
+	DeliveryID     int64     `json:"-" db:"delivery_id"`
+	ResponseStatus *int      `json:"response_status,omitempty" db:"response_status"`
+	Error          *string   `json:"error,omitempty" db:"error"`
+	DurationMS     int       `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+// Succeeded is whether the webhook accepted the delivery
+func (a WebhookDeliveryAttempt) Succeeded() bool {
+	return a.ResponseStatus != nil && *a.ResponseStatus >= 200 && *a.ResponseStatus <= 299
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"github.com/pkg/errors"
+	"time"
+)
+
+// TimeOfDayFormat is the format working hours start/end times are sent and stored in
+const TimeOfDayFormat = "15:04"
+
+// DefaultTimezone is the timezone the gym operates in
+const DefaultTimezone = "America/Los_Angeles"
+
+// WorkingHours models database table, a trainer's shift on a day of the week
+type WorkingHours struct {
+	ID        int64        `json:"id" db:"id"`
+	TrainerID int64        `json:"trainer_id" db:"trainer_id"`
+	Weekday   time.Weekday `json:"weekday" db:"weekday"`
+	StartTime string       `json:"start_time" db:"start_time"`
+	EndTime   string       `json:"end_time" db:"end_time"`
+	Timezone  string       `json:"timezone" db:"timezone"`
+	CreatedAt time.Time    `json:"-" db:"created_at"`
+	UpdatedAt time.Time    `json:"-" db:"updated_at"`
+}
+
+// WorkingHoursRequest models API Request Payload to create or update a trainer's working hours
+type WorkingHoursRequest struct {
+	Weekday   time.Weekday `json:"weekday"`
+	StartTime string       `json:"start_time"`
+	EndTime   string       `json:"end_time"`
+	Timezone  string       `json:"timezone"`
+}
+
+// Validate checks the weekday, times and timezone, and defaults the timezone when it is missing
+func (r *WorkingHoursRequest) Validate() error {
+	if r.Weekday < time.Sunday || r.Weekday > time.Saturday {
+		return errors.New("invalid weekday, must be 0 (Sunday) through 6 (Saturday)")
+	}
+
+	start, err := time.Parse(TimeOfDayFormat, r.StartTime)
+	if err != nil {
+		return errors.Wrap(err, "invalid start time")
+	}
+
+	end, err := time.Parse(TimeOfDayFormat, r.EndTime)
+	if err != nil {
+		return errors.Wrap(err, "invalid end time")
+	}
+
+	if !start.Before(end) {
+		return errors.New("start time must be before end time")
+	}
+
+	if r.Timezone == "" {
+		r.Timezone = DefaultTimezone
+	}
+
+	if _, err := time.LoadLocation(r.Timezone); err != nil {
+		return errors.Wrap(err, "invalid timezone")
+	}
+
+	return nil
+}
+
+// shift is a parsed WorkingHours, so locations are only loaded once per schedule
+type shift struct {
+	weekday time.Weekday
+	start   int // minutes after midnight
+	end     int // minutes after midnight
+	loc     *time.Location
+}
+
+// WorkingSchedule is the set of shifts a trainer works during the week, minus any time off
+type WorkingSchedule struct {
+	shifts  []shift
+	timeOff []TimeOff
+}
+
+// DefaultWorkingHours are used for trainers without any working hours, Monday through Friday 8am to 5pm pacific
+func DefaultWorkingHours(trainerID int64) []WorkingHours {
+	hours := make([]WorkingHours, 0, 5)
+	for weekday := time.Monday; weekday <= time.Friday; weekday++ {
+		hours = append(hours, WorkingHours{
+			TrainerID: trainerID,
+			Weekday:   weekday,
+			StartTime: "08:00",
+			EndTime:   "17:00",
+			Timezone:  DefaultTimezone,
+		})
+	}
+
+	return hours
+}
+
+// NewWorkingSchedule builds a schedule from working hours, falling back to the default working hours when there are none
+func NewWorkingSchedule(trainerID int64, hours []WorkingHours) (WorkingSchedule, error) {
+	if len(hours) == 0 {
+		hours = DefaultWorkingHours(trainerID)
+	}
+
+	schedule := WorkingSchedule{shifts: make([]shift, 0, len(hours))}
+	for _, h := range hours {
+		loc, err := time.LoadLocation(h.Timezone)
+		if err != nil {
+			return WorkingSchedule{}, errors.Wrap(err, "could not load timezone location")
+		}
+
+		start, err := time.Parse(TimeOfDayFormat, h.StartTime)
+		if err != nil {
+			return WorkingSchedule{}, errors.Wrap(err, "invalid working hours start time")
+		}
+
+		end, err := time.Parse(TimeOfDayFormat, h.EndTime)
+		if err != nil {
+			return WorkingSchedule{}, errors.Wrap(err, "invalid working hours end time")
+		}
+
+		schedule.shifts = append(schedule.shifts, shift{
+			weekday: h.Weekday,
+			start:   start.Hour()*60 + start.Minute(),
+			end:     end.Hour()*60 + end.Minute(),
+			loc:     loc,
+		})
+	}
+
+	return schedule, nil
+}
+
+// WithTimeOff returns a copy of the schedule where the time off is unavailable
+func (s WorkingSchedule) WithTimeOff(timeOff []TimeOff) WorkingSchedule {
+	s.timeOff = append(append([]TimeOff{}, s.timeOff...), timeOff...)
+	return s
+}
+
+// Contains checks if the time slot falls completely within one of the shifts and doesn't overlap any time off
+func (s WorkingSchedule) Contains(startsAt time.Time, endsAt time.Time) bool {
+	for _, t := range s.timeOff {
+		if t.Overlaps(startsAt, endsAt) {
+			return false
+		}
+	}
+
+	for _, sh := range s.shifts {
+		localStart := startsAt.In(sh.loc)
+		if localStart.Weekday() != sh.weekday {
+			continue
+		}
+
+		// time.Date normalizes the minutes, which keeps the shift right on daylight saving days
+		shiftStart := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, sh.start, 0, 0, sh.loc)
+		shiftEnd := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, sh.end, 0, 0, sh.loc)
+		if !startsAt.Before(shiftStart) && !endsAt.After(shiftEnd) {
+			return true
+		}
+	}
+
+	return false
+}
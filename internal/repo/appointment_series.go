@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"sort"
+)
+
+const createAppointmentSeriesQuery = `
+insert into scheduling.appointment_series(trainer_id, user_id, session_type_id, frequency, starts_at, ends_at, count, until)
+VALUES ($1, $2, nullif($3, 0), $4, $5, $6, nullif($7, 0), $8)
+returning id, trainer_id, user_id, session_type_id, frequency, starts_at, ends_at, count, until, created_at
+`
+
+// CreateAppointmentSeries saves the series and books each occurrence in a single transaction, so either every occurrence
+// is booked or none are. occurrences are expected to already be checked for conflicts, one booked since returns a SlotTakenError
+func (ar *AppointmentsRepoType) CreateAppointmentSeries(ctx context.Context, series models.AppointmentSeriesCreateRequest, occurrences []models.TimeSlot) (models.AppointmentSeriesResult, error) {
+	tx, err := ar.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.AppointmentSeriesResult{}, errors.Wrap(err, "error creating appointment series")
+	}
+	defer tx.Rollback()
+
+	var s models.AppointmentSeries
+	err = tx.QueryRowxContext(ctx, createAppointmentSeriesQuery, series.TrainerID, series.UserID, series.SessionTypeID, series.Frequency,
+		series.StartsAt, series.EndsAt, series.Count, series.Until).StructScan(&s)
+
+	if isConstraintViolation(err, foreignKeyViolation, appointmentSeriesTrainerFKey) {
+		return models.AppointmentSeriesResult{}, ErrUnknownTrainer
+	}
+
+	if isConstraintViolation(err, foreignKeyViolation, appointmentSeriesUserFKey) {
+		return models.AppointmentSeriesResult{}, ErrUnknownUser
+	}
+
+	if err != nil {
+		return models.AppointmentSeriesResult{}, errors.Wrap(err, "error creating appointment series")
+	}
+
+	appts := make([]models.Appointment, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		// a SlotTakenError is from the time slot being booked or held by someone else since checking for conflicts
+		a, err := createAppointment(ctx, tx, models.AppointmentCreateRequest{
+			TrainerID:     series.TrainerID,
+			UserID:        series.UserID,
+			StartsAt:      occurrence.StartsAt,
+			EndsAt:        occurrence.EndsAt,
+			SessionTypeID: series.SessionTypeID,
+		}, s.ID)
+		if err != nil {
+			return models.AppointmentSeriesResult{}, err
+		}
+
+		appts = append(appts, a)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.AppointmentSeriesResult{}, errors.Wrap(err, "error creating appointment series")
+	}
+
+	return models.AppointmentSeriesResult{Series: s, Appointments: appts, Conflicts: []models.SeriesConflict{}}, nil
+}
+
+const getAppointmentSeriesQuery = `
+select id, trainer_id, user_id, session_type_id, frequency, starts_at, ends_at, count, until, created_at
+from scheduling.appointment_series
+where id = $1
+`
+
+const getSeriesAppointmentsQuery = `
+select id, trainer_id, user_id, starts_at, ends_at, session_type_id, series_id, created_at, updated_at, canceled_at, version
+from scheduling.appointments
+where series_id = $1
+order by starts_at, id
+`
+
+// GetAppointmentSeries gets a series with all of its appointments, canceled or not. returns sql.ErrNoRows when it doesn't exist
+func (ar *AppointmentsRepoType) GetAppointmentSeries(ctx context.Context, id int64) (models.AppointmentSeriesResult, error) {
+	var s models.AppointmentSeries
+	err := ar.db.QueryRowxContext(ctx, getAppointmentSeriesQuery, id).StructScan(&s)
+	if err != nil {
+		return models.AppointmentSeriesResult{}, errors.Wrap(err, "error getting appointment series")
+	}
+
+	rows, err := ar.db.QueryxContext(ctx, getSeriesAppointmentsQuery, id)
+	if err != nil {
+		return models.AppointmentSeriesResult{}, errors.Wrap(err, "error getting appointment series")
+	}
+	defer rows.Close()
+
+	appts := make([]models.Appointment, 0)
+	for rows.Next() {
+		var a models.Appointment
+		if err := rows.StructScan(&a); err != nil {
+			return models.AppointmentSeriesResult{}, errors.Wrap(err, "error getting appointment series")
+		}
+
+		appts = append(appts, a)
+	}
+
+	return models.AppointmentSeriesResult{Series: s, Appointments: appts, Conflicts: []models.SeriesConflict{}}, nil
+}
+
+const cancelFollowingAppointmentsQuery = `
+update scheduling.appointments
+set canceled_at = now(), updated_at = now(), version = version + 1
+where series_id = $1 and starts_at >= $2 and canceled_at is null
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, series_id, created_at, updated_at, canceled_at, version
+`
+
+// CancelFollowingAppointments cancels the appointment and every later appointment in its series in a single transaction
+// earlier occurrences that were already canceled are left alone. returns ErrNotInSeries for a one-off appointment
+// and ErrStaleVersion when the appointment changed since the client got version
+func (ar *AppointmentsRepoType) CancelFollowingAppointments(ctx context.Context, id int64, version int64) ([]models.Appointment, error) {
+	tx, err := ar.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return []models.Appointment{}, errors.Wrap(err, "error canceling appointments")
+	}
+	defer tx.Rollback()
+
+	var current models.Appointment
+	err = tx.QueryRowxContext(ctx, getAppointmentForUpdateQuery, id).StructScan(&current)
+	if err != nil {
+		return []models.Appointment{}, errors.Wrap(err, "error canceling appointments")
+	}
+
+	if current.SeriesID == nil {
+		return []models.Appointment{}, ErrNotInSeries
+	}
+
+	if current.CanceledAt != nil {
+		return []models.Appointment{}, ErrAlreadyCanceled
+	}
+
+	// only the appointment's version is checked, it's the one the client has
+	if current.Version != version {
+		return []models.Appointment{}, ErrStaleVersion
+	}
+
+	rows, err := tx.QueryxContext(ctx, cancelFollowingAppointmentsQuery, *current.SeriesID, current.StartsAt)
+	if err != nil {
+		return []models.Appointment{}, errors.Wrap(err, "error canceling appointments")
+	}
+	defer rows.Close()
+
+	appts := make([]models.Appointment, 0)
+	for rows.Next() {
+		var a models.Appointment
+		if err := rows.StructScan(&a); err != nil {
+			return []models.Appointment{}, errors.Wrap(err, "error canceling appointments")
+		}
+
+		appts = append(appts, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []models.Appointment{}, errors.Wrap(err, "error canceling appointments")
+	}
+
+	for _, a := range appts {
+		err = addEvent(ctx, tx, events.NewAppointmentCanceledEvent(a))
+		if err != nil {
+			return []models.Appointment{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []models.Appointment{}, errors.Wrap(err, "error canceling appointments")
+	}
+
+	// update ... returning doesn't keep an order
+	sort.Slice(appts, func(i, j int) bool { return appts[i].StartsAt.Before(appts[j].StartsAt) })
+	return appts, nil
+}
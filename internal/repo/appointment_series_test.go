@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func weeklySeries(trainerID int64, userID int64, startsAt time.Time) models.AppointmentSeriesCreateRequest {
+	return models.AppointmentSeriesCreateRequest{
+		TrainerID: trainerID,
+		UserID:    userID,
+		StartsAt:  startsAt,
+		EndsAt:    startsAt.Add(30 * time.Minute),
+		Frequency: models.SeriesFrequencyWeekly,
+		Count:     3,
+	}
+}
+
+func TestAppointmentRepository_CreateAppointmentSeries(t *testing.T) {
+	PurgeTables()
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	series := weeklySeries(1, 1, time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC))
+	created, err := r.CreateAppointmentSeries(context.Background(), series, series.Occurrences())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, models.SeriesFrequencyWeekly, created.Series.Frequency)
+	assert.Equal(t, 3, *created.Series.Count)
+	assert.Len(t, created.Appointments, 3)
+	for _, a := range created.Appointments {
+		assert.Equal(t, created.Series.ID, *a.SeriesID)
+	}
+
+	got, err := r.GetAppointmentSeries(context.Background(), created.Series.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, created.Appointments, got.Appointments)
+
+	// its first occurrence overlaps the last one of the first series, so none of the second series is booked
+	overlapping := weeklySeries(1, 2, time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC).AddDate(0, 0, 14))
+	_, err = r.CreateAppointmentSeries(context.Background(), overlapping, overlapping.Occurrences())
+	assert.True(t, errors.As(err, &SlotTakenError{}))
+
+	booked, err := r.GetBookedTimeSlots(context.Background(), 1, time.Date(2022, 03, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 05, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, booked, 3)
+
+	unknownUser := weeklySeries(1, 100, time.Date(2022, 05, 5, 19, 0, 0, 0, time.UTC))
+	_, err = r.CreateAppointmentSeries(context.Background(), unknownUser, unknownUser.Occurrences())
+	assert.Equal(t, ErrUnknownUser, errors.Cause(err))
+}
+
+func TestAppointmentRepository_CancelFollowingAppointments(t *testing.T) {
+	PurgeTables()
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	series := weeklySeries(1, 1, time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC))
+	created, err := r.CreateAppointmentSeries(context.Background(), series, series.Occurrences())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.CancelFollowingAppointments(context.Background(), created.Appointments[1].ID, created.Appointments[1].Version+1)
+	assert.Equal(t, ErrStaleVersion, errors.Cause(err))
+
+	canceled, err := r.CancelFollowingAppointments(context.Background(), created.Appointments[1].ID, created.Appointments[1].Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, canceled, 2)
+	assert.Equal(t, created.Appointments[1].ID, canceled[0].ID)
+	assert.Equal(t, created.Appointments[2].ID, canceled[1].ID)
+
+	got, err := r.GetAppointmentSeries(context.Background(), created.Series.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, got.Appointments[0].CanceledAt)
+	assert.NotNil(t, got.Appointments[1].CanceledAt)
+	assert.NotNil(t, got.Appointments[2].CanceledAt)
+
+	_, err = r.CancelFollowingAppointments(context.Background(), created.Appointments[2].ID, created.Appointments[2].Version)
+	assert.Equal(t, ErrAlreadyCanceled, errors.Cause(err))
+
+	oneOff, err := r.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+		TrainerID: 2,
+		UserID:    1,
+		StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.CancelFollowingAppointments(context.Background(), oneOff.ID, oneOff.Version)
+	assert.Equal(t, ErrNotInSeries, errors.Cause(err))
+}
@@ -2,17 +2,29 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
 	"github.com/samuelmahr/appt-scheduling/internal/models"
 	"time"
 )
 
 type AppointmentsRepository interface {
 	CreateAppointment(ctx context.Context, newUser models.AppointmentCreateRequest) (models.Appointment, error)
-	GetScheduledAppointments(ctx context.Context, tID int64, startsAt time.Time, endsAt time.Time) ([]models.Appointment, error)
-	GetScheduledAppointmentsAsTimeSlots(ctx context.Context, tID int64, startsAt time.Time, endsAt time.Time) (map[int64]int64, error)
+	CreateAppointments(ctx context.Context, newAppts []models.AppointmentCreateRequest) ([]models.Appointment, error)
+	GetScheduledAppointments(ctx context.Context, filter models.AppointmentsFilter) ([]models.Appointment, error)
+	GetBookedTimeSlots(ctx context.Context, tID int64, startsAt time.Time, endsAt time.Time) ([]models.TimeSlot, error)
+	CancelAppointment(ctx context.Context, id int64, version int64) (models.Appointment, error)
+	RescheduleAppointment(ctx context.Context, id int64, reschedule models.AppointmentRescheduleRequest, version int64) (models.Appointment, error)
+	GetAppointment(ctx context.Context, id int64) (models.Appointment, error)
+	CreateAppointmentSeries(ctx context.Context, series models.AppointmentSeriesCreateRequest, occurrences []models.TimeSlot) (models.AppointmentSeriesResult, error)
+	GetAppointmentSeries(ctx context.Context, id int64) (models.AppointmentSeriesResult, error)
+	CancelFollowingAppointments(ctx context.Context, id int64, version int64) ([]models.Appointment, error)
+	ClaimIdempotencyKey(ctx context.Context, endpoint string, key string, requestHash string) (models.IdempotencyKey, error)
+	SaveIdempotentResponse(ctx context.Context, endpoint string, key string, status int, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, endpoint string, key string) error
 }
 
 type AppointmentsRepoType struct {
@@ -26,24 +38,262 @@ func NewAppointmentsRepository(db *sqlx.DB) AppointmentsRepoType {
 }
 
 const createAppointmentQuery = `
-insert into scheduling.appointments(trainer_id, user_id, starts_at, ends_at)
-VALUES ($1, $2, $3, $4)
-returning id, trainer_id, user_id, starts_at, ends_at, created_at, updated_at, canceled_at
+insert into scheduling.appointments(trainer_id, user_id, starts_at, ends_at, session_type_id, series_id)
+VALUES ($1, $2, $3, $4, nullif($5, 0), nullif($6, 0))
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, series_id, created_at, updated_at, canceled_at, version
 `
 
 func (ar *AppointmentsRepoType) CreateAppointment(ctx context.Context, newAppt models.AppointmentCreateRequest) (models.Appointment, error) {
+	tx, err := ar.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error creating appointment")
+	}
+	defer tx.Rollback()
+
+	a, err := createAppointment(ctx, tx, newAppt, 0)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error creating appointment")
+	}
+
+	return a, nil
+}
+
+// CreateAppointments books every appointment in a single transaction, so either all of them are booked or none are
+// the error for the first appointment that can't be booked is returned as a BatchItemError with its index
+func (ar *AppointmentsRepoType) CreateAppointments(ctx context.Context, newAppts []models.AppointmentCreateRequest) ([]models.Appointment, error) {
+	tx, err := ar.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return []models.Appointment{}, errors.Wrap(err, "error creating appointments")
+	}
+	defer tx.Rollback()
+
+	appts := make([]models.Appointment, 0, len(newAppts))
+	for i, newAppt := range newAppts {
+		a, err := createAppointment(ctx, tx, newAppt, 0)
+		if err != nil {
+			return []models.Appointment{}, BatchItemError{Index: i, Err: err}
+		}
+
+		appts = append(appts, a)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []models.Appointment{}, errors.Wrap(err, "error creating appointments")
+	}
+
+	return appts, nil
+}
+
+// createAppointment inserts the appointment and its created event in the transaction, mapping constraint violations to typed errors
+// another user's hold on the time slot is a SlotTakenError, a series ID of 0 is a one-off appointment
+func createAppointment(ctx context.Context, tx *sqlx.Tx, newAppt models.AppointmentCreateRequest, seriesID int64) (models.Appointment, error) {
+	err := checkHeld(ctx, tx, newAppt.TrainerID, newAppt.UserID, newAppt.StartsAt, newAppt.EndsAt)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
 	var a models.Appointment
-	err := ar.db.QueryRowx(createAppointmentQuery, newAppt.TrainerID, newAppt.UserID, newAppt.StartsAt, newAppt.EndsAt).StructScan(&a)
+	err = tx.QueryRowxContext(ctx, createAppointmentQuery, newAppt.TrainerID, newAppt.UserID, newAppt.StartsAt, newAppt.EndsAt, newAppt.SessionTypeID, seriesID).StructScan(&a)
+
+	if isSlotTaken(err) {
+		return models.Appointment{}, SlotTakenError{TrainerID: newAppt.TrainerID, StartsAt: newAppt.StartsAt, EndsAt: newAppt.EndsAt}
+	}
+
+	if isConstraintViolation(err, foreignKeyViolation, appointmentsTrainerFKey) {
+		return models.Appointment{}, ErrUnknownTrainer
+	}
+
+	if isConstraintViolation(err, foreignKeyViolation, appointmentsUserFKey) {
+		return models.Appointment{}, ErrUnknownUser
+	}
 
 	if err != nil {
 		return models.Appointment{}, errors.Wrap(err, "error creating appointment")
 	}
 
+	err = addEvent(ctx, tx, events.NewAppointmentCreatedEvent(a))
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	return a, nil
+}
+
+const getAppointmentQuery = `
+select id, trainer_id, user_id, starts_at, ends_at, session_type_id, series_id, created_at, updated_at, canceled_at, version
+from scheduling.appointments
+where id = $1
+`
+
+// GetAppointment gets a single appointment, canceled or not. returns sql.ErrNoRows when it doesn't exist
+func (ar *AppointmentsRepoType) GetAppointment(ctx context.Context, id int64) (models.Appointment, error) {
+	var a models.Appointment
+	err := ar.db.QueryRowxContext(ctx, getAppointmentQuery, id).StructScan(&a)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error getting appointment")
+	}
+
+	return a, nil
+}
+
+const cancelAppointmentQuery = `
+update scheduling.appointments
+set canceled_at = now(), updated_at = now(), version = version + 1
+where id = $1 and canceled_at is null and version = $2
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, series_id, created_at, updated_at, canceled_at, version
+`
+
+const getAppointmentCanceledAtQuery = `
+select canceled_at, version from scheduling.appointments where id = $1
+`
+
+// CancelAppointment soft cancels an appointment by setting canceled_at, which frees up the time slot
+// returns ErrStaleVersion when the appointment changed since the client got version
+func (ar *AppointmentsRepoType) CancelAppointment(ctx context.Context, id int64, version int64) (models.Appointment, error) {
+	tx, err := ar.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error canceling appointment")
+	}
+	defer tx.Rollback()
+
+	var a models.Appointment
+	err = tx.QueryRowxContext(ctx, cancelAppointmentQuery, id, version).StructScan(&a)
+	if err == nil {
+		err = addEvent(ctx, tx, events.NewAppointmentCanceledEvent(a))
+		if err != nil {
+			return models.Appointment{}, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return models.Appointment{}, errors.Wrap(err, "error canceling appointment")
+		}
+
+		return a, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return models.Appointment{}, errors.Wrap(err, "error canceling appointment")
+	}
+
+	// nothing updated, either the appointment doesn't exist, was already canceled or changed since version
+	var canceledAt *time.Time
+	var current int64
+	err = tx.QueryRowxContext(ctx, getAppointmentCanceledAtQuery, id).Scan(&canceledAt, &current)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error canceling appointment")
+	}
+
+	if canceledAt != nil {
+		return models.Appointment{}, ErrAlreadyCanceled
+	}
+
+	return models.Appointment{}, ErrStaleVersion
+}
+
+const getAppointmentForUpdateQuery = `
+select id, trainer_id, user_id, starts_at, ends_at, session_type_id, series_id, created_at, updated_at, canceled_at, version
+from scheduling.appointments
+where id = $1
+for update
+`
+
+const trainerSlotTakenQuery = `
+select exists(
+    select 1 from scheduling.appointments
+    where trainer_id = $1 and starts_at < $3 and ends_at > $2 and canceled_at is null and id <> $4
+)
+`
+
+const rescheduleAppointmentQuery = `
+update scheduling.appointments
+set trainer_id = $2, starts_at = $3, ends_at = $4, updated_at = now(), version = version + 1
+where id = $1 and version = $5
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, series_id, created_at, updated_at, canceled_at, version
+`
+
+// RescheduleAppointment moves an appointment to a new time slot (and optionally trainer) in a single transaction,
+// so the original booking is kept if the new time slot is already taken
+// returns ErrStaleVersion when the appointment changed since the client got version
+func (ar *AppointmentsRepoType) RescheduleAppointment(ctx context.Context, id int64, reschedule models.AppointmentRescheduleRequest, version int64) (models.Appointment, error) {
+	tx, err := ar.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error rescheduling appointment")
+	}
+	defer tx.Rollback()
+
+	var current models.Appointment
+	err = tx.QueryRowxContext(ctx, getAppointmentForUpdateQuery, id).StructScan(&current)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error rescheduling appointment")
+	}
+
+	if current.CanceledAt != nil {
+		return models.Appointment{}, ErrAlreadyCanceled
+	}
+
+	if current.Version != version {
+		return models.Appointment{}, ErrStaleVersion
+	}
+
+	trainerID := reschedule.TrainerID
+	if trainerID == 0 {
+		trainerID = current.TrainerID
+	}
+
+	err = checkHeld(ctx, tx, trainerID, current.UserID, reschedule.StartsAt, reschedule.EndsAt)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	var taken bool
+	err = tx.QueryRowxContext(ctx, trainerSlotTakenQuery, trainerID, reschedule.StartsAt, reschedule.EndsAt, id).Scan(&taken)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error rescheduling appointment")
+	}
+
+	slotTaken := SlotTakenError{TrainerID: trainerID, StartsAt: reschedule.StartsAt, EndsAt: reschedule.EndsAt}
+	if taken {
+		return models.Appointment{}, slotTaken
+	}
+
+	var a models.Appointment
+	err = tx.QueryRowxContext(ctx, rescheduleAppointmentQuery, id, trainerID, reschedule.StartsAt, reschedule.EndsAt, version).StructScan(&a)
+	if err == sql.ErrNoRows {
+		// the version is checked with the row locked, this only guards the update itself
+		return models.Appointment{}, ErrStaleVersion
+	}
+
+	if isSlotTaken(err) {
+		// booked by someone else since checking
+		return models.Appointment{}, slotTaken
+	}
+
+	if isConstraintViolation(err, foreignKeyViolation, appointmentsTrainerFKey) {
+		return models.Appointment{}, ErrUnknownTrainer
+	}
+
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error rescheduling appointment")
+	}
+
+	err = addEvent(ctx, tx, events.NewAppointmentRescheduledEvent(a, current))
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error rescheduling appointment")
+	}
+
 	return a, nil
 }
 
-func (ar *AppointmentsRepoType) GetScheduledAppointments(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time) ([]models.Appointment, error) {
-	sql, args, err := buildGetScheduledApptsQuery(trainerID, startsAt, endsAt)
+func (ar *AppointmentsRepoType) GetScheduledAppointments(ctx context.Context, filter models.AppointmentsFilter) ([]models.Appointment, error) {
+	sql, args, err := buildGetScheduledApptsQuery(filter)
 	if err != nil {
 		return []models.Appointment{}, errors.Wrap(err, "error getting appointments")
 	}
@@ -52,6 +302,7 @@ func (ar *AppointmentsRepoType) GetScheduledAppointments(ctx context.Context, tr
 	if err != nil {
 		return []models.Appointment{}, errors.Wrap(err, "error getting appointments")
 	}
+	defer rows.Close()
 
 	appts := make([]models.Appointment, 0)
 	for rows.Next() {
@@ -66,41 +317,106 @@ func (ar *AppointmentsRepoType) GetScheduledAppointments(ctx context.Context, tr
 	return appts, nil
 }
 
-func (ar *AppointmentsRepoType) GetScheduledAppointmentsAsTimeSlots(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time) (map[int64]int64, error) {
-	sql, args, err := buildGetScheduledApptsQuery(trainerID, startsAt, endsAt)
+// GetBookedTimeSlots gets the time slots of active appointments and holds overlapping the time range, including ones that
+// only partially overlap it, so availability can be checked by overlap instead of matching start times
+// a trainer ID of 0 gets the time slots booked with every trainer
+func (ar *AppointmentsRepoType) GetBookedTimeSlots(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time) ([]models.TimeSlot, error) {
+	sql, args, err := buildGetBookedTimeSlotsQuery(trainerID, startsAt, endsAt)
 	if err != nil {
-		return map[int64]int64{}, errors.Wrap(err, "error getting appointments")
+		return []models.TimeSlot{}, errors.Wrap(err, "error getting booked time slots")
 	}
 
-	rows, err := ar.db.Queryx(sql, args...)
+	slots := make([]models.TimeSlot, 0)
+	err = ar.db.SelectContext(ctx, &slots, sql, args...)
 	if err != nil {
-		return map[int64]int64{}, errors.Wrap(err, "error getting appointments")
+		return []models.TimeSlot{}, errors.Wrap(err, "error getting booked time slots")
 	}
 
-	startToEndUnix := make(map[int64]int64)
-	for rows.Next() {
-		var a models.Appointment
-		if err := rows.StructScan(&a); err != nil {
-			return map[int64]int64{}, errors.Wrap(err, "error getting appointments")
-		}
+	return slots, nil
+}
 
-		startToEndUnix[a.StartsAt.Unix()] = a.EndsAt.Unix()
+// buildGetBookedTimeSlotsQuery unions the appointments and holds overlapping the time range
+// squirrel doesn't support unions, so each select is built with ? placeholders and they're numbered after joining them
+func buildGetBookedTimeSlotsQuery(trainerID int64, startsAt time.Time, endsAt time.Time) (string, []interface{}, error) {
+	appts := sq.Select("trainer_id", "starts_at", "ends_at").From("scheduling.appointments").
+		Where(sq.And{sq.Lt{"starts_at": endsAt}, sq.Gt{"ends_at": startsAt}}).
+		Where(sq.Eq{"canceled_at": nil}) // canceled appointments don't block a time slot
+
+	holds := sq.Select("trainer_id", "starts_at", "ends_at").From("scheduling.holds").
+		Where(sq.And{sq.Lt{"starts_at": endsAt}, sq.Gt{"ends_at": startsAt}}).
+		Where(openHoldCondition)
+
+	if trainerID != 0 {
+		appts = appts.Where(sq.Eq{"trainer_id": trainerID})
+		holds = holds.Where(sq.Eq{"trainer_id": trainerID})
+	}
+
+	apptsSQL, apptsArgs, err := appts.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+
+	holdsSQL, holdsArgs, err := holds.ToSql()
+	if err != nil {
+		return "", nil, err
 	}
 
-	return startToEndUnix, nil
+	sql, err := sq.Dollar.ReplacePlaceholders(apptsSQL + " union all " + holdsSQL + " order by starts_at")
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sql, append(apptsArgs, holdsArgs...), nil
 }
 
-func buildGetScheduledApptsQuery(trainerID int64, startsAt time.Time, endsAt time.Time) (string, []interface{}, error) {
-	query := sq.Select("id", "trainer_id", "user_id", "starts_at", "ends_at", "created_at", "updated_at", "canceled_at").From("scheduling.appointments")
-	if trainerID != 0 {
+func buildGetScheduledApptsQuery(filter models.AppointmentsFilter) (string, []interface{}, error) {
+	query := sq.Select("id", "trainer_id", "user_id", "starts_at", "ends_at", "session_type_id", "series_id", "created_at", "updated_at", "canceled_at", "version").From("scheduling.appointments")
+	if filter.TrainerID != 0 {
 		// find for trainer ID
-		query = query.Where(sq.Eq{"trainer_id": trainerID})
+		query = query.Where(sq.Eq{"trainer_id": filter.TrainerID})
+	}
+
+	if filter.UserID != 0 {
+		query = query.Where(sq.Eq{"user_id": filter.UserID})
 	}
 
 	query = query.PlaceholderFormat(sq.Dollar)
-	if !startsAt.IsZero() && !endsAt.IsZero() {
+	if !filter.StartsAt.IsZero() && !filter.EndsAt.IsZero() {
 		// check between times
-		query = query.Where(sq.And{sq.GtOrEq{"starts_at": startsAt}, sq.LtOrEq{"ends_at": endsAt}})
+		query = query.Where(sq.And{sq.GtOrEq{"starts_at": filter.StartsAt}, sq.LtOrEq{"ends_at": filter.EndsAt}})
+	}
+
+	switch filter.Status {
+	case models.AppointmentStatusAll:
+		// no filter on canceled_at
+	case models.AppointmentStatusCanceled:
+		query = query.Where(sq.NotEq{"canceled_at": nil})
+	default:
+		query = query.Where(sq.Eq{"canceled_at": nil})
+	}
+
+	sort := filter.Sort
+	if sort == "" {
+		sort = models.AppointmentsSortStartsAt
+	}
+
+	if !sort.Valid() {
+		return "", []interface{}{}, errors.Errorf("unknown sort %s", sort)
+	}
+
+	// keyset pagination, the id breaks ties so appointments with the same time aren't skipped or repeated between pages
+	column, direction, comparison := sort.Column(), "asc", ">"
+	if sort.Descending() {
+		direction, comparison = "desc", "<"
+	}
+
+	if filter.Cursor != nil {
+		query = query.Where(sq.Expr("("+column+", id) "+comparison+" (?, ?)", filter.Cursor.Value, filter.Cursor.ID))
+	}
+
+	query = query.OrderBy(column+" "+direction, "id "+direction)
+	if filter.Limit > 0 {
+		query = query.Limit(uint64(filter.Limit))
 	}
 
 	sql, args, err := query.ToSql()
@@ -11,21 +11,88 @@ type MockAppointments struct {
 	CreateAppointmentsResponse models.Appointment
 	CreateAppointmentsErr      error
 
+	CreateAppointmentsBatchResponse []models.Appointment
+	CreateAppointmentsBatchErr      error
+
 	GetScheduledAppointmentsResponse []models.Appointment
 	GetScheduledAppointmentsErr      error
 
-	GetScheduledAppointmentsAsTimeSlotsResponse map[int64]int64
-	GetScheduledAppointmentsAsTimeSlotsErr      error
+	GetBookedTimeSlotsResponse []models.TimeSlot
+	GetBookedTimeSlotsErr      error
+
+	CancelAppointmentResponse models.Appointment
+	CancelAppointmentErr      error
+
+	RescheduleAppointmentResponse models.Appointment
+	RescheduleAppointmentErr      error
+
+	GetAppointmentResponse models.Appointment
+	GetAppointmentErr      error
+
+	CreateAppointmentSeriesResponse models.AppointmentSeriesResult
+	CreateAppointmentSeriesErr      error
+
+	GetAppointmentSeriesResponse models.AppointmentSeriesResult
+	GetAppointmentSeriesErr      error
+
+	CancelFollowingAppointmentsResponse []models.Appointment
+	CancelFollowingAppointmentsErr      error
+
+	ClaimIdempotencyKeyResponse models.IdempotencyKey
+	ClaimIdempotencyKeyErr      error
+
+	SaveIdempotentResponseErr error
+	ReleaseIdempotencyKeyErr  error
 }
 
 func (m *MockAppointments) CreateAppointment(ctx context.Context, newUser models.AppointmentCreateRequest) (models.Appointment, error) {
 	return m.CreateAppointmentsResponse, m.CreateAppointmentsErr
 }
 
-func (m *MockAppointments) GetScheduledAppointments(ctx context.Context, tID int64, startsAt time.Time, endsAt time.Time) ([]models.Appointment, error) {
+func (m *MockAppointments) CreateAppointments(ctx context.Context, newAppts []models.AppointmentCreateRequest) ([]models.Appointment, error) {
+	return m.CreateAppointmentsBatchResponse, m.CreateAppointmentsBatchErr
+}
+
+func (m *MockAppointments) GetScheduledAppointments(ctx context.Context, filter models.AppointmentsFilter) ([]models.Appointment, error) {
 	return m.GetScheduledAppointmentsResponse, m.GetScheduledAppointmentsErr
 }
 
-func (m *MockAppointments) GetScheduledAppointmentsAsTimeSlots(ctx context.Context, tID int64, startsAt time.Time, endsAt time.Time) (map[int64]int64, error) {
-	return m.GetScheduledAppointmentsAsTimeSlotsResponse, m.GetScheduledAppointmentsAsTimeSlotsErr
+func (m *MockAppointments) GetBookedTimeSlots(ctx context.Context, tID int64, startsAt time.Time, endsAt time.Time) ([]models.TimeSlot, error) {
+	return m.GetBookedTimeSlotsResponse, m.GetBookedTimeSlotsErr
+}
+
+func (m *MockAppointments) CancelAppointment(ctx context.Context, id int64, version int64) (models.Appointment, error) {
+	return m.CancelAppointmentResponse, m.CancelAppointmentErr
+}
+
+func (m *MockAppointments) RescheduleAppointment(ctx context.Context, id int64, reschedule models.AppointmentRescheduleRequest, version int64) (models.Appointment, error) {
+	return m.RescheduleAppointmentResponse, m.RescheduleAppointmentErr
+}
+
+func (m *MockAppointments) GetAppointment(ctx context.Context, id int64) (models.Appointment, error) {
+	return m.GetAppointmentResponse, m.GetAppointmentErr
+}
+
+func (m *MockAppointments) CreateAppointmentSeries(ctx context.Context, series models.AppointmentSeriesCreateRequest, occurrences []models.TimeSlot) (models.AppointmentSeriesResult, error) {
+	return m.CreateAppointmentSeriesResponse, m.CreateAppointmentSeriesErr
+}
+
+func (m *MockAppointments) GetAppointmentSeries(ctx context.Context, id int64) (models.AppointmentSeriesResult, error) {
+	return m.GetAppointmentSeriesResponse, m.GetAppointmentSeriesErr
+}
+
+func (m *MockAppointments) CancelFollowingAppointments(ctx context.Context, id int64, version int64) ([]models.Appointment, error) {
+	return m.CancelFollowingAppointmentsResponse, m.CancelFollowingAppointmentsErr
+}
+
+func (m *MockAppointments) ClaimIdempotencyKey(ctx context.Context, endpoint string, key string, requestHash string) (models.IdempotencyKey, error) {
+	return m.ClaimIdempotencyKeyResponse, m.ClaimIdempotencyKeyErr
+}
+
+func (m *MockAppointments) SaveIdempotentResponse(ctx context.Context, endpoint string, key string, status int, body []byte) error {
+	return m.SaveIdempotentResponseErr
+}
+
+func (m *MockAppointments) ReleaseIdempotencyKey(ctx context.Context, endpoint string, key string) error {
+	return m.ReleaseIdempotencyKeyErr
 }
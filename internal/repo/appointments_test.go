@@ -2,7 +2,9 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"github.com/pkg/errors"
 	"github.com/samuelmahr/appt-scheduling/internal/models"
 	"os"
 	"testing"
@@ -87,6 +89,69 @@ func TestAppointmentRepository_CreateAppointment(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "error overlapping time slot",
+			want: models.Appointment{
+				ID:        1,
+				TrainerID: 1,
+				UserID:    1,
+				StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC),
+			},
+			wantErr: true,
+			args: args{
+				appointments: []appt{
+					{
+						createRequest: models.AppointmentCreateRequest{
+							TrainerID: 1,
+							UserID:    1,
+							StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC),
+						},
+						wantAssert: true,
+					},
+					{
+						createRequest: models.AppointmentCreateRequest{
+							TrainerID: 1,
+							UserID:    2,
+							StartsAt:  time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "back to back time slots don't overlap",
+			want: models.Appointment{
+				ID:        2,
+				TrainerID: 1,
+				UserID:    2,
+				StartsAt:  time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 13, 45, 0, 0, time.UTC),
+			},
+			args: args{
+				appointments: []appt{
+					{
+						createRequest: models.AppointmentCreateRequest{
+							TrainerID: 1,
+							UserID:    1,
+							StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC),
+						},
+					},
+					{
+						createRequest: models.AppointmentCreateRequest{
+							TrainerID: 1,
+							UserID:    2,
+							StartsAt:  time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC),
+							EndsAt:    time.Date(2022, 03, 17, 13, 45, 0, 0, time.UTC),
+						},
+						wantAssert: true,
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -100,7 +165,11 @@ func TestAppointmentRepository_CreateAppointment(t *testing.T) {
 				got, err := r.CreateAppointment(context.Background(), appt.createRequest)
 				fmt.Printf("%#v", got)
 				if err != nil && tt.wantErr {
-					// I'd really prefer to assert the error otherwise we could have false positive tests
+					assert.Equal(t, SlotTakenError{
+						TrainerID: appt.createRequest.TrainerID,
+						StartsAt:  appt.createRequest.StartsAt,
+						EndsAt:    appt.createRequest.EndsAt,
+					}, err)
 					return
 				} else if err != nil {
 					t.Fatal(err)
@@ -176,7 +245,11 @@ func TestAppointmentRepository_GetScheduledAppointments(t *testing.T) {
 				}
 			}
 
-			got, err := r.GetScheduledAppointments(context.Background(), tt.args.TrainerID, tt.args.StartsAt, tt.args.EndsAt)
+			got, err := r.GetScheduledAppointments(context.Background(), models.AppointmentsFilter{
+				TrainerID: tt.args.TrainerID,
+				StartsAt:  tt.args.StartsAt,
+				EndsAt:    tt.args.EndsAt,
+			})
 			if err != nil && tt.wantErr {
 				// I'd really prefer to assert the error otherwise we could have false positive tests
 				return
@@ -196,3 +269,773 @@ func TestAppointmentRepository_GetScheduledAppointments(t *testing.T) {
 		})
 	}
 }
+
+func TestAppointmentRepository_GetScheduledAppointments_Status(t *testing.T) {
+	appointments := []models.AppointmentCreateRequest{
+		{
+			TrainerID: 1,
+			UserID:    1,
+			StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			TrainerID: 1,
+			UserID:    2,
+			StartsAt:  time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 13, 30, 0, 0, time.UTC),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		status  models.AppointmentStatus
+		wantIDs []int64
+	}{
+		{
+			name:    "default is active",
+			status:  "",
+			wantIDs: []int64{2},
+		},
+		{
+			name:    "active",
+			status:  models.AppointmentStatusActive,
+			wantIDs: []int64{2},
+		},
+		{
+			name:    "canceled",
+			status:  models.AppointmentStatusCanceled,
+			wantIDs: []int64{1},
+		},
+		{
+			name:    "all",
+			status:  models.AppointmentStatusAll,
+			wantIDs: []int64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PurgeTables()
+
+			r := &AppointmentsRepoType{
+				db: DB,
+			}
+
+			for _, appt := range appointments {
+				_, err := r.CreateAppointment(context.Background(), appt)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := r.CancelAppointment(context.Background(), 1, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := r.GetScheduledAppointments(context.Background(), models.AppointmentsFilter{
+				TrainerID: 1,
+				Status:    tt.status,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotIDs := make([]int64, 0)
+			for _, a := range got {
+				gotIDs = append(gotIDs, a.ID)
+			}
+
+			assert.ElementsMatch(t, tt.wantIDs, gotIDs)
+		})
+	}
+}
+
+func TestAppointmentRepository_GetAppointment(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int64
+		want    models.Appointment
+		wantErr error
+	}{
+		{
+			name: "happy path",
+			id:   1,
+			want: models.Appointment{
+				ID:        1,
+				TrainerID: 1,
+				UserID:    1,
+				StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "error not found",
+			id:      42,
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PurgeTables()
+
+			r := &AppointmentsRepoType{
+				db: DB,
+			}
+
+			_, err := r.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+				TrainerID: 1,
+				UserID:    1,
+				StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := r.GetAppointment(context.Background(), tt.id)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, errors.Cause(err))
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.want.ID, got.ID)
+			assert.Equal(t, tt.want.TrainerID, got.TrainerID)
+			assert.Equal(t, tt.want.UserID, got.UserID)
+			assert.Equal(t, tt.want.StartsAt, got.StartsAt)
+			assert.Equal(t, tt.want.EndsAt, got.EndsAt)
+		})
+	}
+}
+
+func TestAppointmentRepository_CancelAppointment(t *testing.T) {
+	type fields struct {
+		appointments []models.AppointmentCreateRequest
+		canceledIDs  []int64
+	}
+
+	tests := []struct {
+		name      string
+		id        int64
+		fields    fields
+		wantErr   error
+		wantSlots int
+	}{
+		{
+			name: "happy path",
+			id:   1,
+			fields: fields{
+				appointments: []models.AppointmentCreateRequest{
+					{
+						TrainerID: 1,
+						UserID:    1,
+						StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+		{
+			name: "error already canceled",
+			id:   1,
+			fields: fields{
+				appointments: []models.AppointmentCreateRequest{
+					{
+						TrainerID: 1,
+						UserID:    1,
+						StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+						EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+					},
+				},
+				canceledIDs: []int64{1},
+			},
+			wantErr: ErrAlreadyCanceled,
+		},
+		{
+			name:    "error not found",
+			id:      42,
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PurgeTables()
+
+			r := &AppointmentsRepoType{
+				db: DB,
+			}
+
+			for _, appt := range tt.fields.appointments {
+				_, err := r.CreateAppointment(context.Background(), appt)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, id := range tt.fields.canceledIDs {
+				_, err := r.CancelAppointment(context.Background(), id, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := r.CancelAppointment(context.Background(), tt.id, 1)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, errors.Cause(err))
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.id, got.ID)
+			assert.NotNil(t, got.CanceledAt)
+
+			// the canceled appointment should no longer block its time slot
+			slots, err := r.GetBookedTimeSlots(context.Background(), 1, time.Date(2022, 03, 17, 0, 0, 0, 0, time.UTC), time.Date(2022, 03, 18, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, slots, tt.wantSlots)
+		})
+	}
+}
+
+func TestAppointmentRepository_RescheduleAppointment(t *testing.T) {
+	original := models.AppointmentCreateRequest{
+		TrainerID: 1,
+		UserID:    1,
+		StartsAt:  time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name       string
+		id         int64
+		reschedule models.AppointmentRescheduleRequest
+		existing   []models.AppointmentCreateRequest
+		cancel     bool
+		want       models.Appointment
+		wantErr    error
+	}{
+		{
+			name: "happy path",
+			id:   1,
+			reschedule: models.AppointmentRescheduleRequest{
+				StartsAt: time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+				EndsAt:   time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+			},
+			want: models.Appointment{
+				ID:        1,
+				TrainerID: 1,
+				UserID:    1,
+				StartsAt:  time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "happy path new trainer",
+			id:   1,
+			reschedule: models.AppointmentRescheduleRequest{
+				TrainerID: 2,
+				StartsAt:  time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC),
+			},
+			want: models.Appointment{
+				ID:        1,
+				TrainerID: 2,
+				UserID:    1,
+				StartsAt:  time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "error slot taken keeps original",
+			id:   1,
+			reschedule: models.AppointmentRescheduleRequest{
+				StartsAt: time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+				EndsAt:   time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+			},
+			existing: []models.AppointmentCreateRequest{
+				{
+					TrainerID: 1,
+					UserID:    2,
+					StartsAt:  time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+					EndsAt:    time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+				},
+			},
+			wantErr: SlotTakenError{
+				TrainerID: 1,
+				StartsAt:  time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "error canceled",
+			id:   1,
+			reschedule: models.AppointmentRescheduleRequest{
+				StartsAt: time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+				EndsAt:   time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+			},
+			cancel:  true,
+			wantErr: ErrAlreadyCanceled,
+		},
+		{
+			name: "error not found",
+			id:   42,
+			reschedule: models.AppointmentRescheduleRequest{
+				StartsAt: time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+				EndsAt:   time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+			},
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PurgeTables()
+
+			r := &AppointmentsRepoType{
+				db: DB,
+			}
+
+			for _, appt := range append([]models.AppointmentCreateRequest{original}, tt.existing...) {
+				_, err := r.CreateAppointment(context.Background(), appt)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.cancel {
+				_, err := r.CancelAppointment(context.Background(), 1, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := r.RescheduleAppointment(context.Background(), tt.id, tt.reschedule, 1)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, errors.Cause(err))
+
+				// the original booking is untouched
+				appts, err := r.GetScheduledAppointments(context.Background(), models.AppointmentsFilter{TrainerID: 1, Status: models.AppointmentStatusAll})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, a := range appts {
+					if a.ID == 1 {
+						assert.Equal(t, original.StartsAt, a.StartsAt)
+						assert.Equal(t, original.EndsAt, a.EndsAt)
+					}
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.want.ID, got.ID)
+			assert.Equal(t, tt.want.TrainerID, got.TrainerID)
+			assert.Equal(t, tt.want.UserID, got.UserID)
+			assert.Equal(t, tt.want.StartsAt, got.StartsAt)
+			assert.Equal(t, tt.want.EndsAt, got.EndsAt)
+			assert.True(t, got.UpdatedAt.After(got.CreatedAt) || got.UpdatedAt.Equal(got.CreatedAt))
+		})
+	}
+}
+
+func TestAppointmentRepository_StaleVersion(t *testing.T) {
+	PurgeTables()
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	created, err := r.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+		TrainerID: 1,
+		UserID:    1,
+		StartsAt:  time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(1), created.Version)
+
+	rescheduled, err := r.RescheduleAppointment(context.Background(), created.ID, models.AppointmentRescheduleRequest{
+		StartsAt: time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+	}, created.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(2), rescheduled.Version)
+
+	// writes based on the first read lose
+	_, err = r.RescheduleAppointment(context.Background(), created.ID, models.AppointmentRescheduleRequest{
+		StartsAt: time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+	}, created.Version)
+	assert.Equal(t, ErrStaleVersion, errors.Cause(err))
+
+	_, err = r.CancelAppointment(context.Background(), created.ID, created.Version)
+	assert.Equal(t, ErrStaleVersion, errors.Cause(err))
+
+	canceled, err := r.CancelAppointment(context.Background(), created.ID, rescheduled.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(3), canceled.Version)
+	assert.Equal(t, rescheduled.StartsAt, canceled.StartsAt)
+}
+
+func TestAppointmentRepository_GetBookedTimeSlots(t *testing.T) {
+	appointments := []models.AppointmentCreateRequest{
+		{
+			TrainerID: 1,
+			UserID:    1,
+			StartsAt:  time.Date(2022, 03, 17, 16, 30, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC),
+		},
+		{
+			TrainerID: 1,
+			UserID:    2,
+			StartsAt:  time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 18, 45, 0, 0, time.UTC),
+		},
+		{
+			TrainerID: 2,
+			UserID:    3,
+			StartsAt:  time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		trainerID int64
+		startsAt  time.Time
+		endsAt    time.Time
+		want      []models.TimeSlot
+	}{
+		{
+			name:      "includes appointments partially in the range",
+			trainerID: 1,
+			startsAt:  time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC),
+			endsAt:    time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+			want: []models.TimeSlot{
+				{TrainerID: 1, StartsAt: time.Date(2022, 03, 17, 16, 30, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC)},
+				{TrainerID: 1, StartsAt: time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 18, 45, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:      "touching the range isn't overlapping",
+			trainerID: 1,
+			startsAt:  time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC),
+			endsAt:    time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC),
+			want:      []models.TimeSlot{},
+		},
+		{
+			name:      "only the trainer's appointments",
+			trainerID: 2,
+			startsAt:  time.Date(2022, 03, 17, 0, 0, 0, 0, time.UTC),
+			endsAt:    time.Date(2022, 03, 18, 0, 0, 0, 0, time.UTC),
+			want: []models.TimeSlot{
+				{TrainerID: 2, StartsAt: time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:      "every trainer's appointments",
+			trainerID: 0,
+			startsAt:  time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC),
+			endsAt:    time.Date(2022, 03, 17, 18, 30, 0, 0, time.UTC),
+			want: []models.TimeSlot{
+				{TrainerID: 1, StartsAt: time.Date(2022, 03, 17, 16, 30, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC)},
+				{TrainerID: 2, StartsAt: time.Date(2022, 03, 17, 17, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 17, 30, 0, 0, time.UTC)},
+				{TrainerID: 1, StartsAt: time.Date(2022, 03, 17, 18, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 18, 45, 0, 0, time.UTC)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PurgeTables()
+
+			r := &AppointmentsRepoType{
+				db: DB,
+			}
+
+			for _, appt := range appointments {
+				_, err := r.CreateAppointment(context.Background(), appt)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := r.GetBookedTimeSlots(context.Background(), tt.trainerID, tt.startsAt, tt.endsAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range got {
+				got[i].StartsAt = got[i].StartsAt.UTC()
+				got[i].EndsAt = got[i].EndsAt.UTC()
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAppointmentRepository_GetScheduledAppointments_Pagination(t *testing.T) {
+	PurgeTables()
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	// two appointments at the same time for different trainers, so the id has to break the tie
+	appointments := []models.AppointmentCreateRequest{
+		{TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 13, 30, 0, 0, time.UTC)},
+		{TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC)},
+		{TrainerID: 2, UserID: 2, StartsAt: time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC)},
+		{TrainerID: 1, UserID: 3, StartsAt: time.Date(2022, 03, 17, 14, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 14, 30, 0, 0, time.UTC)},
+	}
+	for _, appt := range appointments {
+		_, err := r.CreateAppointment(context.Background(), appt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sort    models.AppointmentsSort
+		wantIDs []int64
+	}{
+		{
+			name:    "starts_at",
+			sort:    models.AppointmentsSortStartsAt,
+			wantIDs: []int64{2, 3, 1, 4},
+		},
+		{
+			name:    "starts_at descending",
+			sort:    models.AppointmentsSortStartsAtDesc,
+			wantIDs: []int64{4, 1, 3, 2},
+		},
+		{
+			name:    "created_at",
+			sort:    models.AppointmentsSortCreatedAt,
+			wantIDs: []int64{1, 2, 3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotIDs := make([]int64, 0)
+			var cursor *models.AppointmentsCursor
+			for pages := 0; pages < len(appointments); pages++ {
+				got, err := r.GetScheduledAppointments(context.Background(), models.AppointmentsFilter{
+					Sort:   tt.sort,
+					Limit:  3,
+					Cursor: cursor,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// page size of 2, the extra appointment is only used to know there's another page
+				more := len(got) > 2
+				if more {
+					got = got[:2]
+				}
+
+				for _, a := range got {
+					gotIDs = append(gotIDs, a.ID)
+				}
+
+				if !more {
+					break
+				}
+
+				next := models.NewAppointmentsCursor(tt.sort, got[len(got)-1])
+				cursor = &next
+			}
+
+			assert.Equal(t, tt.wantIDs, gotIDs)
+		})
+	}
+}
+
+func TestAppointmentRepository_GetScheduledAppointments_UserID(t *testing.T) {
+	PurgeTables()
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	appointments := []models.AppointmentCreateRequest{
+		{TrainerID: 1, UserID: 1, StartsAt: time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC)},
+		{TrainerID: 1, UserID: 2, StartsAt: time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 13, 30, 0, 0, time.UTC)},
+		{TrainerID: 2, UserID: 1, StartsAt: time.Date(2022, 03, 17, 13, 0, 0, 0, time.UTC), EndsAt: time.Date(2022, 03, 17, 13, 30, 0, 0, time.UTC)},
+	}
+	for _, appt := range appointments {
+		_, err := r.CreateAppointment(context.Background(), appt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filter  models.AppointmentsFilter
+		wantIDs []int64
+	}{
+		{
+			name:    "user across trainers",
+			filter:  models.AppointmentsFilter{UserID: 1},
+			wantIDs: []int64{1, 3},
+		},
+		{
+			name:    "user and trainer",
+			filter:  models.AppointmentsFilter{UserID: 1, TrainerID: 2},
+			wantIDs: []int64{3},
+		},
+		{
+			name:    "unknown user",
+			filter:  models.AppointmentsFilter{UserID: 100},
+			wantIDs: []int64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.GetScheduledAppointments(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotIDs := make([]int64, 0)
+			for _, a := range got {
+				gotIDs = append(gotIDs, a.ID)
+			}
+
+			assert.Equal(t, tt.wantIDs, gotIDs)
+		})
+	}
+}
+
+func TestAppointmentRepository_CreateAppointment_ForeignKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		request models.AppointmentCreateRequest
+		wantErr error
+	}{
+		{
+			name: "unknown trainer",
+			request: models.AppointmentCreateRequest{
+				TrainerID: 100,
+				UserID:    1,
+				StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+			},
+			wantErr: ErrUnknownTrainer,
+		},
+		{
+			name: "unknown user",
+			request: models.AppointmentCreateRequest{
+				TrainerID: 1,
+				UserID:    100,
+				StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+				EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+			},
+			wantErr: ErrUnknownUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PurgeTables()
+
+			r := &AppointmentsRepoType{
+				db: DB,
+			}
+
+			_, err := r.CreateAppointment(context.Background(), tt.request)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+		})
+	}
+}
+
+func TestAppointmentRepository_CreateAppointments(t *testing.T) {
+	PurgeTables()
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	created, err := r.CreateAppointments(context.Background(), []models.AppointmentCreateRequest{
+		{
+			TrainerID: 1,
+			UserID:    1,
+			StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			TrainerID: 1,
+			UserID:    2,
+			StartsAt:  time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, created, 2)
+
+	// the second appointment is for a user that doesn't exist, so none of the batch is booked
+	_, err = r.CreateAppointments(context.Background(), []models.AppointmentCreateRequest{
+		{
+			TrainerID: 2,
+			UserID:    1,
+			StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			TrainerID: 2,
+			UserID:    100,
+			StartsAt:  time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 20, 30, 0, 0, time.UTC),
+		},
+	})
+
+	itemErr := BatchItemError{}
+	assert.True(t, errors.As(err, &itemErr))
+	assert.Equal(t, 1, itemErr.Index)
+	assert.Equal(t, ErrUnknownUser, errors.Cause(err))
+
+	// the second appointment overlaps one booked in the first batch
+	_, err = r.CreateAppointments(context.Background(), []models.AppointmentCreateRequest{
+		{
+			TrainerID: 2,
+			UserID:    1,
+			StartsAt:  time.Date(2022, 03, 17, 21, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 21, 30, 0, 0, time.UTC),
+		},
+		{
+			TrainerID: 1,
+			UserID:    3,
+			StartsAt:  time.Date(2022, 03, 17, 19, 15, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 19, 45, 0, 0, time.UTC),
+		},
+	})
+
+	assert.True(t, errors.As(err, &SlotTakenError{}))
+
+	booked, err := r.GetBookedTimeSlots(context.Background(), 0, time.Date(2022, 03, 17, 0, 0, 0, 0, time.UTC), time.Date(2022, 03, 18, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, booked, 2)
+}
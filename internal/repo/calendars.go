@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+type CalendarsRepository interface {
+	SetCalendarToken(ctx context.Context, owner models.CalendarOwner, id int64, token string) error
+	GetCalendarToken(ctx context.Context, owner models.CalendarOwner, id int64) (string, error)
+}
+
+type CalendarsRepoType struct {
+	db *sqlx.DB
+}
+
+func NewCalendarsRepository(db *sqlx.DB) CalendarsRepoType {
+	return CalendarsRepoType{
+		db: db,
+	}
+}
+
+// calendar tokens are kept on the trainers and users tables, so there is a query for each owner
+var setCalendarTokenQueries = map[models.CalendarOwner]string{
+	models.CalendarOwnerTrainer: `update scheduling.trainers set calendar_token = $2 where id = $1 returning id`,
+	models.CalendarOwnerUser:    `update scheduling.users set calendar_token = $2 where id = $1 returning id`,
+}
+
+var getCalendarTokenQueries = map[models.CalendarOwner]string{
+	models.CalendarOwnerTrainer: `select coalesce(calendar_token, '') from scheduling.trainers where id = $1`,
+	models.CalendarOwnerUser:    `select coalesce(calendar_token, '') from scheduling.users where id = $1`,
+}
+
+// SetCalendarToken replaces the owner's calendar token, feed URLs with the old one stop working
+// returns sql.ErrNoRows when the trainer or user doesn't exist
+func (cr *CalendarsRepoType) SetCalendarToken(ctx context.Context, owner models.CalendarOwner, id int64, token string) error {
+	query, ok := setCalendarTokenQueries[owner]
+	if !ok {
+		return errors.Errorf("unknown calendar owner %s", owner)
+	}
+
+	var updated int64
+	err := cr.db.QueryRowxContext(ctx, query, id, token).Scan(&updated)
+	if err != nil {
+		return errors.Wrap(err, "error setting calendar token")
+	}
+
+	return nil
+}
+
+// GetCalendarToken gets the owner's calendar token, it's empty when a feed URL hasn't been created
+// returns sql.ErrNoRows when the trainer or user doesn't exist
+func (cr *CalendarsRepoType) GetCalendarToken(ctx context.Context, owner models.CalendarOwner, id int64) (string, error) {
+	query, ok := getCalendarTokenQueries[owner]
+	if !ok {
+		return "", errors.Errorf("unknown calendar owner %s", owner)
+	}
+
+	var token string
+	err := cr.db.QueryRowxContext(ctx, query, id).Scan(&token)
+	if err != nil {
+		return "", errors.Wrap(err, "error getting calendar token")
+	}
+
+	return token, nil
+}
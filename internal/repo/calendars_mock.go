@@ -0,0 +1,29 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+// MockCalendars is an implementation of CalendarsRepository to set values to use as a mock when testing
+// SetToken is the last token set
+type MockCalendars struct {
+	SetCalendarTokenErr error
+
+	GetCalendarTokenResponse string
+	GetCalendarTokenErr      error
+
+	SetToken string
+}
+
+func (m *MockCalendars) SetCalendarToken(ctx context.Context, owner models.CalendarOwner, id int64, token string) error {
+	if m.SetCalendarTokenErr == nil {
+		m.SetToken = token
+	}
+
+	return m.SetCalendarTokenErr
+}
+
+func (m *MockCalendars) GetCalendarToken(ctx context.Context, owner models.CalendarOwner, id int64) (string, error) {
+	return m.GetCalendarTokenResponse, m.GetCalendarTokenErr
+}
@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCalendarsRepository_CalendarToken(t *testing.T) {
+	PurgeTables()
+
+	cr := &CalendarsRepoType{db: DB}
+
+	for _, owner := range []models.CalendarOwner{models.CalendarOwnerTrainer, models.CalendarOwnerUser} {
+		t.Run(string(owner), func(t *testing.T) {
+			token, err := cr.GetCalendarToken(context.Background(), owner, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, "", token)
+
+			err = cr.SetCalendarToken(context.Background(), owner, 1, "first")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = cr.SetCalendarToken(context.Background(), owner, 1, "second")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err = cr.GetCalendarToken(context.Background(), owner, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, "second", token)
+
+			// the other trainer or user keeps theirs
+			token, err = cr.GetCalendarToken(context.Background(), owner, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, "", token)
+
+			err = cr.SetCalendarToken(context.Background(), owner, 42, "unknown")
+			assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+
+			_, err = cr.GetCalendarToken(context.Background(), owner, 42)
+			assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+		})
+	}
+}
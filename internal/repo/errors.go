@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	// postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+	foreignKeyViolation pq.ErrorCode = "23503"
+	uniqueViolation     pq.ErrorCode = "23505"
+	exclusionViolation  pq.ErrorCode = "23P01"
+
+	// trainerScheduledIndex is the unique index preventing a trainer from being double booked for the same time slot
+	trainerScheduledIndex = "trainer_scheduled"
+
+	// trainerNoOverlapConstraint is the exclusion constraint preventing a trainer's appointments from overlapping
+	trainerNoOverlapConstraint = "trainer_no_overlap"
+
+	// appointmentsTrainerFKey and appointmentsUserFKey are the appointments foreign keys to trainers and users
+	appointmentsTrainerFKey = "appointments_trainer_id_fkey"
+	appointmentsUserFKey    = "appointments_user_id_fkey"
+
+	// appointmentSeriesTrainerFKey and appointmentSeriesUserFKey are the appointment_series foreign keys to trainers and users
+	appointmentSeriesTrainerFKey = "appointment_series_trainer_id_fkey"
+	appointmentSeriesUserFKey    = "appointment_series_user_id_fkey"
+
+	// holdsTrainerFKey and holdsUserFKey are the holds foreign keys to trainers and users
+	holdsTrainerFKey = "holds_trainer_id_fkey"
+	holdsUserFKey    = "holds_user_id_fkey"
+
+	// waitlistTrainerFKey and waitlistUserFKey are the waitlist foreign keys to trainers and users
+	waitlistTrainerFKey = "waitlist_trainer_id_fkey"
+	waitlistUserFKey    = "waitlist_user_id_fkey"
+
+	// waitlistUserSlotIndex is the unique index allowing a user to wait for a time slot once
+	waitlistUserSlotIndex = "waitlist_user_slot"
+
+	// trainersEmailIndex and usersEmailIndex are the unique indexes on emails, ignoring case
+	trainersEmailIndex = "trainers_email"
+	usersEmailIndex    = "users_email"
+
+	// trainerWorkingHoursWeekdayIndex is the unique index allowing one shift per day of the week for a trainer
+	trainerWorkingHoursWeekdayIndex = "trainer_working_hours_weekday"
+)
+
+// typedError is an error the API can expose to clients with a machine-readable type
+type typedError struct {
+	errType string
+	message string
+}
+
+func (e typedError) Error() string {
+	return e.message
+}
+
+// ErrorType satisfies the errorTyper interface in the controllers package
+func (e typedError) ErrorType() string {
+	return e.errType
+}
+
+// ErrAlreadyCanceled is returned when an appointment has already been canceled and can't be changed
+var ErrAlreadyCanceled = typedError{errType: "already_canceled", message: "appointment is already canceled"}
+
+// ErrWorkingHoursExist is returned when a trainer already has working hours on the weekday
+var ErrWorkingHoursExist = typedError{errType: "working_hours_exist", message: "trainer already has working hours on this weekday"}
+
+// ErrEmailTaken is returned when another trainer or user already has the email
+var ErrEmailTaken = typedError{errType: "email_taken", message: "email is already in use"}
+
+// ErrUnknownTrainer is returned when an appointment is for a trainer that doesn't exist
+var ErrUnknownTrainer = typedError{errType: "unknown_trainer", message: "trainer does not exist"}
+
+// ErrInactiveTrainer is returned when an appointment is for a trainer that has been deactivated
+var ErrInactiveTrainer = typedError{errType: "inactive_trainer", message: "trainer is not active"}
+
+// ErrUnknownUser is returned when an appointment is for a user that doesn't exist
+var ErrUnknownUser = typedError{errType: "unknown_user", message: "user does not exist"}
+
+// ErrNotInSeries is returned when canceling the following appointments of a one-off appointment
+var ErrNotInSeries = typedError{errType: "not_in_series", message: "appointment is not part of a series"}
+
+// ErrHoldNotOpen is returned when a hold has already been released or confirmed
+var ErrHoldNotOpen = typedError{errType: "hold_not_open", message: "hold has already been released or confirmed"}
+
+// ErrHoldExpired is returned when confirming a hold after it expired
+var ErrHoldExpired = typedError{errType: "hold_expired", message: "hold has expired"}
+
+// ErrAlreadyWaitlisted is returned when the user is already waiting for the time slot
+var ErrAlreadyWaitlisted = typedError{errType: "already_waitlisted", message: "user is already waiting for the time slot"}
+
+// ErrNotWaiting is returned when leaving the waitlist after the entry was offered the time slot or removed
+var ErrNotWaiting = typedError{errType: "not_waiting", message: "waitlist entry was already offered the time slot or removed"}
+
+// ErrStaleVersion is returned when changing an appointment that changed since the version the client has
+var ErrStaleVersion = typedError{errType: "stale_version", message: "appointment was changed since it was read"}
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with a different request
+var ErrIdempotencyKeyReused = typedError{errType: "idempotency_key_reused", message: "idempotency key was already used for a different request"}
+
+// ErrIdempotencyKeyInProgress is returned when an idempotency key is sent again before the first request finished
+var ErrIdempotencyKeyInProgress = typedError{errType: "idempotency_key_in_progress", message: "a request with the idempotency key is still in progress"}
+
+// SlotTakenError is returned when the trainer already has an appointment during the requested time slot
+type SlotTakenError struct {
+	TrainerID int64
+	StartsAt  time.Time
+	EndsAt    time.Time
+}
+
+func (e SlotTakenError) Error() string {
+	return fmt.Sprintf("trainer %d is already booked between %s and %s", e.TrainerID, e.StartsAt.Format(time.RFC3339), e.EndsAt.Format(time.RFC3339))
+}
+
+// ErrorType satisfies the errorTyper interface in the controllers package
+func (e SlotTakenError) ErrorType() string {
+	return "slot_taken"
+}
+
+// ErrorDetails satisfies the errorDetailer interface in the controllers package
+func (e SlotTakenError) ErrorDetails() map[string]interface{} {
+	return map[string]interface{}{
+		"trainer_id": e.TrainerID,
+		"starts_at":  e.StartsAt.UTC(),
+		"ends_at":    e.EndsAt.UTC(),
+	}
+}
+
+// isConstraintViolation checks if err is a postgres error with the code on the named constraint or index
+func isConstraintViolation(err error, code pq.ErrorCode, constraint string) bool {
+	pqErr, ok := errors.Cause(err).(*pq.Error)
+	return ok && pqErr.Code == code && pqErr.Constraint == constraint
+}
+
+// isSlotTaken checks if err is from the trainer being double booked, either the same time slot or an overlapping one
+func isSlotTaken(err error) bool {
+	return isConstraintViolation(err, uniqueViolation, trainerScheduledIndex) ||
+		isConstraintViolation(err, exclusionViolation, trainerNoOverlapConstraint)
+}
+
+// BatchItemError is returned when one appointment in a batch can't be booked, Index is its position in the batch
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e BatchItemError) Error() string {
+	return fmt.Sprintf("appointment %d: %s", e.Index, e.Err.Error())
+}
+
+// Cause lets errors.Cause get to the error for the appointment
+func (e BatchItemError) Cause() error {
+	return e.Err
+}
+
+// Unwrap lets errors.As find the error for the appointment
+func (e BatchItemError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,253 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"time"
+)
+
+type HoldsRepository interface {
+	CreateHold(ctx context.Context, newHold models.HoldCreateRequest, ttl time.Duration) (models.Hold, error)
+	GetHold(ctx context.Context, id int64) (models.Hold, error)
+	ReleaseHold(ctx context.Context, id int64) (models.Hold, error)
+	ConfirmHold(ctx context.Context, id int64) (models.Appointment, error)
+	ReleaseExpiredHolds(ctx context.Context) ([]models.Hold, error)
+}
+
+type HoldsRepoType struct {
+	db *sqlx.DB
+}
+
+func NewHoldsRepository(db *sqlx.DB) HoldsRepoType {
+	return HoldsRepoType{
+		db: db,
+	}
+}
+
+// openHoldCondition matches holds that still block their time slot
+var openHoldCondition = sq.Expr("released_at is null and appointment_id is null and expires_at > now()")
+
+// trainerLockNamespace is the first key of the per-trainer advisory lock and the trainer ID is the second, so it can't
+// collide with another advisory lock keyed on a small integer. postgres keeps one-key and two-key locks apart
+const trainerLockNamespace = 7301
+
+// lockTrainerQuery serializes bookings and holds for a trainer until the transaction ends, pass trainerLockNamespace
+// and the trainer ID. holds are in their own table, so the exclusion constraint can't stop a booking from overlapping a
+// hold. the keys are 32-bit, trainer IDs past that share a lock with a lower one, which only makes them wait on each other
+const lockTrainerQuery = `select pg_advisory_xact_lock($1, ($2::bigint & 2147483647)::int)`
+
+const heldByOtherUserQuery = `
+select exists(
+    select 1 from scheduling.holds
+    where trainer_id = $1 and starts_at < $3 and ends_at > $2 and user_id <> $4
+      and released_at is null and appointment_id is null and expires_at > now()
+)
+`
+
+// checkHeld locks the trainer and returns a SlotTakenError if another user has an open hold overlapping the time slot
+// a user's own holds don't block them
+func checkHeld(ctx context.Context, tx *sqlx.Tx, trainerID int64, userID int64, startsAt time.Time, endsAt time.Time) error {
+	_, err := tx.ExecContext(ctx, lockTrainerQuery, trainerLockNamespace, trainerID)
+	if err != nil {
+		return errors.Wrap(err, "error locking trainer")
+	}
+
+	var held bool
+	err = tx.QueryRowxContext(ctx, heldByOtherUserQuery, trainerID, startsAt, endsAt, userID).Scan(&held)
+	if err != nil {
+		return errors.Wrap(err, "error checking holds")
+	}
+
+	if held {
+		return SlotTakenError{TrainerID: trainerID, StartsAt: startsAt, EndsAt: endsAt}
+	}
+
+	return nil
+}
+
+const createHoldQuery = `
+insert into scheduling.holds(trainer_id, user_id, starts_at, ends_at, session_type_id, expires_at)
+VALUES ($1, $2, $3, $4, nullif($5, 0), now() + make_interval(secs => $6))
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, expires_at, released_at, appointment_id, created_at
+`
+
+// CreateHold reserves the time slot for the user until the ttl passes, returns a SlotTakenError when the time slot is
+// already booked or held by another user
+func (hr *HoldsRepoType) CreateHold(ctx context.Context, newHold models.HoldCreateRequest, ttl time.Duration) (models.Hold, error) {
+	tx, err := hr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Hold{}, errors.Wrap(err, "error creating hold")
+	}
+	defer tx.Rollback()
+
+	err = checkHeld(ctx, tx, newHold.TrainerID, newHold.UserID, newHold.StartsAt, newHold.EndsAt)
+	if err != nil {
+		return models.Hold{}, err
+	}
+
+	var taken bool
+	err = tx.QueryRowxContext(ctx, trainerSlotTakenQuery, newHold.TrainerID, newHold.StartsAt, newHold.EndsAt, 0).Scan(&taken)
+	if err != nil {
+		return models.Hold{}, errors.Wrap(err, "error creating hold")
+	}
+
+	if taken {
+		return models.Hold{}, SlotTakenError{TrainerID: newHold.TrainerID, StartsAt: newHold.StartsAt, EndsAt: newHold.EndsAt}
+	}
+
+	var h models.Hold
+	err = tx.QueryRowxContext(ctx, createHoldQuery, newHold.TrainerID, newHold.UserID, newHold.StartsAt, newHold.EndsAt,
+		newHold.SessionTypeID, ttl.Seconds()).StructScan(&h)
+
+	if isConstraintViolation(err, foreignKeyViolation, holdsTrainerFKey) {
+		return models.Hold{}, ErrUnknownTrainer
+	}
+
+	if isConstraintViolation(err, foreignKeyViolation, holdsUserFKey) {
+		return models.Hold{}, ErrUnknownUser
+	}
+
+	if err != nil {
+		return models.Hold{}, errors.Wrap(err, "error creating hold")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Hold{}, errors.Wrap(err, "error creating hold")
+	}
+
+	return h, nil
+}
+
+const getHoldQuery = `
+select id, trainer_id, user_id, starts_at, ends_at, session_type_id, expires_at, released_at, appointment_id, created_at
+from scheduling.holds
+where id = $1
+`
+
+// GetHold gets a single hold, open or not. returns sql.ErrNoRows when it doesn't exist
+func (hr *HoldsRepoType) GetHold(ctx context.Context, id int64) (models.Hold, error) {
+	var h models.Hold
+	err := hr.db.QueryRowxContext(ctx, getHoldQuery, id).StructScan(&h)
+	if err != nil {
+		return models.Hold{}, errors.Wrap(err, "error getting hold")
+	}
+
+	return h, nil
+}
+
+const releaseHoldQuery = `
+update scheduling.holds
+set released_at = now()
+where id = $1 and released_at is null and appointment_id is null
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, expires_at, released_at, appointment_id, created_at
+`
+
+// ReleaseHold gives up the hold before it expires, returns ErrHoldNotOpen when it was already released or confirmed
+func (hr *HoldsRepoType) ReleaseHold(ctx context.Context, id int64) (models.Hold, error) {
+	var h models.Hold
+	err := hr.db.QueryRowxContext(ctx, releaseHoldQuery, id).StructScan(&h)
+	if err == nil {
+		return h, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return models.Hold{}, errors.Wrap(err, "error releasing hold")
+	}
+
+	// nothing updated, either the hold doesn't exist or it isn't open
+	_, err = hr.GetHold(ctx, id)
+	if err != nil {
+		return models.Hold{}, err
+	}
+
+	return models.Hold{}, ErrHoldNotOpen
+}
+
+const getHoldForUpdateQuery = `
+select id, trainer_id, user_id, starts_at, ends_at, session_type_id, expires_at, released_at, appointment_id, created_at,
+       expires_at <= now() as expired
+from scheduling.holds
+where id = $1
+for update
+`
+
+const confirmHoldQuery = `
+update scheduling.holds set appointment_id = $2 where id = $1
+`
+
+// ConfirmHold books the held time slot for the hold's user and marks the hold confirmed, in a single transaction
+// returns ErrHoldNotOpen when it was already released or confirmed and ErrHoldExpired once it expires
+func (hr *HoldsRepoType) ConfirmHold(ctx context.Context, id int64) (models.Appointment, error) {
+	tx, err := hr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error confirming hold")
+	}
+	defer tx.Rollback()
+
+	var h struct {
+		models.Hold
+		Expired bool `db:"expired"`
+	}
+	err = tx.QueryRowxContext(ctx, getHoldForUpdateQuery, id).StructScan(&h)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error confirming hold")
+	}
+
+	if h.ReleasedAt != nil || h.AppointmentID != nil {
+		return models.Appointment{}, ErrHoldNotOpen
+	}
+
+	if h.Expired {
+		return models.Appointment{}, ErrHoldExpired
+	}
+
+	var sessionTypeID int64
+	if h.SessionTypeID != nil {
+		sessionTypeID = *h.SessionTypeID
+	}
+
+	a, err := createAppointment(ctx, tx, models.AppointmentCreateRequest{
+		TrainerID:     h.TrainerID,
+		UserID:        h.UserID,
+		StartsAt:      h.StartsAt,
+		EndsAt:        h.EndsAt,
+		SessionTypeID: sessionTypeID,
+	}, 0)
+	if err != nil {
+		return models.Appointment{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, confirmHoldQuery, id, a.ID)
+	if err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error confirming hold")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Appointment{}, errors.Wrap(err, "error confirming hold")
+	}
+
+	return a, nil
+}
+
+const releaseExpiredHoldsQuery = `
+update scheduling.holds
+set released_at = now()
+where released_at is null and appointment_id is null and expires_at <= now()
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, expires_at, released_at, appointment_id, created_at
+`
+
+// ReleaseExpiredHolds marks every expired hold released, expired holds already stop blocking their time slot,
+// this closes them out so they show up as released
+func (hr *HoldsRepoType) ReleaseExpiredHolds(ctx context.Context) ([]models.Hold, error) {
+	holds := make([]models.Hold, 0)
+	err := hr.db.SelectContext(ctx, &holds, releaseExpiredHoldsQuery)
+	if err != nil {
+		return []models.Hold{}, errors.Wrap(err, "error releasing expired holds")
+	}
+
+	return holds, nil
+}
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"time"
+)
+
+// MockHolds is an implementation of HoldsRepository to set values to use as a mock when testing
+type MockHolds struct {
+	CreateHoldResponse models.Hold
+	CreateHoldErr      error
+
+	GetHoldResponse models.Hold
+	GetHoldErr      error
+
+	ReleaseHoldResponse models.Hold
+	ReleaseHoldErr      error
+
+	ConfirmHoldResponse models.Appointment
+	ConfirmHoldErr      error
+
+	ReleaseExpiredHoldsResponse []models.Hold
+	ReleaseExpiredHoldsErr      error
+}
+
+func (m *MockHolds) CreateHold(ctx context.Context, newHold models.HoldCreateRequest, ttl time.Duration) (models.Hold, error) {
+	return m.CreateHoldResponse, m.CreateHoldErr
+}
+
+func (m *MockHolds) GetHold(ctx context.Context, id int64) (models.Hold, error) {
+	return m.GetHoldResponse, m.GetHoldErr
+}
+
+func (m *MockHolds) ReleaseHold(ctx context.Context, id int64) (models.Hold, error) {
+	return m.ReleaseHoldResponse, m.ReleaseHoldErr
+}
+
+func (m *MockHolds) ConfirmHold(ctx context.Context, id int64) (models.Appointment, error) {
+	return m.ConfirmHoldResponse, m.ConfirmHoldErr
+}
+
+func (m *MockHolds) ReleaseExpiredHolds(ctx context.Context) ([]models.Hold, error) {
+	return m.ReleaseExpiredHoldsResponse, m.ReleaseExpiredHoldsErr
+}
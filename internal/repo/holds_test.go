@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func holdRequest(trainerID int64, userID int64, startsAt time.Time) models.HoldCreateRequest {
+	return models.HoldCreateRequest{
+		TrainerID: trainerID,
+		UserID:    userID,
+		StartsAt:  startsAt,
+		EndsAt:    startsAt.Add(30 * time.Minute),
+	}
+}
+
+// expireHold moves the hold's expiry into the past instead of waiting for it
+func expireHold(t *testing.T, id int64) {
+	_, err := DB.Exec("update scheduling.holds set expires_at = now() - interval '1 second' where id = $1", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHoldsRepository_CreateHold(t *testing.T) {
+	PurgeTables()
+
+	hr := &HoldsRepoType{db: DB}
+	ar := &AppointmentsRepoType{db: DB}
+
+	startsAt := time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)
+	hold, err := hr.CreateHold(context.Background(), holdRequest(1, 1, startsAt), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, hold.ReleasedAt)
+	assert.Nil(t, hold.AppointmentID)
+	assert.True(t, hold.ExpiresAt.After(time.Now()))
+
+	// another user can't hold or book an overlapping time slot
+	_, err = hr.CreateHold(context.Background(), holdRequest(1, 2, startsAt.Add(15*time.Minute)), 10*time.Minute)
+	assert.True(t, errors.As(err, &SlotTakenError{}))
+
+	_, err = ar.CreateAppointment(context.Background(), holdRequest(1, 2, startsAt).AppointmentCreateRequest())
+	assert.True(t, errors.As(err, &SlotTakenError{}))
+
+	// the hold shows up as booked
+	booked, err := ar.GetBookedTimeSlots(context.Background(), 1, startsAt.Add(-time.Hour), startsAt.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, booked, 1)
+
+	// a different trainer at the same time is fine
+	_, err = hr.CreateHold(context.Background(), holdRequest(2, 2, startsAt), 10*time.Minute)
+	assert.Nil(t, err)
+
+	// once it expires it stops blocking the time slot
+	expireHold(t, hold.ID)
+	_, err = ar.CreateAppointment(context.Background(), holdRequest(1, 2, startsAt).AppointmentCreateRequest())
+	assert.Nil(t, err)
+
+	// holding a booked time slot fails
+	_, err = hr.CreateHold(context.Background(), holdRequest(1, 3, startsAt), 10*time.Minute)
+	assert.True(t, errors.As(err, &SlotTakenError{}))
+
+	_, err = hr.CreateHold(context.Background(), holdRequest(1, 100, startsAt.Add(time.Hour)), 10*time.Minute)
+	assert.Equal(t, ErrUnknownUser, errors.Cause(err))
+}
+
+func TestHoldsRepository_ConfirmHold(t *testing.T) {
+	PurgeTables()
+
+	hr := &HoldsRepoType{db: DB}
+
+	startsAt := time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)
+	hold, err := hr.CreateHold(context.Background(), holdRequest(1, 1, startsAt), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appointment, err := hr.ConfirmHold(context.Background(), hold.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(1), appointment.UserID)
+	assert.Equal(t, startsAt, appointment.StartsAt.UTC())
+
+	got, err := hr.GetHold(context.Background(), hold.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, appointment.ID, *got.AppointmentID)
+
+	_, err = hr.ConfirmHold(context.Background(), hold.ID)
+	assert.Equal(t, ErrHoldNotOpen, errors.Cause(err))
+
+	expired, err := hr.CreateHold(context.Background(), holdRequest(1, 1, startsAt.Add(time.Hour)), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expireHold(t, expired.ID)
+	_, err = hr.ConfirmHold(context.Background(), expired.ID)
+	assert.Equal(t, ErrHoldExpired, errors.Cause(err))
+}
+
+func TestHoldsRepository_ReleaseHold(t *testing.T) {
+	PurgeTables()
+
+	hr := &HoldsRepoType{db: DB}
+
+	startsAt := time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)
+	hold, err := hr.CreateHold(context.Background(), holdRequest(1, 1, startsAt), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released, err := hr.ReleaseHold(context.Background(), hold.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotNil(t, released.ReleasedAt)
+
+	_, err = hr.ReleaseHold(context.Background(), hold.ID)
+	assert.Equal(t, ErrHoldNotOpen, errors.Cause(err))
+
+	_, err = hr.ConfirmHold(context.Background(), hold.ID)
+	assert.Equal(t, ErrHoldNotOpen, errors.Cause(err))
+
+	// released, so another user can hold it
+	_, err = hr.CreateHold(context.Background(), holdRequest(1, 2, startsAt), 10*time.Minute)
+	assert.Nil(t, err)
+}
+
+func TestHoldsRepository_ReleaseExpiredHolds(t *testing.T) {
+	PurgeTables()
+
+	hr := &HoldsRepoType{db: DB}
+
+	startsAt := time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)
+	expired, err := hr.CreateHold(context.Background(), holdRequest(1, 1, startsAt), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open, err := hr.CreateHold(context.Background(), holdRequest(2, 1, startsAt), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expireHold(t, expired.ID)
+	released, err := hr.ReleaseExpiredHolds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, released, 1)
+	assert.Equal(t, expired.ID, released[0].ID)
+
+	got, err := hr.GetHold(context.Background(), open.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, got.ReleasedAt)
+}
@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+// claimIdempotencyKeyQuery saves a new key, or takes over one that expired after 24 hours, or one whose request was
+// abandoned without a response for over a minute (ex. the API restarted) if it's the same request
+const claimIdempotencyKeyQuery = `
+insert into scheduling.idempotency_keys(endpoint, key, request_hash)
+VALUES ($1, $2, $3)
+on conflict (endpoint, key) do update
+set request_hash = excluded.request_hash, response_status = null, response_body = null, created_at = now(), completed_at = null
+where idempotency_keys.created_at < now() - interval '24 hours'
+   or (idempotency_keys.response_status is null and idempotency_keys.created_at < now() - interval '1 minute'
+       and idempotency_keys.request_hash = excluded.request_hash)
+returning endpoint, key, request_hash, response_status, response_body, created_at, completed_at
+`
+
+const getIdempotencyKeyQuery = `
+select endpoint, key, request_hash, response_status, response_body, created_at, completed_at
+from scheduling.idempotency_keys
+where endpoint = $1 and key = $2
+`
+
+// ClaimIdempotencyKey claims the key for handling the request. without an error, a nil ResponseStatus means the request
+// is new and should be handled, otherwise it is a retry and the saved response should be replayed
+// returns ErrIdempotencyKeyReused when the key was used for a different request, and ErrIdempotencyKeyInProgress when
+// the first request with the key hasn't finished
+func (ar *AppointmentsRepoType) ClaimIdempotencyKey(ctx context.Context, endpoint string, key string, requestHash string) (models.IdempotencyKey, error) {
+	var k models.IdempotencyKey
+	err := ar.db.QueryRowxContext(ctx, claimIdempotencyKeyQuery, endpoint, key, requestHash).StructScan(&k)
+	if err == nil {
+		return k, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return models.IdempotencyKey{}, errors.Wrap(err, "error claiming idempotency key")
+	}
+
+	// the key is already used
+	err = ar.db.QueryRowxContext(ctx, getIdempotencyKeyQuery, endpoint, key).StructScan(&k)
+	if err == sql.ErrNoRows {
+		// released between the two queries, the first request failed and this one can be retried
+		return models.IdempotencyKey{}, ErrIdempotencyKeyInProgress
+	}
+
+	if err != nil {
+		return models.IdempotencyKey{}, errors.Wrap(err, "error claiming idempotency key")
+	}
+
+	if k.RequestHash != requestHash {
+		return models.IdempotencyKey{}, ErrIdempotencyKeyReused
+	}
+
+	if k.ResponseStatus == nil {
+		return models.IdempotencyKey{}, ErrIdempotencyKeyInProgress
+	}
+
+	return k, nil
+}
+
+const saveIdempotentResponseQuery = `
+update scheduling.idempotency_keys
+set response_status = $3, response_body = $4, completed_at = now()
+where endpoint = $1 and key = $2
+`
+
+// SaveIdempotentResponse saves the response to replay for retries with the key
+func (ar *AppointmentsRepoType) SaveIdempotentResponse(ctx context.Context, endpoint string, key string, status int, body []byte) error {
+	_, err := ar.db.ExecContext(ctx, saveIdempotentResponseQuery, endpoint, key, status, body)
+	if err != nil {
+		return errors.Wrap(err, "error saving idempotent response")
+	}
+
+	return nil
+}
+
+const releaseIdempotencyKeyQuery = `
+delete from scheduling.idempotency_keys
+where endpoint = $1 and key = $2 and response_status is null
+`
+
+// ReleaseIdempotencyKey forgets a claimed key without a response, so a retry is handled again
+func (ar *AppointmentsRepoType) ReleaseIdempotencyKey(ctx context.Context, endpoint string, key string) error {
+	_, err := ar.db.ExecContext(ctx, releaseIdempotencyKeyQuery, endpoint, key)
+	if err != nil {
+		return errors.Wrap(err, "error releasing idempotency key")
+	}
+
+	return nil
+}
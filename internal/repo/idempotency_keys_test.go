@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// purgeIdempotencyKeys empties the keys, they aren't in purgeTables since they don't have an id sequence
+func purgeIdempotencyKeys(t *testing.T) {
+	_, err := DB.Exec("delete from scheduling.idempotency_keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppointmentRepository_ClaimIdempotencyKey(t *testing.T) {
+	purgeIdempotencyKeys(t)
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	endpoint := "POST /v1/appointments"
+	claimed, err := r.ClaimIdempotencyKey(context.Background(), endpoint, "key-1", "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, claimed.ResponseStatus)
+
+	// retried before the first request finished
+	_, err = r.ClaimIdempotencyKey(context.Background(), endpoint, "key-1", "hash-1")
+	assert.Equal(t, ErrIdempotencyKeyInProgress, errors.Cause(err))
+
+	err = r.SaveIdempotentResponse(context.Background(), endpoint, "key-1", http.StatusCreated, []byte(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := r.ClaimIdempotencyKey(context.Background(), endpoint, "key-1", "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusCreated, *replayed.ResponseStatus)
+	assert.Equal(t, []byte(`{"id":1}`), replayed.ResponseBody)
+	assert.NotNil(t, replayed.CompletedAt)
+
+	_, err = r.ClaimIdempotencyKey(context.Background(), endpoint, "key-1", "hash-2")
+	assert.Equal(t, ErrIdempotencyKeyReused, errors.Cause(err))
+
+	// keys are per endpoint
+	other, err := r.ClaimIdempotencyKey(context.Background(), "POST /v1/holds", "key-1", "hash-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, other.ResponseStatus)
+}
+
+func TestAppointmentRepository_ReleaseIdempotencyKey(t *testing.T) {
+	purgeIdempotencyKeys(t)
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	endpoint := "POST /v1/appointments"
+	_, err := r.ClaimIdempotencyKey(context.Background(), endpoint, "key-1", "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.ReleaseIdempotencyKey(context.Background(), endpoint, "key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// released after a 5xx, so the retry is handled again
+	claimed, err := r.ClaimIdempotencyKey(context.Background(), endpoint, "key-1", "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, claimed.ResponseStatus)
+
+	// abandoned for over a minute, the same request can take it over
+	_, err = DB.Exec("update scheduling.idempotency_keys set created_at = now() - interval '2 minutes'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err = r.ClaimIdempotencyKey(context.Background(), endpoint, "key-1", "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, claimed.ResponseStatus)
+}
+
+func TestAppointmentRepository_ClaimIdempotencyKeyConcurrently(t *testing.T) {
+	purgeIdempotencyKeys(t)
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	// only one of the retries sent at once gets to handle the request
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = r.ClaimIdempotencyKey(context.Background(), "POST /v1/appointments", "key-1", "hash-1")
+		}(i)
+	}
+
+	wg.Wait()
+
+	claimed := 0
+	for _, err := range errs {
+		if err == nil {
+			claimed++
+			continue
+		}
+
+		assert.Equal(t, ErrIdempotencyKeyInProgress, errors.Cause(err))
+	}
+
+	assert.Equal(t, 1, claimed)
+}
@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// trainer_id and user_id started out as text, these make sure they behave like numbers after being converted to bigint
+
+func TestAppointmentsMigration_IDColumnTypes(t *testing.T) {
+	columns := make([]struct {
+		Name     string `db:"column_name"`
+		DataType string `db:"data_type"`
+	}, 0)
+	err := DB.Select(&columns, `
+select column_name, data_type
+from information_schema.columns
+where table_schema = 'scheduling' and table_name = 'appointments' and column_name in ('trainer_id', 'user_id')
+order by column_name
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, columns, 2)
+	for _, c := range columns {
+		assert.Equal(t, "bigint", c.DataType, c.Name)
+	}
+}
+
+func TestAppointmentsMigration_NumericTrainerIDs(t *testing.T) {
+	PurgeTables()
+
+	// as text, 10 and 11 would sort before 2
+	_, err := DB.Exec(`
+insert into scheduling.trainers (id, name, email)
+VALUES (10, 'Trainer 10', 'trainer10@example.com'),
+       (11, 'Trainer 11', 'trainer11@example.com');
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &AppointmentsRepoType{
+		db: DB,
+	}
+
+	for _, trainerID := range []int64{11, 2, 10, 1} {
+		_, err := r.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+			TrainerID: trainerID,
+			UserID:    1,
+			StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("ordered numerically", func(t *testing.T) {
+		trainerIDs := make([]int64, 0)
+		err := DB.Select(&trainerIDs, `select trainer_id from scheduling.appointments order by trainer_id`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, []int64{1, 2, 10, 11}, trainerIDs)
+	})
+
+	t.Run("filtered numerically", func(t *testing.T) {
+		got, err := r.GetScheduledAppointments(context.Background(), models.AppointmentsFilter{TrainerID: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, got, 1)
+		assert.Equal(t, int64(1), got[0].TrainerID)
+
+		got, err = r.GetScheduledAppointments(context.Background(), models.AppointmentsFilter{TrainerID: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, got, 1)
+		assert.Equal(t, int64(10), got[0].TrainerID)
+	})
+
+	t.Run("trainer_scheduled still prevents double booking", func(t *testing.T) {
+		_, err := r.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+			TrainerID: 10,
+			UserID:    2,
+			StartsAt:  time.Date(2022, 03, 17, 12, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 17, 12, 30, 0, 0, time.UTC),
+		})
+		assert.IsType(t, SlotTakenError{}, err)
+	})
+}
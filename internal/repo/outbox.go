@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"sort"
+)
+
+type OutboxRepository interface {
+	ClaimOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	MarkOutboxEventFailed(ctx context.Context, id int64, cause error) error
+}
+
+type OutboxRepoType struct {
+	db *sqlx.DB
+}
+
+func NewOutboxRepository(db *sqlx.DB) OutboxRepoType {
+	return OutboxRepoType{
+		db: db,
+	}
+}
+
+const addOutboxEventQuery = `
+insert into scheduling.outbox(event_type, payload, occurred_at)
+VALUES ($1, $2, $3)
+`
+
+// addEvent saves the event to the outbox in the transaction, so it's only published when the change it's about is committed
+func addEvent(ctx context.Context, tx *sqlx.Tx, event events.Event) error {
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		return errors.Wrap(err, "error adding event to outbox")
+	}
+
+	// lib/pq sends []byte as bytea, jsonb needs it as text
+	_, err = tx.ExecContext(ctx, addOutboxEventQuery, event.Type, string(payload), event.OccurredAt)
+	if err != nil {
+		return errors.Wrap(err, "error adding event to outbox")
+	}
+
+	return nil
+}
+
+// claimOutboxEventsQuery pushes next_attempt_at back before publishing, so the claimed events aren't claimed again by
+// another relay. if the relay dies before marking them, they're retried once it passes. the delay doubles with each
+// attempt from 30 seconds up to an hour
+const claimOutboxEventsQuery = `
+update scheduling.outbox
+set attempts = attempts + 1,
+    next_attempt_at = now() + make_interval(secs => least(30 * power(2, attempts), 3600))
+where id in (
+    select id from scheduling.outbox
+    where published_at is null and next_attempt_at <= now()
+    order by id
+    limit $1
+    for update skip locked
+)
+returning id, event_type, payload, occurred_at, attempts, next_attempt_at, last_error, published_at
+`
+
+// ClaimOutboxEvents gets up to limit unpublished events that are due, oldest first, and schedules their next attempt
+// each one has to be marked published or failed after publishing it
+func (or *OutboxRepoType) ClaimOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	claimed := make([]models.OutboxEvent, 0)
+	err := or.db.SelectContext(ctx, &claimed, claimOutboxEventsQuery, limit)
+	if err != nil {
+		return []models.OutboxEvent{}, errors.Wrap(err, "error claiming outbox events")
+	}
+
+	// update ... returning doesn't keep an order
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	return claimed, nil
+}
+
+const markOutboxEventPublishedQuery = `
+update scheduling.outbox
+set published_at = now(), last_error = null
+where id = $1
+`
+
+func (or *OutboxRepoType) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	_, err := or.db.ExecContext(ctx, markOutboxEventPublishedQuery, id)
+	if err != nil {
+		return errors.Wrap(err, "error marking outbox event published")
+	}
+
+	return nil
+}
+
+const markOutboxEventFailedQuery = `
+update scheduling.outbox
+set last_error = $2
+where id = $1
+`
+
+// MarkOutboxEventFailed saves why publishing the event failed, it is retried when its next attempt is due
+func (or *OutboxRepoType) MarkOutboxEventFailed(ctx context.Context, id int64, cause error) error {
+	_, err := or.db.ExecContext(ctx, markOutboxEventFailedQuery, id, cause.Error())
+	if err != nil {
+		return errors.Wrap(err, "error marking outbox event failed")
+	}
+
+	return nil
+}
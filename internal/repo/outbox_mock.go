@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+// MockOutbox is an implementation of OutboxRepository to set values to use as a mock when testing
+// the IDs of the events marked published or failed are kept to check
+type MockOutbox struct {
+	ClaimOutboxEventsResponse []models.OutboxEvent
+	ClaimOutboxEventsErr      error
+
+	MarkOutboxEventPublishedErr error
+	MarkOutboxEventFailedErr    error
+
+	PublishedIDs []int64
+	FailedIDs    []int64
+}
+
+func (m *MockOutbox) ClaimOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	return m.ClaimOutboxEventsResponse, m.ClaimOutboxEventsErr
+}
+
+func (m *MockOutbox) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	if m.MarkOutboxEventPublishedErr != nil {
+		return m.MarkOutboxEventPublishedErr
+	}
+
+	m.PublishedIDs = append(m.PublishedIDs, id)
+	return nil
+}
+
+func (m *MockOutbox) MarkOutboxEventFailed(ctx context.Context, id int64, cause error) error {
+	if m.MarkOutboxEventFailedErr != nil {
+		return m.MarkOutboxEventFailedErr
+	}
+
+	m.FailedIDs = append(m.FailedIDs, id)
+	return nil
+}
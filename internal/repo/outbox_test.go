@@ -0,0 +1,267 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// outboxEventTypes gets the types of every event in the outbox, oldest first
+func outboxEventTypes(t *testing.T) []string {
+	types := make([]string, 0)
+	err := DB.Select(&types, "select event_type from scheduling.outbox order by id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return types
+}
+
+func TestOutboxRepository_AppointmentEvents(t *testing.T) {
+	PurgeTables()
+
+	ar := &AppointmentsRepoType{db: DB}
+
+	newAppt := models.AppointmentCreateRequest{
+		TrainerID: 1,
+		UserID:    1,
+		StartsAt:  time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2022, 03, 17, 19, 30, 0, 0, time.UTC),
+	}
+	created, err := ar.CreateAppointment(context.Background(), newAppt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a booking that fails is rolled back along with its event
+	_, err = ar.CreateAppointment(context.Background(), newAppt)
+	assert.IsType(t, SlotTakenError{}, errors.Cause(err))
+
+	rescheduled, err := ar.RescheduleAppointment(context.Background(), created.ID, models.AppointmentRescheduleRequest{
+		StartsAt: time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2022, 03, 17, 20, 30, 0, 0, time.UTC),
+	}, created.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ar.CancelAppointment(context.Background(), created.ID, created.Version)
+	assert.Equal(t, ErrStaleVersion, errors.Cause(err))
+
+	_, err = ar.CancelAppointment(context.Background(), created.ID, rescheduled.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{events.AppointmentCreated, events.AppointmentRescheduled, events.AppointmentCanceled}, outboxEventTypes(t))
+
+	var payload []byte
+	err = DB.Get(&payload, "select payload from scheduling.outbox where event_type = $1", events.AppointmentRescheduled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data events.AppointmentData
+	err = json.Unmarshal(payload, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, created.ID, data.Appointment.ID)
+	assert.True(t, rescheduled.StartsAt.Equal(data.Appointment.StartsAt))
+	if assert.NotNil(t, data.Previous) {
+		assert.True(t, created.StartsAt.Equal(data.Previous.StartsAt))
+	}
+}
+
+func TestOutboxRepository_WaitlistOfferedEvents(t *testing.T) {
+	PurgeTables()
+
+	ar := &AppointmentsRepoType{db: DB}
+	wr := &WaitlistRepoType{db: DB}
+
+	startsAt := futureSlot()
+	booked, err := ar.CreateAppointment(context.Background(), waitlistRequest(1, 1, startsAt).AppointmentCreateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := wr.JoinWaitlist(context.Background(), waitlistRequest(1, 2, startsAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ar.CancelAppointment(context.Background(), booked.ID, booked.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offers, err := wr.OfferFreedSlot(context.Background(), 1, booked.StartsAt, booked.EndsAt, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Len(t, offers, 1) {
+		return
+	}
+
+	assert.Equal(t, []string{events.AppointmentCreated, events.AppointmentCanceled, events.WaitlistOffered}, outboxEventTypes(t))
+
+	// xmin is the transaction that wrote the row, the event, the hold and the offered entry are all from the same one
+	var eventTx, holdTx, entryTx string
+	err = DB.Get(&eventTx, "select xmin::text from scheduling.outbox where event_type = $1", events.WaitlistOffered)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DB.Get(&holdTx, "select xmin::text from scheduling.holds where id = $1", offers[0].Hold.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DB.Get(&entryTx, "select xmin::text from scheduling.waitlist where id = $1", entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, eventTx, holdTx)
+	assert.Equal(t, eventTx, entryTx)
+
+	var payload []byte
+	err = DB.Get(&payload, "select payload from scheduling.outbox where event_type = $1", events.WaitlistOffered)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data models.WaitlistOffer
+	err = json.Unmarshal(payload, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, entry.ID, data.Entry.ID)
+	assert.Equal(t, models.WaitlistOffered, data.Entry.Status)
+	assert.Equal(t, offers[0].Hold.ID, data.Hold.ID)
+}
+
+func TestOutboxRepository_ClaimOutboxEvents(t *testing.T) {
+	PurgeTables()
+
+	ar := &AppointmentsRepoType{db: DB}
+	or := &OutboxRepoType{db: DB}
+
+	for i := 0; i < 3; i++ {
+		startsAt := time.Date(2022, 03, 17, 17+i, 0, 0, 0, time.UTC)
+		_, err := ar.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+			TrainerID: 1,
+			UserID:    1,
+			StartsAt:  startsAt,
+			EndsAt:    startsAt.Add(30 * time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	claimed, err := or.ClaimOutboxEvents(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, claimed, 2)
+	assert.Equal(t, int64(1), claimed[0].ID)
+	assert.Equal(t, int64(2), claimed[1].ID)
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.True(t, claimed[0].NextAttemptAt.After(time.Now()))
+
+	// claimed events aren't claimed again until their next attempt
+	next, err := or.ClaimOutboxEvents(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, next, 1)
+	assert.Equal(t, int64(3), next[0].ID)
+
+	err = or.MarkOutboxEventPublished(context.Background(), claimed[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = or.MarkOutboxEventFailed(context.Background(), claimed[1].ID, errors.New("sink is down"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// make the failed event due, the published one is never claimed again
+	_, err = DB.Exec("update scheduling.outbox set next_attempt_at = now() - interval '1 second'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retried, err := or.ClaimOutboxEvents(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, retried, 2)
+	assert.Equal(t, claimed[1].ID, retried[0].ID)
+	assert.Equal(t, 2, retried[0].Attempts)
+	if assert.NotNil(t, retried[0].LastError) {
+		assert.Equal(t, "sink is down", *retried[0].LastError)
+	}
+}
+
+func TestOutboxRepository_ClaimOutboxEvents_Concurrent(t *testing.T) {
+	PurgeTables()
+
+	ar := &AppointmentsRepoType{db: DB}
+	or := &OutboxRepoType{db: DB}
+
+	for i := 0; i < 10; i++ {
+		startsAt := time.Date(2022, 03, 17, 15, 0, 0, 0, time.UTC).Add(time.Duration(i) * 30 * time.Minute)
+		_, err := ar.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+			TrainerID: 1,
+			UserID:    1,
+			StartsAt:  startsAt,
+			EndsAt:    startsAt.Add(30 * time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// relays claiming at the same time never get the same event
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[int64]int)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := or.ClaimOutboxEvents(context.Background(), 3)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, e := range claimed {
+				seen[e.ID]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 10)
+	for id, count := range seen {
+		assert.Equal(t, 1, count, "event %d claimed more than once", id)
+	}
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+type SessionTypesRepository interface {
+	ListSessionTypes(ctx context.Context) ([]models.SessionType, error)
+	GetSessionType(ctx context.Context, id int64) (models.SessionType, error)
+	CreateSessionType(ctx context.Context, sessionType models.SessionTypeCreateRequest) (models.SessionType, error)
+}
+
+type SessionTypesRepoType struct {
+	db *sqlx.DB
+}
+
+func NewSessionTypesRepository(db *sqlx.DB) SessionTypesRepoType {
+	return SessionTypesRepoType{
+		db: db,
+	}
+}
+
+const listSessionTypesQuery = `
+select id, name, duration_minutes, slot_granularity_minutes, created_at
+from scheduling.session_types
+order by duration_minutes, id
+`
+
+const getSessionTypeQuery = `
+select id, name, duration_minutes, slot_granularity_minutes, created_at
+from scheduling.session_types
+where id = $1
+`
+
+const createSessionTypeQuery = `
+insert into scheduling.session_types(name, duration_minutes, slot_granularity_minutes)
+VALUES ($1, $2, $3)
+returning id, name, duration_minutes, slot_granularity_minutes, created_at
+`
+
+func (sr *SessionTypesRepoType) ListSessionTypes(ctx context.Context) ([]models.SessionType, error) {
+	sessionTypes := make([]models.SessionType, 0)
+	err := sr.db.SelectContext(ctx, &sessionTypes, listSessionTypesQuery)
+	if err != nil {
+		return []models.SessionType{}, errors.Wrap(err, "error getting session types")
+	}
+
+	return sessionTypes, nil
+}
+
+// GetSessionType gets a single session type, returns sql.ErrNoRows when it doesn't exist
+func (sr *SessionTypesRepoType) GetSessionType(ctx context.Context, id int64) (models.SessionType, error) {
+	var s models.SessionType
+	err := sr.db.QueryRowxContext(ctx, getSessionTypeQuery, id).StructScan(&s)
+	if err != nil {
+		return models.SessionType{}, errors.Wrap(err, "error getting session type")
+	}
+
+	return s, nil
+}
+
+func (sr *SessionTypesRepoType) CreateSessionType(ctx context.Context, sessionType models.SessionTypeCreateRequest) (models.SessionType, error) {
+	var s models.SessionType
+	err := sr.db.QueryRowxContext(ctx, createSessionTypeQuery, sessionType.Name, sessionType.DurationMinutes, sessionType.SlotGranularityMinutes).StructScan(&s)
+	if err != nil {
+		return models.SessionType{}, errors.Wrap(err, "error creating session type")
+	}
+
+	return s, nil
+}
@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+// MockSessionTypes is an implementation of SessionTypesRepository to set values to use as a mock when testing
+type MockSessionTypes struct {
+	ListSessionTypesResponse []models.SessionType
+	ListSessionTypesErr      error
+
+	GetSessionTypeResponse models.SessionType
+	GetSessionTypeErr      error
+
+	CreateSessionTypeResponse models.SessionType
+	CreateSessionTypeErr      error
+}
+
+func (m *MockSessionTypes) ListSessionTypes(ctx context.Context) ([]models.SessionType, error) {
+	return m.ListSessionTypesResponse, m.ListSessionTypesErr
+}
+
+func (m *MockSessionTypes) GetSessionType(ctx context.Context, id int64) (models.SessionType, error) {
+	return m.GetSessionTypeResponse, m.GetSessionTypeErr
+}
+
+func (m *MockSessionTypes) CreateSessionType(ctx context.Context, sessionType models.SessionTypeCreateRequest) (models.SessionType, error) {
+	return m.CreateSessionTypeResponse, m.CreateSessionTypeErr
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSessionTypesRepository_ListSessionTypes(t *testing.T) {
+	r := &SessionTypesRepoType{
+		db: DB,
+	}
+
+	got, err := r.ListSessionTypes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// seeded by the migration, ordered by duration
+	durations := make([]int, 0)
+	for _, s := range got {
+		durations = append(durations, s.DurationMinutes)
+	}
+
+	assert.Subset(t, durations, []int{30, 45, 60, 90})
+	for i := 1; i < len(durations); i++ {
+		assert.LessOrEqual(t, durations[i-1], durations[i])
+	}
+}
+
+func TestSessionTypesRepository_CreateAndGetSessionType(t *testing.T) {
+	r := &SessionTypesRepoType{
+		db: DB,
+	}
+
+	created, err := r.CreateSessionType(context.Background(), models.SessionTypeCreateRequest{
+		Name:                   "20 minute session",
+		DurationMinutes:        20,
+		SlotGranularityMinutes: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.GetSessionType(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "20 minute session", got.Name)
+	assert.Equal(t, 20, got.DurationMinutes)
+	assert.Equal(t, 10, got.SlotGranularityMinutes)
+
+	_, err = r.GetSessionType(context.Background(), -1)
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+}
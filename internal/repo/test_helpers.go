@@ -106,15 +106,56 @@ func findMigrationRoot() string {
 	return ""
 }
 
+// purgeTables are emptied before each test, their id sequences are restarted at 1
+// the waitlist, holds, appointments and appointment series reference trainers and users, so they have to be emptied first
+// and webhook deliveries reference the outbox
+var purgeTables = []string{
+	"scheduling.webhook_delivery_attempts",
+	"scheduling.webhook_deliveries",
+	"scheduling.webhooks",
+	"scheduling.outbox",
+	"scheduling.waitlist",
+	"scheduling.holds",
+	"scheduling.appointments",
+	"scheduling.appointment_series",
+	"scheduling.trainer_working_hours",
+	"scheduling.time_off",
+	"scheduling.users",
+	"scheduling.trainers",
+}
+
+// seedTestDataQuery adds the trainers and users the tests book appointments for, trainer 3 is inactive
+const seedTestDataQuery = `
+insert into scheduling.trainers (name, email, active)
+VALUES ('Trainer 1', 'trainer1@example.com', true),
+       ('Trainer 2', 'trainer2@example.com', true),
+       ('Trainer 3', 'trainer3@example.com', false);
+
+insert into scheduling.users (name, email)
+VALUES ('User 1', 'user1@example.com'),
+       ('User 2', 'user2@example.com'),
+       ('User 3', 'user3@example.com');
+`
+
 func PurgeTables() {
+	for _, table := range purgeTables {
+		table := table
+		withTimeout(time.Second*2, func() error {
+			if _, err := DB.Exec(fmt.Sprintf("delete from %s;", table)); err != nil {
+				return err
+			}
+			return nil
+		})
+		withTimeout(time.Second*2, func() error {
+			if _, err := DB.Exec(fmt.Sprintf("ALTER SEQUENCE %s_id_seq RESTART WITH 1;", table)); err != nil {
+				return err
+			}
+			return nil
+		})
+	}
+
 	withTimeout(time.Second*2, func() error {
-		if _, err := DB.Exec("delete from scheduling.appointments;"); err != nil {
-			return err
-		}
-		return nil
-	})
-	withTimeout(time.Second*2, func() error {
-		if _, err := DB.Exec("ALTER SEQUENCE scheduling.appointments_id_seq RESTART WITH 1;"); err != nil {
+		if _, err := DB.Exec(seedTestDataQuery); err != nil {
 			return err
 		}
 		return nil
@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"time"
+)
+
+type TimeOffRepository interface {
+	CreateTimeOff(ctx context.Context, timeOff models.TimeOffCreateRequest) (models.TimeOff, error)
+	ListTimeOff(ctx context.Context, filter models.TimeOffFilter) ([]models.TimeOff, error)
+	ListTimeOffForTrainer(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time) ([]models.TimeOff, error)
+	ListTimeOffForTrainers(ctx context.Context, trainerIDs []int64, startsAt time.Time, endsAt time.Time) ([]models.TimeOff, error)
+	DeleteTimeOff(ctx context.Context, id int64) error
+	ReplaceImportedTimeOff(ctx context.Context, trainerID int64, timeOff []models.TimeOffCreateRequest) ([]models.TimeOff, error)
+}
+
+type TimeOffRepoType struct {
+	db *sqlx.DB
+}
+
+func NewTimeOffRepository(db *sqlx.DB) TimeOffRepoType {
+	return TimeOffRepoType{
+		db: db,
+	}
+}
+
+const createTimeOffQuery = `
+insert into scheduling.time_off(trainer_id, starts_at, ends_at, reason)
+VALUES ($1, $2, $3, $4)
+returning id, trainer_id, starts_at, ends_at, reason, source, created_at
+`
+
+// trainer's own time off and gym-wide time off overlapping the time range
+const listTimeOffForTrainerQuery = `
+select id, trainer_id, starts_at, ends_at, reason, source, created_at
+from scheduling.time_off
+where (trainer_id = $1 or trainer_id is null) and starts_at < $3 and ends_at > $2
+order by starts_at
+`
+
+// every one of the trainers' time off and gym-wide time off overlapping the time range
+const listTimeOffForTrainersQuery = `
+select id, trainer_id, starts_at, ends_at, reason, source, created_at
+from scheduling.time_off
+where (trainer_id = any($1) or trainer_id is null) and starts_at < $3 and ends_at > $2
+order by starts_at
+`
+
+const createImportedTimeOffQuery = `
+insert into scheduling.time_off(trainer_id, starts_at, ends_at, reason, source)
+VALUES ($1, $2, $3, $4, 'ics')
+returning id, trainer_id, starts_at, ends_at, reason, source, created_at
+`
+
+const deleteImportedTimeOffQuery = `
+delete from scheduling.time_off where trainer_id = $1 and source = 'ics'
+`
+
+// lockTrainerRowQuery checks the trainer exists, locking its row so imports for the same trainer run one at a time
+// no key update doesn't block bookings, which only need the row to not be deleted
+const lockTrainerRowQuery = `
+select id from scheduling.trainers where id = $1 for no key update
+`
+
+const deleteTimeOffQuery = `
+delete from scheduling.time_off where id = $1
+`
+
+func (tr *TimeOffRepoType) CreateTimeOff(ctx context.Context, timeOff models.TimeOffCreateRequest) (models.TimeOff, error) {
+	var t models.TimeOff
+	err := tr.db.QueryRowxContext(ctx, createTimeOffQuery, timeOff.TrainerID, timeOff.StartsAt, timeOff.EndsAt, timeOff.Reason).StructScan(&t)
+	if err != nil {
+		return models.TimeOff{}, errors.Wrap(err, "error creating time off")
+	}
+
+	return t, nil
+}
+
+// ListTimeOff gets time off matching the filter, filtering on a trainer only returns that trainer's time off
+func (tr *TimeOffRepoType) ListTimeOff(ctx context.Context, filter models.TimeOffFilter) ([]models.TimeOff, error) {
+	query := sq.Select("id", "trainer_id", "starts_at", "ends_at", "reason", "source", "created_at").From("scheduling.time_off").PlaceholderFormat(sq.Dollar)
+	if filter.TrainerID != 0 {
+		query = query.Where(sq.Eq{"trainer_id": filter.TrainerID})
+	}
+
+	if !filter.StartsAt.IsZero() && !filter.EndsAt.IsZero() {
+		// anything overlapping the time range
+		query = query.Where(sq.And{sq.Lt{"starts_at": filter.EndsAt}, sq.Gt{"ends_at": filter.StartsAt}})
+	}
+
+	sql, args, err := query.OrderBy("starts_at").ToSql()
+	if err != nil {
+		return []models.TimeOff{}, errors.Wrap(err, "error getting time off")
+	}
+
+	timeOff := make([]models.TimeOff, 0)
+	err = tr.db.SelectContext(ctx, &timeOff, sql, args...)
+	if err != nil {
+		return []models.TimeOff{}, errors.Wrap(err, "error getting time off")
+	}
+
+	return timeOff, nil
+}
+
+// ListTimeOffForTrainer gets everything blocking the trainer during the time range, including gym-wide time off
+// a trainer ID of 0 only gets gym-wide time off
+func (tr *TimeOffRepoType) ListTimeOffForTrainer(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time) ([]models.TimeOff, error) {
+	timeOff := make([]models.TimeOff, 0)
+	err := tr.db.SelectContext(ctx, &timeOff, listTimeOffForTrainerQuery, trainerID, startsAt, endsAt)
+	if err != nil {
+		return []models.TimeOff{}, errors.Wrap(err, "error getting time off")
+	}
+
+	return timeOff, nil
+}
+
+// ListTimeOffForTrainers gets everything blocking any of the trainers during the time range in one query, gym-wide
+// time off has a nil trainer ID and applies to all of them
+func (tr *TimeOffRepoType) ListTimeOffForTrainers(ctx context.Context, trainerIDs []int64, startsAt time.Time, endsAt time.Time) ([]models.TimeOff, error) {
+	timeOff := make([]models.TimeOff, 0)
+	err := tr.db.SelectContext(ctx, &timeOff, listTimeOffForTrainersQuery, pq.Array(trainerIDs), startsAt, endsAt)
+	if err != nil {
+		return []models.TimeOff{}, errors.Wrap(err, "error getting time off")
+	}
+
+	return timeOff, nil
+}
+
+// DeleteTimeOff removes time off, returns sql.ErrNoRows when it doesn't exist
+func (tr *TimeOffRepoType) DeleteTimeOff(ctx context.Context, id int64) error {
+	res, err := tr.db.ExecContext(ctx, deleteTimeOffQuery, id)
+	if err != nil {
+		return errors.Wrap(err, "error deleting time off")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "error deleting time off")
+	}
+
+	if n == 0 {
+		return errors.Wrap(sql.ErrNoRows, "error deleting time off")
+	}
+
+	return nil
+}
+
+// ReplaceImportedTimeOff replaces the trainer's imported time off with timeOff in a single transaction, time off that
+// wasn't imported is kept. importing the same calendar again ends up with the same time off
+// returns sql.ErrNoRows when the trainer doesn't exist
+func (tr *TimeOffRepoType) ReplaceImportedTimeOff(ctx context.Context, trainerID int64, timeOff []models.TimeOffCreateRequest) ([]models.TimeOff, error) {
+	tx, err := tr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return []models.TimeOff{}, errors.Wrap(err, "error importing time off")
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowxContext(ctx, lockTrainerRowQuery, trainerID).Scan(&id)
+	if err != nil {
+		return []models.TimeOff{}, errors.Wrap(err, "error importing time off")
+	}
+
+	_, err = tx.ExecContext(ctx, deleteImportedTimeOffQuery, trainerID)
+	if err != nil {
+		return []models.TimeOff{}, errors.Wrap(err, "error importing time off")
+	}
+
+	imported := make([]models.TimeOff, 0, len(timeOff))
+	for _, to := range timeOff {
+		var t models.TimeOff
+		err = tx.QueryRowxContext(ctx, createImportedTimeOffQuery, trainerID, to.StartsAt, to.EndsAt, to.Reason).StructScan(&t)
+		if err != nil {
+			return []models.TimeOff{}, errors.Wrap(err, "error importing time off")
+		}
+
+		imported = append(imported, t)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []models.TimeOff{}, errors.Wrap(err, "error importing time off")
+	}
+
+	return imported, nil
+}
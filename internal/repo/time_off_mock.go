@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"time"
+)
+
+// MockTimeOff is an implementation of TimeOffRepository to set values to use as a mock when testing
+type MockTimeOff struct {
+	CreateTimeOffResponse models.TimeOff
+	CreateTimeOffErr      error
+
+	ListTimeOffResponse []models.TimeOff
+	ListTimeOffErr      error
+
+	ListTimeOffForTrainerResponse []models.TimeOff
+	ListTimeOffForTrainerErr      error
+
+	ListTimeOffForTrainersResponse []models.TimeOff
+	ListTimeOffForTrainersErr      error
+
+	DeleteTimeOffErr error
+
+	ReplaceImportedTimeOffResponse []models.TimeOff
+	ReplaceImportedTimeOffErr      error
+
+	// Imported is the time off passed to the last import
+	Imported []models.TimeOffCreateRequest
+}
+
+func (m *MockTimeOff) CreateTimeOff(ctx context.Context, timeOff models.TimeOffCreateRequest) (models.TimeOff, error) {
+	return m.CreateTimeOffResponse, m.CreateTimeOffErr
+}
+
+func (m *MockTimeOff) ListTimeOff(ctx context.Context, filter models.TimeOffFilter) ([]models.TimeOff, error) {
+	return m.ListTimeOffResponse, m.ListTimeOffErr
+}
+
+func (m *MockTimeOff) ListTimeOffForTrainer(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time) ([]models.TimeOff, error) {
+	return m.ListTimeOffForTrainerResponse, m.ListTimeOffForTrainerErr
+}
+
+func (m *MockTimeOff) ListTimeOffForTrainers(ctx context.Context, trainerIDs []int64, startsAt time.Time, endsAt time.Time) ([]models.TimeOff, error) {
+	return m.ListTimeOffForTrainersResponse, m.ListTimeOffForTrainersErr
+}
+
+func (m *MockTimeOff) DeleteTimeOff(ctx context.Context, id int64) error {
+	return m.DeleteTimeOffErr
+}
+
+func (m *MockTimeOff) ReplaceImportedTimeOff(ctx context.Context, trainerID int64, timeOff []models.TimeOffCreateRequest) ([]models.TimeOff, error) {
+	m.Imported = timeOff
+	return m.ReplaceImportedTimeOffResponse, m.ReplaceImportedTimeOffErr
+}
@@ -0,0 +1,295 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTimeOffRepository_ListTimeOffForTrainer(t *testing.T) {
+	trainerOne := int64(1)
+	trainerTwo := int64(2)
+
+	timeOff := []models.TimeOffCreateRequest{
+		{
+			TrainerID: &trainerOne,
+			StartsAt:  time.Date(2022, 03, 21, 7, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 26, 7, 0, 0, 0, time.UTC),
+			Reason:    "vacation",
+		},
+		{
+			TrainerID: &trainerTwo,
+			StartsAt:  time.Date(2022, 03, 22, 7, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 23, 7, 0, 0, 0, time.UTC),
+			Reason:    "sick",
+		},
+		{
+			StartsAt: time.Date(2022, 03, 25, 7, 0, 0, 0, time.UTC),
+			EndsAt:   time.Date(2022, 03, 26, 7, 0, 0, 0, time.UTC),
+			Reason:   "gym closed",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		trainerID int64
+		startsAt  time.Time
+		endsAt    time.Time
+		wantIDs   []int64
+	}{
+		{
+			name:      "trainer and gym-wide time off",
+			trainerID: 1,
+			startsAt:  time.Date(2022, 03, 20, 0, 0, 0, 0, time.UTC),
+			endsAt:    time.Date(2022, 03, 27, 0, 0, 0, 0, time.UTC),
+			wantIDs:   []int64{1, 3},
+		},
+		{
+			name:      "only overlapping time off",
+			trainerID: 2,
+			startsAt:  time.Date(2022, 03, 22, 12, 0, 0, 0, time.UTC),
+			endsAt:    time.Date(2022, 03, 22, 13, 0, 0, 0, time.UTC),
+			wantIDs:   []int64{2},
+		},
+		{
+			name:      "no trainer only gets gym-wide time off",
+			trainerID: 0,
+			startsAt:  time.Date(2022, 03, 20, 0, 0, 0, 0, time.UTC),
+			endsAt:    time.Date(2022, 03, 27, 0, 0, 0, 0, time.UTC),
+			wantIDs:   []int64{3},
+		},
+		{
+			name:      "touching the end isn't overlapping",
+			trainerID: 1,
+			startsAt:  time.Date(2022, 03, 26, 7, 0, 0, 0, time.UTC),
+			endsAt:    time.Date(2022, 03, 26, 8, 0, 0, 0, time.UTC),
+			wantIDs:   []int64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PurgeTables()
+
+			r := &TimeOffRepoType{
+				db: DB,
+			}
+
+			for _, to := range timeOff {
+				_, err := r.CreateTimeOff(context.Background(), to)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := r.ListTimeOffForTrainer(context.Background(), tt.trainerID, tt.startsAt, tt.endsAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotIDs := make([]int64, 0)
+			for _, to := range got {
+				gotIDs = append(gotIDs, to.ID)
+			}
+
+			assert.Equal(t, tt.wantIDs, gotIDs)
+		})
+	}
+}
+
+func TestTimeOffRepository_ListTimeOffForTrainers(t *testing.T) {
+	PurgeTables()
+
+	r := &TimeOffRepoType{
+		db: DB,
+	}
+
+	trainerOne := int64(1)
+	trainerTwo := int64(2)
+	trainerThree := int64(3)
+	for _, to := range []models.TimeOffCreateRequest{
+		{
+			TrainerID: &trainerOne,
+			StartsAt:  time.Date(2022, 03, 21, 7, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 26, 7, 0, 0, 0, time.UTC),
+			Reason:    "vacation",
+		},
+		{
+			TrainerID: &trainerTwo,
+			StartsAt:  time.Date(2022, 03, 22, 7, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 23, 7, 0, 0, 0, time.UTC),
+			Reason:    "sick",
+		},
+		{
+			TrainerID: &trainerThree,
+			StartsAt:  time.Date(2022, 03, 22, 7, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2022, 03, 23, 7, 0, 0, 0, time.UTC),
+			Reason:    "sick",
+		},
+		{
+			StartsAt: time.Date(2022, 03, 25, 7, 0, 0, 0, time.UTC),
+			EndsAt:   time.Date(2022, 03, 26, 7, 0, 0, 0, time.UTC),
+			Reason:   "gym closed",
+		},
+	} {
+		if _, err := r.CreateTimeOff(context.Background(), to); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// trainer 3 isn't asked for, gym-wide time off is always included
+	got, err := r.ListTimeOffForTrainers(context.Background(), []int64{1, 2}, time.Date(2022, 03, 20, 0, 0, 0, 0, time.UTC), time.Date(2022, 03, 27, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotIDs := make([]int64, 0)
+	for _, to := range got {
+		gotIDs = append(gotIDs, to.ID)
+	}
+
+	assert.Equal(t, []int64{1, 2, 4}, gotIDs)
+}
+
+func TestTimeOffRepository_ListAndDeleteTimeOff(t *testing.T) {
+	PurgeTables()
+
+	r := &TimeOffRepoType{
+		db: DB,
+	}
+
+	trainerID := int64(1)
+	created, err := r.CreateTimeOff(context.Background(), models.TimeOffCreateRequest{
+		TrainerID: &trainerID,
+		StartsAt:  time.Date(2022, 03, 21, 7, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2022, 03, 26, 7, 0, 0, 0, time.UTC),
+		Reason:    "vacation",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, &trainerID, created.TrainerID)
+	assert.Equal(t, "vacation", created.Reason)
+
+	got, err := r.ListTimeOff(context.Background(), models.TimeOffFilter{TrainerID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, got, 1)
+
+	err = r.DeleteTimeOff(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.DeleteTimeOff(context.Background(), created.ID)
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+}
+
+func TestTimeOffRepository_ReplaceImportedTimeOff(t *testing.T) {
+	PurgeTables()
+
+	r := &TimeOffRepoType{
+		db: DB,
+	}
+
+	trainerOne := int64(1)
+	trainerTwo := int64(2)
+	manual, err := r.CreateTimeOff(context.Background(), models.TimeOffCreateRequest{
+		TrainerID: &trainerOne,
+		StartsAt:  time.Date(2022, 03, 21, 7, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2022, 03, 26, 7, 0, 0, 0, time.UTC),
+		Reason:    "vacation",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, models.TimeOffSourceManual, manual.Source)
+
+	dentist := models.TimeOffCreateRequest{
+		StartsAt: time.Date(2022, 03, 17, 14, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2022, 03, 17, 15, 30, 0, 0, time.UTC),
+		Reason:   "busy",
+	}
+	run := models.TimeOffCreateRequest{
+		StartsAt: time.Date(2022, 03, 18, 15, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2022, 03, 18, 16, 15, 0, 0, time.UTC),
+		Reason:   "busy",
+	}
+
+	imported, err := r.ReplaceImportedTimeOff(context.Background(), trainerOne, []models.TimeOffCreateRequest{dentist, run})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, imported, 2) {
+		assert.Equal(t, &trainerOne, imported[0].TrainerID)
+		assert.Equal(t, models.TimeOffSourceICS, imported[0].Source)
+	}
+
+	_, err = r.ReplaceImportedTimeOff(context.Background(), trainerTwo, []models.TimeOffCreateRequest{dentist})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// importing again replaces the last import, twice is the same as once
+	for i := 0; i < 2; i++ {
+		_, err = r.ReplaceImportedTimeOff(context.Background(), trainerOne, []models.TimeOffCreateRequest{run})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := r.ListTimeOff(context.Background(), models.TimeOffFilter{TrainerID: trainerOne})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, got, 2) {
+		assert.True(t, run.StartsAt.Equal(got[0].StartsAt))
+		assert.Equal(t, models.TimeOffSourceICS, got[0].Source)
+		assert.Equal(t, manual.ID, got[1].ID)
+	}
+
+	// imported time off blocks the trainer like any other time off
+	blocking, err := r.ListTimeOffForTrainer(context.Background(), trainerOne, run.StartsAt, run.EndsAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, blocking, 1)
+
+	// the other trainer's import is kept
+	got, err = r.ListTimeOff(context.Background(), models.TimeOffFilter{TrainerID: trainerTwo})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, got, 1)
+
+	// an empty calendar removes the imported time off
+	imported, err = r.ReplaceImportedTimeOff(context.Background(), trainerOne, []models.TimeOffCreateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, imported, 0)
+
+	got, err = r.ListTimeOff(context.Background(), models.TimeOffFilter{TrainerID: trainerOne})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, manual.ID, got[0].ID)
+	}
+
+	_, err = r.ReplaceImportedTimeOff(context.Background(), 42, []models.TimeOffCreateRequest{run})
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+}
@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+type TrainersRepository interface {
+	ListTrainers(ctx context.Context, activeOnly bool) ([]models.Trainer, error)
+	GetTrainer(ctx context.Context, id int64) (models.Trainer, error)
+	CreateTrainer(ctx context.Context, trainer models.TrainerRequest) (models.Trainer, error)
+	UpdateTrainer(ctx context.Context, id int64, trainer models.TrainerRequest) (models.Trainer, error)
+	DeactivateTrainer(ctx context.Context, id int64) (models.Trainer, error)
+}
+
+type TrainersRepoType struct {
+	db *sqlx.DB
+}
+
+func NewTrainersRepository(db *sqlx.DB) TrainersRepoType {
+	return TrainersRepoType{
+		db: db,
+	}
+}
+
+const listTrainersQuery = `
+select id, name, email, active, created_at, updated_at
+from scheduling.trainers
+where active or not $1
+order by id
+`
+
+const getTrainerQuery = `
+select id, name, email, active, created_at, updated_at
+from scheduling.trainers
+where id = $1
+`
+
+const createTrainerQuery = `
+insert into scheduling.trainers(name, email, active)
+VALUES ($1, $2, $3)
+returning id, name, email, active, created_at, updated_at
+`
+
+const updateTrainerQuery = `
+update scheduling.trainers
+set name = $2, email = $3, active = $4, updated_at = now()
+where id = $1
+returning id, name, email, active, created_at, updated_at
+`
+
+const deactivateTrainerQuery = `
+update scheduling.trainers
+set active = false, updated_at = now()
+where id = $1
+returning id, name, email, active, created_at, updated_at
+`
+
+func (tr *TrainersRepoType) ListTrainers(ctx context.Context, activeOnly bool) ([]models.Trainer, error) {
+	trainers := make([]models.Trainer, 0)
+	err := tr.db.SelectContext(ctx, &trainers, listTrainersQuery, activeOnly)
+	if err != nil {
+		return []models.Trainer{}, errors.Wrap(err, "error getting trainers")
+	}
+
+	return trainers, nil
+}
+
+// GetTrainer gets a single trainer, active or not. returns sql.ErrNoRows when it doesn't exist
+func (tr *TrainersRepoType) GetTrainer(ctx context.Context, id int64) (models.Trainer, error) {
+	var t models.Trainer
+	err := tr.db.QueryRowxContext(ctx, getTrainerQuery, id).StructScan(&t)
+	if err != nil {
+		return models.Trainer{}, errors.Wrap(err, "error getting trainer")
+	}
+
+	return t, nil
+}
+
+func (tr *TrainersRepoType) CreateTrainer(ctx context.Context, trainer models.TrainerRequest) (models.Trainer, error) {
+	var t models.Trainer
+	err := tr.db.QueryRowxContext(ctx, createTrainerQuery, trainer.Name, trainer.Email, trainer.Active).StructScan(&t)
+	if isConstraintViolation(err, uniqueViolation, trainersEmailIndex) {
+		return models.Trainer{}, ErrEmailTaken
+	}
+
+	if err != nil {
+		return models.Trainer{}, errors.Wrap(err, "error creating trainer")
+	}
+
+	return t, nil
+}
+
+func (tr *TrainersRepoType) UpdateTrainer(ctx context.Context, id int64, trainer models.TrainerRequest) (models.Trainer, error) {
+	var t models.Trainer
+	err := tr.db.QueryRowxContext(ctx, updateTrainerQuery, id, trainer.Name, trainer.Email, trainer.Active).StructScan(&t)
+	if isConstraintViolation(err, uniqueViolation, trainersEmailIndex) {
+		return models.Trainer{}, ErrEmailTaken
+	}
+
+	if err != nil {
+		return models.Trainer{}, errors.Wrap(err, "error updating trainer")
+	}
+
+	return t, nil
+}
+
+// DeactivateTrainer soft deletes a trainer, their appointments are kept but they can't be booked anymore
+func (tr *TrainersRepoType) DeactivateTrainer(ctx context.Context, id int64) (models.Trainer, error) {
+	var t models.Trainer
+	err := tr.db.QueryRowxContext(ctx, deactivateTrainerQuery, id).StructScan(&t)
+	if err != nil {
+		return models.Trainer{}, errors.Wrap(err, "error deactivating trainer")
+	}
+
+	return t, nil
+}
@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+// MockTrainers is an implementation of TrainersRepository to set values to use as a mock when testing
+type MockTrainers struct {
+	ListTrainersResponse []models.Trainer
+	ListTrainersErr      error
+
+	GetTrainerResponse models.Trainer
+	GetTrainerErr      error
+
+	CreateTrainerResponse models.Trainer
+	CreateTrainerErr      error
+
+	UpdateTrainerResponse models.Trainer
+	UpdateTrainerErr      error
+
+	DeactivateTrainerResponse models.Trainer
+	DeactivateTrainerErr      error
+}
+
+func (m *MockTrainers) ListTrainers(ctx context.Context, activeOnly bool) ([]models.Trainer, error) {
+	return m.ListTrainersResponse, m.ListTrainersErr
+}
+
+func (m *MockTrainers) GetTrainer(ctx context.Context, id int64) (models.Trainer, error) {
+	return m.GetTrainerResponse, m.GetTrainerErr
+}
+
+func (m *MockTrainers) CreateTrainer(ctx context.Context, trainer models.TrainerRequest) (models.Trainer, error) {
+	return m.CreateTrainerResponse, m.CreateTrainerErr
+}
+
+func (m *MockTrainers) UpdateTrainer(ctx context.Context, id int64, trainer models.TrainerRequest) (models.Trainer, error) {
+	return m.UpdateTrainerResponse, m.UpdateTrainerErr
+}
+
+func (m *MockTrainers) DeactivateTrainer(ctx context.Context, id int64) (models.Trainer, error) {
+	return m.DeactivateTrainerResponse, m.DeactivateTrainerErr
+}
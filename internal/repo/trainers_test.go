@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTrainersRepository_ListTrainers(t *testing.T) {
+	PurgeTables()
+
+	r := &TrainersRepoType{
+		db: DB,
+	}
+
+	tests := []struct {
+		name       string
+		activeOnly bool
+		wantIDs    []int64
+	}{
+		{
+			name:       "all trainers",
+			activeOnly: false,
+			wantIDs:    []int64{1, 2, 3},
+		},
+		{
+			name:       "active trainers",
+			activeOnly: true,
+			wantIDs:    []int64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.ListTrainers(context.Background(), tt.activeOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotIDs := make([]int64, 0)
+			for _, trainer := range got {
+				gotIDs = append(gotIDs, trainer.ID)
+			}
+
+			assert.Equal(t, tt.wantIDs, gotIDs)
+		})
+	}
+}
+
+func TestTrainersRepository_CreateUpdateDeactivateTrainer(t *testing.T) {
+	PurgeTables()
+
+	r := &TrainersRepoType{
+		db: DB,
+	}
+
+	active := true
+	created, err := r.CreateTrainer(context.Background(), models.TrainerRequest{Name: "Jane Doe", Email: "jane@example.com", Active: &active})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(4), created.ID)
+	assert.True(t, created.Active)
+
+	// emails are unique ignoring case
+	_, err = r.CreateTrainer(context.Background(), models.TrainerRequest{Name: "Jane Smith", Email: "JANE@example.com", Active: &active})
+	assert.Equal(t, ErrEmailTaken, errors.Cause(err))
+
+	updated, err := r.UpdateTrainer(context.Background(), created.ID, models.TrainerRequest{Name: "Jane Smith", Email: "jane.smith@example.com", Active: &active})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Jane Smith", updated.Name)
+	assert.Equal(t, "jane.smith@example.com", updated.Email)
+
+	_, err = r.UpdateTrainer(context.Background(), created.ID, models.TrainerRequest{Name: "Jane Smith", Email: "trainer1@example.com", Active: &active})
+	assert.Equal(t, ErrEmailTaken, errors.Cause(err))
+
+	deactivated, err := r.DeactivateTrainer(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, deactivated.Active)
+
+	got, err := r.GetTrainer(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, got.Active)
+
+	_, err = r.GetTrainer(context.Background(), 100)
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+
+	_, err = r.DeactivateTrainer(context.Background(), 100)
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+}
@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+type UsersRepository interface {
+	ListUsers(ctx context.Context, activeOnly bool) ([]models.User, error)
+	GetUser(ctx context.Context, id int64) (models.User, error)
+	CreateUser(ctx context.Context, user models.UserRequest) (models.User, error)
+	UpdateUser(ctx context.Context, id int64, user models.UserRequest) (models.User, error)
+	DeactivateUser(ctx context.Context, id int64) (models.User, error)
+}
+
+type UsersRepoType struct {
+	db *sqlx.DB
+}
+
+func NewUsersRepository(db *sqlx.DB) UsersRepoType {
+	return UsersRepoType{
+		db: db,
+	}
+}
+
+const listUsersQuery = `
+select id, name, email, active, created_at, updated_at
+from scheduling.users
+where active or not $1
+order by id
+`
+
+const getUserQuery = `
+select id, name, email, active, created_at, updated_at
+from scheduling.users
+where id = $1
+`
+
+const createUserQuery = `
+insert into scheduling.users(name, email, active)
+VALUES ($1, $2, $3)
+returning id, name, email, active, created_at, updated_at
+`
+
+const updateUserQuery = `
+update scheduling.users
+set name = $2, email = $3, active = $4, updated_at = now()
+where id = $1
+returning id, name, email, active, created_at, updated_at
+`
+
+const deactivateUserQuery = `
+update scheduling.users
+set active = false, updated_at = now()
+where id = $1
+returning id, name, email, active, created_at, updated_at
+`
+
+func (ur *UsersRepoType) ListUsers(ctx context.Context, activeOnly bool) ([]models.User, error) {
+	users := make([]models.User, 0)
+	err := ur.db.SelectContext(ctx, &users, listUsersQuery, activeOnly)
+	if err != nil {
+		return []models.User{}, errors.Wrap(err, "error getting users")
+	}
+
+	return users, nil
+}
+
+// GetUser gets a single user, active or not. returns sql.ErrNoRows when it doesn't exist
+func (ur *UsersRepoType) GetUser(ctx context.Context, id int64) (models.User, error) {
+	var u models.User
+	err := ur.db.QueryRowxContext(ctx, getUserQuery, id).StructScan(&u)
+	if err != nil {
+		return models.User{}, errors.Wrap(err, "error getting user")
+	}
+
+	return u, nil
+}
+
+func (ur *UsersRepoType) CreateUser(ctx context.Context, user models.UserRequest) (models.User, error) {
+	var u models.User
+	err := ur.db.QueryRowxContext(ctx, createUserQuery, user.Name, user.Email, user.Active).StructScan(&u)
+	if isConstraintViolation(err, uniqueViolation, usersEmailIndex) {
+		return models.User{}, ErrEmailTaken
+	}
+
+	if err != nil {
+		return models.User{}, errors.Wrap(err, "error creating user")
+	}
+
+	return u, nil
+}
+
+func (ur *UsersRepoType) UpdateUser(ctx context.Context, id int64, user models.UserRequest) (models.User, error) {
+	var u models.User
+	err := ur.db.QueryRowxContext(ctx, updateUserQuery, id, user.Name, user.Email, user.Active).StructScan(&u)
+	if isConstraintViolation(err, uniqueViolation, usersEmailIndex) {
+		return models.User{}, ErrEmailTaken
+	}
+
+	if err != nil {
+		return models.User{}, errors.Wrap(err, "error updating user")
+	}
+
+	return u, nil
+}
+
+// DeactivateUser soft deletes a user, their appointments are kept
+func (ur *UsersRepoType) DeactivateUser(ctx context.Context, id int64) (models.User, error) {
+	var u models.User
+	err := ur.db.QueryRowxContext(ctx, deactivateUserQuery, id).StructScan(&u)
+	if err != nil {
+		return models.User{}, errors.Wrap(err, "error deactivating user")
+	}
+
+	return u, nil
+}
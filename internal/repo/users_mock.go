@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+// MockUsers is an implementation of UsersRepository to set values to use as a mock when testing
+type MockUsers struct {
+	ListUsersResponse []models.User
+	ListUsersErr      error
+
+	GetUserResponse models.User
+	GetUserErr      error
+
+	CreateUserResponse models.User
+	CreateUserErr      error
+
+	UpdateUserResponse models.User
+	UpdateUserErr      error
+
+	DeactivateUserResponse models.User
+	DeactivateUserErr      error
+}
+
+func (m *MockUsers) ListUsers(ctx context.Context, activeOnly bool) ([]models.User, error) {
+	return m.ListUsersResponse, m.ListUsersErr
+}
+
+func (m *MockUsers) GetUser(ctx context.Context, id int64) (models.User, error) {
+	return m.GetUserResponse, m.GetUserErr
+}
+
+func (m *MockUsers) CreateUser(ctx context.Context, user models.UserRequest) (models.User, error) {
+	return m.CreateUserResponse, m.CreateUserErr
+}
+
+func (m *MockUsers) UpdateUser(ctx context.Context, id int64, user models.UserRequest) (models.User, error) {
+	return m.UpdateUserResponse, m.UpdateUserErr
+}
+
+func (m *MockUsers) DeactivateUser(ctx context.Context, id int64) (models.User, error) {
+	return m.DeactivateUserResponse, m.DeactivateUserErr
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUsersRepository_CreateUpdateDeactivateUser(t *testing.T) {
+	PurgeTables()
+
+	r := &UsersRepoType{
+		db: DB,
+	}
+
+	active := true
+	created, err := r.CreateUser(context.Background(), models.UserRequest{Name: "John Doe", Email: "john@example.com", Active: &active})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(4), created.ID)
+
+	_, err = r.CreateUser(context.Background(), models.UserRequest{Name: "John Smith", Email: "user1@example.com", Active: &active})
+	assert.Equal(t, ErrEmailTaken, errors.Cause(err))
+
+	inactive := false
+	updated, err := r.UpdateUser(context.Background(), created.ID, models.UserRequest{Name: "John Smith", Email: "john@example.com", Active: &inactive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "John Smith", updated.Name)
+	assert.False(t, updated.Active)
+
+	got, err := r.ListUsers(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, got, 3)
+
+	_, err = r.UpdateUser(context.Background(), 100, models.UserRequest{Name: "Nobody", Email: "nobody@example.com", Active: &active})
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+}
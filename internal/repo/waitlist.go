@@ -0,0 +1,227 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"time"
+)
+
+type WaitlistRepository interface {
+	JoinWaitlist(ctx context.Context, newEntry models.WaitlistCreateRequest) (models.WaitlistEntry, error)
+	GetWaitlistEntry(ctx context.Context, id int64) (models.WaitlistEntry, error)
+	LeaveWaitlist(ctx context.Context, id int64) (models.WaitlistEntry, error)
+	OfferFreedSlot(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time, ttl time.Duration) ([]models.WaitlistOffer, error)
+	ListUnofferedFreedSlots(ctx context.Context) ([]models.TimeSlot, error)
+}
+
+type WaitlistRepoType struct {
+	db *sqlx.DB
+}
+
+func NewWaitlistRepository(db *sqlx.DB) WaitlistRepoType {
+	return WaitlistRepoType{
+		db: db,
+	}
+}
+
+const joinWaitlistQuery = `
+insert into scheduling.waitlist(trainer_id, user_id, starts_at, ends_at, session_type_id)
+VALUES ($1, $2, $3, $4, nullif($5, 0))
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, status, hold_id, created_at
+`
+
+// JoinWaitlist adds the user to the end of the waitlist for the time slot, returns ErrAlreadyWaitlisted when they're already on it
+func (wr *WaitlistRepoType) JoinWaitlist(ctx context.Context, newEntry models.WaitlistCreateRequest) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	err := wr.db.QueryRowxContext(ctx, joinWaitlistQuery, newEntry.TrainerID, newEntry.UserID, newEntry.StartsAt, newEntry.EndsAt,
+		newEntry.SessionTypeID).StructScan(&e)
+
+	if isConstraintViolation(err, uniqueViolation, waitlistUserSlotIndex) {
+		return models.WaitlistEntry{}, ErrAlreadyWaitlisted
+	}
+
+	if isConstraintViolation(err, foreignKeyViolation, waitlistTrainerFKey) {
+		return models.WaitlistEntry{}, ErrUnknownTrainer
+	}
+
+	if isConstraintViolation(err, foreignKeyViolation, waitlistUserFKey) {
+		return models.WaitlistEntry{}, ErrUnknownUser
+	}
+
+	if err != nil {
+		return models.WaitlistEntry{}, errors.Wrap(err, "error joining waitlist")
+	}
+
+	return e, nil
+}
+
+const getWaitlistEntryQuery = `
+select id, trainer_id, user_id, starts_at, ends_at, session_type_id, status, hold_id, created_at
+from scheduling.waitlist
+where id = $1
+`
+
+// GetWaitlistEntry gets a single waitlist entry, returns sql.ErrNoRows when it doesn't exist
+func (wr *WaitlistRepoType) GetWaitlistEntry(ctx context.Context, id int64) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	err := wr.db.QueryRowxContext(ctx, getWaitlistEntryQuery, id).StructScan(&e)
+	if err != nil {
+		return models.WaitlistEntry{}, errors.Wrap(err, "error getting waitlist entry")
+	}
+
+	return e, nil
+}
+
+const leaveWaitlistQuery = `
+update scheduling.waitlist
+set status = 'removed'
+where id = $1 and status = 'waiting'
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, status, hold_id, created_at
+`
+
+// LeaveWaitlist removes the user from the waitlist, returns ErrNotWaiting when the entry isn't waiting anymore
+func (wr *WaitlistRepoType) LeaveWaitlist(ctx context.Context, id int64) (models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	err := wr.db.QueryRowxContext(ctx, leaveWaitlistQuery, id).StructScan(&e)
+	if err == nil {
+		return e, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return models.WaitlistEntry{}, errors.Wrap(err, "error leaving waitlist")
+	}
+
+	// nothing updated, either the entry doesn't exist or it isn't waiting
+	_, err = wr.GetWaitlistEntry(ctx, id)
+	if err != nil {
+		return models.WaitlistEntry{}, err
+	}
+
+	return models.WaitlistEntry{}, ErrNotWaiting
+}
+
+// nextWaitingQuery gets the entry waiting the longest for a time slot within the freed time that is free to book,
+// hasn't started yet and doesn't overlap an active appointment or an open hold. ties on created_at go to the lower id
+const nextWaitingQuery = `
+select w.id, w.trainer_id, w.user_id, w.starts_at, w.ends_at, w.session_type_id, w.status, w.hold_id, w.created_at
+from scheduling.waitlist w
+where w.trainer_id = $1 and w.status = 'waiting' and w.starts_at >= $2 and w.ends_at <= $3 and w.starts_at > now()
+  and not exists(
+    select 1 from scheduling.appointments a
+    where a.trainer_id = w.trainer_id and a.canceled_at is null and a.starts_at < w.ends_at and a.ends_at > w.starts_at
+  )
+  and not exists(
+    select 1 from scheduling.holds h
+    where h.trainer_id = w.trainer_id and h.starts_at < w.ends_at and h.ends_at > w.starts_at
+      and h.released_at is null and h.appointment_id is null and h.expires_at > now()
+  )
+order by w.created_at, w.id
+limit 1
+`
+
+const offerWaitlistEntryQuery = `
+update scheduling.waitlist
+set status = 'offered', hold_id = $2
+where id = $1
+returning id, trainer_id, user_id, starts_at, ends_at, session_type_id, status, hold_id, created_at
+`
+
+// OfferFreedSlot offers the freed time to the waitlist, in one transaction. the entry waiting the longest gets a hold for
+// ttl, then the next entry that doesn't overlap it, until there are no free time slots anyone is waiting for
+// the trainer is locked first, so two cancellations at once can't offer the same time slot twice. each offer's
+// waitlist.offered event is saved to the outbox in the same transaction
+func (wr *WaitlistRepoType) OfferFreedSlot(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time, ttl time.Duration) ([]models.WaitlistOffer, error) {
+	tx, err := wr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return []models.WaitlistOffer{}, errors.Wrap(err, "error offering freed time slot")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, lockTrainerQuery, trainerLockNamespace, trainerID)
+	if err != nil {
+		return []models.WaitlistOffer{}, errors.Wrap(err, "error locking trainer")
+	}
+
+	offers := make([]models.WaitlistOffer, 0)
+	for {
+		var e models.WaitlistEntry
+		err = tx.QueryRowxContext(ctx, nextWaitingQuery, trainerID, startsAt, endsAt).StructScan(&e)
+		if err == sql.ErrNoRows {
+			break
+		}
+
+		if err != nil {
+			return []models.WaitlistOffer{}, errors.Wrap(err, "error offering freed time slot")
+		}
+
+		var sessionTypeID int64
+		if e.SessionTypeID != nil {
+			sessionTypeID = *e.SessionTypeID
+		}
+
+		var h models.Hold
+		err = tx.QueryRowxContext(ctx, createHoldQuery, e.TrainerID, e.UserID, e.StartsAt, e.EndsAt, sessionTypeID, ttl.Seconds()).StructScan(&h)
+		if err != nil {
+			return []models.WaitlistOffer{}, errors.Wrap(err, "error offering freed time slot")
+		}
+
+		err = tx.QueryRowxContext(ctx, offerWaitlistEntryQuery, e.ID, h.ID).StructScan(&e)
+		if err != nil {
+			return []models.WaitlistOffer{}, errors.Wrap(err, "error offering freed time slot")
+		}
+
+		offer := models.WaitlistOffer{Entry: e, Hold: h}
+		err = addEvent(ctx, tx, events.NewWaitlistOfferedEvent(offer))
+		if err != nil {
+			return []models.WaitlistOffer{}, err
+		}
+
+		offers = append(offers, offer)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []models.WaitlistOffer{}, errors.Wrap(err, "error offering freed time slot")
+	}
+
+	return offers, nil
+}
+
+// listUnofferedFreedSlotsQuery gets the canceled appointments that haven't started yet with an entry waiting for a
+// time slot within them that nextWaitingQuery would offer, their offer failed or hasn't happened yet. it runs on every
+// sweep, appointments_canceled_starts_at keeps it to the canceled appointments that haven't started
+const listUnofferedFreedSlotsQuery = `
+select distinct a.trainer_id, a.starts_at, a.ends_at
+from scheduling.appointments a
+where a.canceled_at is not null and a.starts_at > now()
+  and exists(
+    select 1 from scheduling.waitlist w
+    where w.trainer_id = a.trainer_id and w.status = 'waiting' and w.starts_at >= a.starts_at and w.ends_at <= a.ends_at
+      and w.starts_at > now()
+      and not exists(
+        select 1 from scheduling.appointments b
+        where b.trainer_id = w.trainer_id and b.canceled_at is null and b.starts_at < w.ends_at and b.ends_at > w.starts_at
+      )
+      and not exists(
+        select 1 from scheduling.holds h
+        where h.trainer_id = w.trainer_id and h.starts_at < w.ends_at and h.ends_at > w.starts_at
+          and h.released_at is null and h.appointment_id is null and h.expires_at > now()
+      )
+  )
+order by a.starts_at, a.trainer_id
+`
+
+// ListUnofferedFreedSlots gets the time freed by canceled appointments that still has to be offered to the waitlist.
+// offering a canceled appointment's time happens after the cancel commits, this finds it again when that failed
+func (wr *WaitlistRepoType) ListUnofferedFreedSlots(ctx context.Context) ([]models.TimeSlot, error) {
+	slots := make([]models.TimeSlot, 0)
+	err := wr.db.SelectContext(ctx, &slots, listUnofferedFreedSlotsQuery)
+	if err != nil {
+		return []models.TimeSlot{}, errors.Wrap(err, "error listing unoffered freed time slots")
+	}
+
+	return slots, nil
+}
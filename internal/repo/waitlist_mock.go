@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"time"
+)
+
+// MockWaitlist is an implementation of WaitlistRepository to set values to use as a mock when testing
+type MockWaitlist struct {
+	JoinWaitlistResponse models.WaitlistEntry
+	JoinWaitlistErr      error
+
+	GetWaitlistEntryResponse models.WaitlistEntry
+	GetWaitlistEntryErr      error
+
+	LeaveWaitlistResponse models.WaitlistEntry
+	LeaveWaitlistErr      error
+
+	OfferFreedSlotResponse []models.WaitlistOffer
+	OfferFreedSlotErr      error
+
+	ListUnofferedFreedSlotsResponse []models.TimeSlot
+	ListUnofferedFreedSlotsErr      error
+
+	// Offered is every time slot OfferFreedSlot was called with, in order
+	Offered []models.TimeSlot
+}
+
+func (m *MockWaitlist) JoinWaitlist(ctx context.Context, newEntry models.WaitlistCreateRequest) (models.WaitlistEntry, error) {
+	return m.JoinWaitlistResponse, m.JoinWaitlistErr
+}
+
+func (m *MockWaitlist) GetWaitlistEntry(ctx context.Context, id int64) (models.WaitlistEntry, error) {
+	return m.GetWaitlistEntryResponse, m.GetWaitlistEntryErr
+}
+
+func (m *MockWaitlist) LeaveWaitlist(ctx context.Context, id int64) (models.WaitlistEntry, error) {
+	return m.LeaveWaitlistResponse, m.LeaveWaitlistErr
+}
+
+func (m *MockWaitlist) OfferFreedSlot(ctx context.Context, trainerID int64, startsAt time.Time, endsAt time.Time, ttl time.Duration) ([]models.WaitlistOffer, error) {
+	m.Offered = append(m.Offered, models.TimeSlot{TrainerID: trainerID, StartsAt: startsAt, EndsAt: endsAt})
+	return m.OfferFreedSlotResponse, m.OfferFreedSlotErr
+}
+
+func (m *MockWaitlist) ListUnofferedFreedSlots(ctx context.Context) ([]models.TimeSlot, error) {
+	return m.ListUnofferedFreedSlotsResponse, m.ListUnofferedFreedSlotsErr
+}
@@ -0,0 +1,303 @@
+package repo
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitlistRequest(trainerID int64, userID int64, startsAt time.Time) models.WaitlistCreateRequest {
+	return models.WaitlistCreateRequest{
+		TrainerID: trainerID,
+		UserID:    userID,
+		StartsAt:  startsAt,
+		EndsAt:    startsAt.Add(30 * time.Minute),
+	}
+}
+
+// futureSlot is a time slot that hasn't started yet, waitlist entries are only offered until their time slot starts
+func futureSlot() time.Time {
+	return time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+}
+
+func TestWaitlistRepository_JoinWaitlist(t *testing.T) {
+	PurgeTables()
+
+	wr := &WaitlistRepoType{db: DB}
+
+	startsAt := futureSlot()
+	entry, err := wr.JoinWaitlist(context.Background(), waitlistRequest(1, 2, startsAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, models.WaitlistWaiting, entry.Status)
+
+	_, err = wr.JoinWaitlist(context.Background(), waitlistRequest(1, 2, startsAt))
+	assert.Equal(t, ErrAlreadyWaitlisted, errors.Cause(err))
+
+	_, err = wr.JoinWaitlist(context.Background(), waitlistRequest(1, 100, startsAt))
+	assert.Equal(t, ErrUnknownUser, errors.Cause(err))
+
+	left, err := wr.LeaveWaitlist(context.Background(), entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, models.WaitlistRemoved, left.Status)
+
+	_, err = wr.LeaveWaitlist(context.Background(), entry.ID)
+	assert.Equal(t, ErrNotWaiting, errors.Cause(err))
+
+	// once removed the user can wait for the time slot again
+	_, err = wr.JoinWaitlist(context.Background(), waitlistRequest(1, 2, startsAt))
+	assert.Nil(t, err)
+}
+
+func TestWaitlistRepository_OfferFreedSlot(t *testing.T) {
+	PurgeTables()
+
+	ar := &AppointmentsRepoType{db: DB}
+	hr := &HoldsRepoType{db: DB}
+	wr := &WaitlistRepoType{db: DB}
+
+	startsAt := futureSlot()
+	booked, err := ar.CreateAppointment(context.Background(), waitlistRequest(1, 1, startsAt).AppointmentCreateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// users 3 then 2 join, user 3 has waited longest
+	first, err := wr.JoinWaitlist(context.Background(), waitlistRequest(1, 3, startsAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := wr.JoinWaitlist(context.Background(), waitlistRequest(1, 2, startsAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// still booked, nothing to offer
+	offers, err := wr.OfferFreedSlot(context.Background(), 1, booked.StartsAt, booked.EndsAt, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, offers, 0)
+
+	_, err = ar.CancelAppointment(context.Background(), booked.ID, booked.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offers, err = wr.OfferFreedSlot(context.Background(), 1, booked.StartsAt, booked.EndsAt, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, offers, 1)
+	assert.Equal(t, first.ID, offers[0].Entry.ID)
+	assert.Equal(t, models.WaitlistOffered, offers[0].Entry.Status)
+	assert.Equal(t, offers[0].Hold.ID, *offers[0].Entry.HoldID)
+	assert.Equal(t, int64(3), offers[0].Hold.UserID)
+
+	// the offer holds the time slot, so the next user has to wait for it to be released
+	_, err = ar.CreateAppointment(context.Background(), waitlistRequest(1, 2, startsAt).AppointmentCreateRequest())
+	assert.True(t, errors.As(err, &SlotTakenError{}))
+
+	_, err = hr.ReleaseHold(context.Background(), offers[0].Hold.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offers, err = wr.OfferFreedSlot(context.Background(), 1, booked.StartsAt, booked.EndsAt, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, offers, 1)
+	assert.Equal(t, second.ID, offers[0].Entry.ID)
+
+	// the user the offer was for can confirm it
+	appointment, err := hr.ConfirmHold(context.Background(), offers[0].Hold.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(2), appointment.UserID)
+}
+
+func TestWaitlistRepository_OfferFreedSlotFillsTheFreedTime(t *testing.T) {
+	PurgeTables()
+
+	ar := &AppointmentsRepoType{db: DB}
+	wr := &WaitlistRepoType{db: DB}
+
+	// an hour appointment frees two half hour time slots, each is offered to the user waiting for it
+	startsAt := futureSlot()
+	booked, err := ar.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+		TrainerID: 1,
+		UserID:    1,
+		StartsAt:  startsAt,
+		EndsAt:    startsAt.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []models.WaitlistCreateRequest{
+		waitlistRequest(1, 2, startsAt.Add(30*time.Minute)),
+		waitlistRequest(1, 3, startsAt),
+		waitlistRequest(1, 3, startsAt.Add(time.Hour)), // not part of the freed time
+	} {
+		_, err = wr.JoinWaitlist(context.Background(), entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = ar.CancelAppointment(context.Background(), booked.ID, booked.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offers, err := wr.OfferFreedSlot(context.Background(), 1, booked.StartsAt, booked.EndsAt, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, offers, 2)
+	assert.Equal(t, int64(2), offers[0].Hold.UserID)
+	assert.Equal(t, int64(3), offers[1].Hold.UserID)
+	assert.Equal(t, startsAt, offers[1].Hold.StartsAt.UTC())
+}
+
+func TestWaitlistRepository_OfferFreedSlotConcurrently(t *testing.T) {
+	PurgeTables()
+
+	wr := &WaitlistRepoType{db: DB}
+
+	startsAt := futureSlot()
+	for _, userID := range []int64{2, 3} {
+		_, err := wr.JoinWaitlist(context.Background(), waitlistRequest(1, userID, startsAt))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the same time slot freed twice at once, ex. a double submitted cancel, is only offered once
+	var wg sync.WaitGroup
+	results := make([][]models.WaitlistOffer, 5)
+	errs := make([]error, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = wr.OfferFreedSlot(context.Background(), 1, startsAt, startsAt.Add(30*time.Minute), 10*time.Minute)
+		}(i)
+	}
+
+	wg.Wait()
+
+	offers := make([]models.WaitlistOffer, 0)
+	for i := range results {
+		assert.Nil(t, errs[i])
+		offers = append(offers, results[i]...)
+	}
+
+	assert.Len(t, offers, 1)
+	assert.Equal(t, int64(2), offers[0].Hold.UserID)
+}
+
+func TestWaitlistRepository_OfferFreedSlotSkipsStartedSlots(t *testing.T) {
+	PurgeTables()
+
+	wr := &WaitlistRepoType{db: DB}
+
+	// time that already started can't be booked anymore, so it isn't offered
+	startsAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Hour)
+	_, err := wr.JoinWaitlist(context.Background(), waitlistRequest(1, 2, startsAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offers, err := wr.OfferFreedSlot(context.Background(), 1, startsAt, startsAt.Add(30*time.Minute), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, offers, 0)
+}
+
+func TestWaitlistRepository_ListUnofferedFreedSlots(t *testing.T) {
+	PurgeTables()
+
+	ar := &AppointmentsRepoType{db: DB}
+	wr := &WaitlistRepoType{db: DB}
+
+	startsAt := futureSlot()
+	booked, err := ar.CreateAppointment(context.Background(), waitlistRequest(1, 1, startsAt).AppointmentCreateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// canceled without anyone waiting, nothing to offer
+	other, err := ar.CreateAppointment(context.Background(), waitlistRequest(1, 1, startsAt.Add(time.Hour)).AppointmentCreateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ar.CancelAppointment(context.Background(), other.ID, other.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wr.JoinWaitlist(context.Background(), waitlistRequest(1, 2, startsAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// still booked, nothing to offer
+	slots, err := wr.ListUnofferedFreedSlots(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, slots, 0)
+
+	// canceled, but the offer after it never happened
+	_, err = ar.CancelAppointment(context.Background(), booked.ID, booked.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err = wr.ListUnofferedFreedSlots(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, slots, 1)
+	assert.Equal(t, int64(1), slots[0].TrainerID)
+	assert.True(t, booked.StartsAt.Equal(slots[0].StartsAt))
+	assert.True(t, booked.EndsAt.Equal(slots[0].EndsAt))
+
+	offers, err := wr.OfferFreedSlot(context.Background(), slots[0].TrainerID, slots[0].StartsAt, slots[0].EndsAt, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, offers, 1)
+
+	// offered, nothing left
+	slots, err = wr.ListUnofferedFreedSlots(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, slots, 0)
+}
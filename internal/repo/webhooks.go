@@ -0,0 +1,257 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"sort"
+)
+
+type WebhooksRepository interface {
+	CreateWebhook(ctx context.Context, newWebhook models.WebhookCreateRequest) (models.Webhook, error)
+	GetWebhook(ctx context.Context, id int64) (models.Webhook, error)
+	DeactivateWebhook(ctx context.Context, id int64) (models.Webhook, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]models.WebhookDelivery, error)
+	EnqueueWebhookDeliveries(ctx context.Context, event events.Event) (int64, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDispatch, error)
+	RecordWebhookDeliveryAttempt(ctx context.Context, deliveryID int64, attempt models.WebhookDeliveryAttempt, maxAttempts int) (models.WebhookDelivery, error)
+}
+
+type WebhooksRepoType struct {
+	db *sqlx.DB
+}
+
+func NewWebhooksRepository(db *sqlx.DB) WebhooksRepoType {
+	return WebhooksRepoType{
+		db: db,
+	}
+}
+
+const createWebhookQuery = `
+insert into scheduling.webhooks(url, secret, event_types)
+VALUES ($1, $2, $3)
+returning id, url, secret, event_types, active, created_at
+`
+
+func (wr *WebhooksRepoType) CreateWebhook(ctx context.Context, newWebhook models.WebhookCreateRequest) (models.Webhook, error) {
+	var w models.Webhook
+	err := wr.db.QueryRowxContext(ctx, createWebhookQuery, newWebhook.URL, newWebhook.Secret, pq.Array(newWebhook.EventTypes)).StructScan(&w)
+	if err != nil {
+		return models.Webhook{}, errors.Wrap(err, "error creating webhook")
+	}
+
+	return w, nil
+}
+
+const getWebhookQuery = `
+select id, url, secret, event_types, active, created_at
+from scheduling.webhooks
+where id = $1
+`
+
+// GetWebhook gets a single webhook, active or not. returns sql.ErrNoRows when it doesn't exist
+func (wr *WebhooksRepoType) GetWebhook(ctx context.Context, id int64) (models.Webhook, error) {
+	var w models.Webhook
+	err := wr.db.QueryRowxContext(ctx, getWebhookQuery, id).StructScan(&w)
+	if err != nil {
+		return models.Webhook{}, errors.Wrap(err, "error getting webhook")
+	}
+
+	return w, nil
+}
+
+const deactivateWebhookQuery = `
+update scheduling.webhooks
+set active = false
+where id = $1
+returning id, url, secret, event_types, active, created_at
+`
+
+const failPendingWebhookDeliveriesQuery = `
+update scheduling.webhook_deliveries
+set status = 'failed'
+where webhook_id = $1 and status = 'pending'
+`
+
+// DeactivateWebhook stops deliveries to the webhook, ones still pending are failed. deliveries reference webhooks,
+// so they are never removed. returns sql.ErrNoRows when it doesn't exist
+func (wr *WebhooksRepoType) DeactivateWebhook(ctx context.Context, id int64) (models.Webhook, error) {
+	tx, err := wr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Webhook{}, errors.Wrap(err, "error deactivating webhook")
+	}
+	defer tx.Rollback()
+
+	var w models.Webhook
+	err = tx.QueryRowxContext(ctx, deactivateWebhookQuery, id).StructScan(&w)
+	if err != nil {
+		return models.Webhook{}, errors.Wrap(err, "error deactivating webhook")
+	}
+
+	_, err = tx.ExecContext(ctx, failPendingWebhookDeliveriesQuery, id)
+	if err != nil {
+		return models.Webhook{}, errors.Wrap(err, "error deactivating webhook")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Webhook{}, errors.Wrap(err, "error deactivating webhook")
+	}
+
+	return w, nil
+}
+
+const listWebhookDeliveriesQuery = `
+select id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, delivered_at
+from scheduling.webhook_deliveries
+where webhook_id = $1
+order by id desc
+limit $2
+`
+
+const listWebhookDeliveryAttemptsQuery = `
+select id, delivery_id, response_status, error, duration_ms, attempted_at
+from scheduling.webhook_delivery_attempts
+where delivery_id = any($1)
+order by id
+`
+
+// ListWebhookDeliveries gets the webhook's latest deliveries, newest first, each with its attempts oldest first
+func (wr *WebhooksRepoType) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := make([]models.WebhookDelivery, 0)
+	err := wr.db.SelectContext(ctx, &deliveries, listWebhookDeliveriesQuery, webhookID, limit)
+	if err != nil {
+		return []models.WebhookDelivery{}, errors.Wrap(err, "error getting webhook deliveries")
+	}
+
+	ids := make([]int64, 0, len(deliveries))
+	for _, d := range deliveries {
+		ids = append(ids, d.ID)
+	}
+
+	attempts := make([]models.WebhookDeliveryAttempt, 0)
+	err = wr.db.SelectContext(ctx, &attempts, listWebhookDeliveryAttemptsQuery, pq.Array(ids))
+	if err != nil {
+		return []models.WebhookDelivery{}, errors.Wrap(err, "error getting webhook deliveries")
+	}
+
+	byDelivery := make(map[int64][]models.WebhookDeliveryAttempt)
+	for _, a := range attempts {
+		byDelivery[a.DeliveryID] = append(byDelivery[a.DeliveryID], a)
+	}
+
+	for i := range deliveries {
+		deliveries[i].Attempts = byDelivery[deliveries[i].ID]
+		if deliveries[i].Attempts == nil {
+			deliveries[i].Attempts = []models.WebhookDeliveryAttempt{}
+		}
+	}
+
+	return deliveries, nil
+}
+
+// enqueueWebhookDeliveriesQuery casts the params, in an insert ... select they'd be text
+const enqueueWebhookDeliveriesQuery = `
+insert into scheduling.webhook_deliveries(webhook_id, event_id, event_type, payload)
+select id, $1::bigint, $2::text, $3::jsonb
+from scheduling.webhooks
+where active and $2::text = any(event_types)
+on conflict (webhook_id, event_id) do nothing
+`
+
+// EnqueueWebhookDeliveries queues a delivery of the outbox event for every active webhook subscribed to its type,
+// returning how many were queued. the relay publishes at least once, an event already queued for a webhook is skipped
+func (wr *WebhooksRepoType) EnqueueWebhookDeliveries(ctx context.Context, event events.Event) (int64, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, errors.Wrap(err, "error queueing webhook deliveries")
+	}
+
+	// lib/pq sends []byte as bytea, jsonb needs it as text
+	result, err := wr.db.ExecContext(ctx, enqueueWebhookDeliveriesQuery, event.ID, event.Type, string(payload))
+	if err != nil {
+		return 0, errors.Wrap(err, "error queueing webhook deliveries")
+	}
+
+	queued, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "error queueing webhook deliveries")
+	}
+
+	return queued, nil
+}
+
+// claimWebhookDeliveriesQuery pushes next_attempt_at back before sending, the same as claiming outbox events, so the
+// backoff doubles with each attempt from 30 seconds up to an hour
+const claimWebhookDeliveriesQuery = `
+update scheduling.webhook_deliveries d
+set attempts = d.attempts + 1,
+    next_attempt_at = now() + make_interval(secs => least(30 * power(2, d.attempts), 3600))
+from scheduling.webhooks w
+where w.id = d.webhook_id and d.id in (
+    select id from scheduling.webhook_deliveries
+    where status = 'pending' and next_attempt_at <= now()
+    order by id
+    limit $1
+    for update skip locked
+)
+returning d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.created_at,
+    d.delivered_at, w.url, w.secret
+`
+
+// ClaimWebhookDeliveries gets up to limit pending deliveries that are due, oldest first, and schedules their next attempt
+// each one has to have its attempt recorded after sending it
+func (wr *WebhooksRepoType) ClaimWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDispatch, error) {
+	claimed := make([]models.WebhookDispatch, 0)
+	err := wr.db.SelectContext(ctx, &claimed, claimWebhookDeliveriesQuery, limit)
+	if err != nil {
+		return []models.WebhookDispatch{}, errors.Wrap(err, "error claiming webhook deliveries")
+	}
+
+	// update ... returning doesn't keep an order
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	return claimed, nil
+}
+
+const createWebhookDeliveryAttemptQuery = `
+insert into scheduling.webhook_delivery_attempts(delivery_id, response_status, error, duration_ms)
+VALUES ($1, $2, $3, $4)
+`
+
+const updateWebhookDeliveryStatusQuery = `
+update scheduling.webhook_deliveries
+set status = case when $2 then 'delivered' when attempts >= $3 then 'failed' else 'pending' end,
+    delivered_at = case when $2 then now() end
+where id = $1
+returning id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, delivered_at
+`
+
+// RecordWebhookDeliveryAttempt saves the attempt and updates the delivery in a single transaction, a successful attempt
+// delivers it and a failed one is retried until it has been tried maxAttempts times
+func (wr *WebhooksRepoType) RecordWebhookDeliveryAttempt(ctx context.Context, deliveryID int64, attempt models.WebhookDeliveryAttempt, maxAttempts int) (models.WebhookDelivery, error) {
+	tx, err := wr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.WebhookDelivery{}, errors.Wrap(err, "error recording webhook delivery attempt")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, createWebhookDeliveryAttemptQuery, deliveryID, attempt.ResponseStatus, attempt.Error, attempt.DurationMS)
+	if err != nil {
+		return models.WebhookDelivery{}, errors.Wrap(err, "error recording webhook delivery attempt")
+	}
+
+	var d models.WebhookDelivery
+	err = tx.QueryRowxContext(ctx, updateWebhookDeliveryStatusQuery, deliveryID, attempt.Succeeded(), maxAttempts).StructScan(&d)
+	if err != nil {
+		return models.WebhookDelivery{}, errors.Wrap(err, "error recording webhook delivery attempt")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.WebhookDelivery{}, errors.Wrap(err, "error recording webhook delivery attempt")
+	}
+
+	return d, nil
+}
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+// MockWebhooks is an implementation of WebhooksRepository to set values to use as a mock when testing
+// the events queued and the attempts recorded are kept to check
+type MockWebhooks struct {
+	CreateWebhookResponse models.Webhook
+	CreateWebhookErr      error
+
+	GetWebhookResponse models.Webhook
+	GetWebhookErr      error
+
+	DeactivateWebhookResponse models.Webhook
+	DeactivateWebhookErr      error
+
+	ListWebhookDeliveriesResponse []models.WebhookDelivery
+	ListWebhookDeliveriesErr      error
+
+	EnqueueWebhookDeliveriesResponse int64
+	EnqueueWebhookDeliveriesErr      error
+
+	ClaimWebhookDeliveriesResponse []models.WebhookDispatch
+	ClaimWebhookDeliveriesErr      error
+
+	RecordWebhookDeliveryAttemptResponse models.WebhookDelivery
+	RecordWebhookDeliveryAttemptErr      error
+
+	Queued   []events.Event
+	Recorded []models.WebhookDeliveryAttempt
+}
+
+func (m *MockWebhooks) CreateWebhook(ctx context.Context, newWebhook models.WebhookCreateRequest) (models.Webhook, error) {
+	return m.CreateWebhookResponse, m.CreateWebhookErr
+}
+
+func (m *MockWebhooks) GetWebhook(ctx context.Context, id int64) (models.Webhook, error) {
+	return m.GetWebhookResponse, m.GetWebhookErr
+}
+
+func (m *MockWebhooks) DeactivateWebhook(ctx context.Context, id int64) (models.Webhook, error) {
+	return m.DeactivateWebhookResponse, m.DeactivateWebhookErr
+}
+
+func (m *MockWebhooks) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	return m.ListWebhookDeliveriesResponse, m.ListWebhookDeliveriesErr
+}
+
+func (m *MockWebhooks) EnqueueWebhookDeliveries(ctx context.Context, event events.Event) (int64, error) {
+	if m.EnqueueWebhookDeliveriesErr != nil {
+		return 0, m.EnqueueWebhookDeliveriesErr
+	}
+
+	m.Queued = append(m.Queued, event)
+	return m.EnqueueWebhookDeliveriesResponse, nil
+}
+
+func (m *MockWebhooks) ClaimWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDispatch, error) {
+	return m.ClaimWebhookDeliveriesResponse, m.ClaimWebhookDeliveriesErr
+}
+
+func (m *MockWebhooks) RecordWebhookDeliveryAttempt(ctx context.Context, deliveryID int64, attempt models.WebhookDeliveryAttempt, maxAttempts int) (models.WebhookDelivery, error) {
+	if m.RecordWebhookDeliveryAttemptErr != nil {
+		return models.WebhookDelivery{}, m.RecordWebhookDeliveryAttemptErr
+	}
+
+	attempt.DeliveryID = deliveryID
+	m.Recorded = append(m.Recorded, attempt)
+	return m.RecordWebhookDeliveryAttemptResponse, nil
+}
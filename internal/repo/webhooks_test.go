@@ -0,0 +1,303 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/events"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// createOutboxEvent books an appointment and returns its appointment.created event, the way the relay publishes it
+func createOutboxEvent(t *testing.T, startsAt time.Time) events.Event {
+	ar := &AppointmentsRepoType{db: DB}
+	or := &OutboxRepoType{db: DB}
+
+	_, err := ar.CreateAppointment(context.Background(), models.AppointmentCreateRequest{
+		TrainerID: 1,
+		UserID:    1,
+		StartsAt:  startsAt,
+		EndsAt:    startsAt.Add(30 * time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := or.ClaimOutboxEvents(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(claimed) != 1 {
+		t.Fatalf("expected 1 outbox event, got %d", len(claimed))
+	}
+
+	return events.NewOutboxEvent(claimed[0])
+}
+
+func TestWebhooksRepository_CreateWebhook(t *testing.T) {
+	PurgeTables()
+
+	wr := &WebhooksRepoType{db: DB}
+
+	created, err := wr.CreateWebhook(context.Background(), models.WebhookCreateRequest{
+		URL:        "https://partner.example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{events.AppointmentCreated, events.AppointmentCanceled},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(1), created.ID)
+	assert.True(t, created.Active)
+
+	got, err := wr.GetWebhook(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, created.URL, got.URL)
+	assert.Equal(t, created.Secret, got.Secret)
+	assert.Equal(t, []string{events.AppointmentCreated, events.AppointmentCanceled}, []string(got.EventTypes))
+
+	deactivated, err := wr.DeactivateWebhook(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, deactivated.Active)
+
+	_, err = wr.GetWebhook(context.Background(), 42)
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+}
+
+func TestWebhooksRepository_Deliveries(t *testing.T) {
+	PurgeTables()
+
+	wr := &WebhooksRepoType{db: DB}
+
+	subscribed, err := wr.CreateWebhook(context.Background(), models.WebhookCreateRequest{
+		URL:        "https://partner.example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{events.AppointmentCreated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wr.CreateWebhook(context.Background(), models.WebhookCreateRequest{
+		URL:        "https://other.example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{events.AppointmentCanceled},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := createOutboxEvent(t, time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC))
+
+	// only the webhook subscribed to the event's type gets it, and only once
+	queued, err := wr.EnqueueWebhookDeliveries(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(1), queued)
+
+	queued, err = wr.EnqueueWebhookDeliveries(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(0), queued)
+
+	claimed, err := wr.ClaimWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Len(t, claimed, 1) {
+		return
+	}
+
+	assert.Equal(t, subscribed.ID, claimed[0].WebhookID)
+	assert.Equal(t, subscribed.URL, claimed[0].URL)
+	assert.Equal(t, subscribed.Secret, claimed[0].Secret)
+	assert.Equal(t, event.ID, claimed[0].EventID)
+	assert.Equal(t, 1, claimed[0].AttemptCount)
+
+	// claimed deliveries wait for their backoff
+	again, err := wr.ClaimWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, again, 0)
+
+	unavailable := http.StatusServiceUnavailable
+	delivery, err := wr.RecordWebhookDeliveryAttempt(context.Background(), claimed[0].ID, models.WebhookDeliveryAttempt{ResponseStatus: &unavailable, DurationMS: 10}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+
+	_, err = DB.Exec("update scheduling.webhook_deliveries set next_attempt_at = now() - interval '1 second'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err = wr.ClaimWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, claimed, 1)
+	assert.Equal(t, 2, claimed[0].AttemptCount)
+
+	ok := http.StatusOK
+	delivery, err = wr.RecordWebhookDeliveryAttempt(context.Background(), claimed[0].ID, models.WebhookDeliveryAttempt{ResponseStatus: &ok, DurationMS: 10}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, models.WebhookDeliveryDelivered, delivery.Status)
+	assert.NotNil(t, delivery.DeliveredAt)
+
+	history, err := wr.ListWebhookDeliveries(context.Background(), subscribed.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, models.WebhookDeliveryDelivered, history[0].Status)
+		assert.Equal(t, events.AppointmentCreated, history[0].EventType)
+		if assert.Len(t, history[0].Attempts, 2) {
+			assert.Equal(t, unavailable, *history[0].Attempts[0].ResponseStatus)
+			assert.Equal(t, ok, *history[0].Attempts[1].ResponseStatus)
+		}
+	}
+}
+
+func TestWebhooksRepository_RecordWebhookDeliveryAttempt_MaxAttempts(t *testing.T) {
+	PurgeTables()
+
+	wr := &WebhooksRepoType{db: DB}
+
+	webhook, err := wr.CreateWebhook(context.Background(), models.WebhookCreateRequest{
+		URL:        "https://partner.example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{events.AppointmentCreated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wr.EnqueueWebhookDeliveries(context.Background(), createOutboxEvent(t, time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := "context deadline exceeded"
+	var delivery models.WebhookDelivery
+	for i := 0; i < 2; i++ {
+		_, err = DB.Exec("update scheduling.webhook_deliveries set next_attempt_at = now() - interval '1 second'")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claimed, err := wr.ClaimWebhookDeliveries(context.Background(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !assert.Len(t, claimed, 1) {
+			return
+		}
+
+		delivery, err = wr.RecordWebhookDeliveryAttempt(context.Background(), claimed[0].ID, models.WebhookDeliveryAttempt{Error: &timeout, DurationMS: 1000}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// out of attempts, it isn't claimed again
+	assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+
+	_, err = DB.Exec("update scheduling.webhook_deliveries set next_attempt_at = now() - interval '1 second'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := wr.ClaimWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, claimed, 0)
+
+	history, err := wr.ListWebhookDeliveries(context.Background(), webhook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, history, 1) && assert.Len(t, history[0].Attempts, 2) {
+		assert.Nil(t, history[0].Attempts[0].ResponseStatus)
+		assert.Equal(t, timeout, *history[0].Attempts[0].Error)
+	}
+}
+
+func TestWebhooksRepository_DeactivateWebhook_FailsPending(t *testing.T) {
+	PurgeTables()
+
+	wr := &WebhooksRepoType{db: DB}
+
+	webhook, err := wr.CreateWebhook(context.Background(), models.WebhookCreateRequest{
+		URL:        "https://partner.example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{events.AppointmentCreated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wr.EnqueueWebhookDeliveries(context.Background(), createOutboxEvent(t, time.Date(2022, 03, 17, 19, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wr.DeactivateWebhook(context.Background(), webhook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := wr.ClaimWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, claimed, 0)
+
+	// an inactive webhook doesn't get new events either
+	queued, err := wr.EnqueueWebhookDeliveries(context.Background(), createOutboxEvent(t, time.Date(2022, 03, 17, 20, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(0), queued)
+
+	history, err := wr.ListWebhookDeliveries(context.Background(), webhook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, models.WebhookDeliveryFailed, history[0].Status)
+		assert.Equal(t, []models.WebhookDeliveryAttempt{}, history[0].Attempts)
+	}
+}
@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+type WorkingHoursRepository interface {
+	ListWorkingHours(ctx context.Context, trainerID int64) ([]models.WorkingHours, error)
+	ListWorkingHoursForTrainers(ctx context.Context, trainerIDs []int64) ([]models.WorkingHours, error)
+	CreateWorkingHours(ctx context.Context, trainerID int64, hours models.WorkingHoursRequest) (models.WorkingHours, error)
+	UpdateWorkingHours(ctx context.Context, trainerID int64, id int64, hours models.WorkingHoursRequest) (models.WorkingHours, error)
+	DeleteWorkingHours(ctx context.Context, trainerID int64, id int64) error
+}
+
+type WorkingHoursRepoType struct {
+	db *sqlx.DB
+}
+
+func NewWorkingHoursRepository(db *sqlx.DB) WorkingHoursRepoType {
+	return WorkingHoursRepoType{
+		db: db,
+	}
+}
+
+// times are selected as text, lib/pq scans the postgres time type into a time.Time on 0000-01-01
+const workingHoursColumns = `id, trainer_id, weekday, to_char(start_time, 'HH24:MI') as start_time, to_char(end_time, 'HH24:MI') as end_time, timezone, created_at, updated_at`
+
+const listWorkingHoursQuery = `
+select ` + workingHoursColumns + `
+from scheduling.trainer_working_hours
+where trainer_id = $1
+order by weekday
+`
+
+const listWorkingHoursForTrainersQuery = `
+select ` + workingHoursColumns + `
+from scheduling.trainer_working_hours
+where trainer_id = any($1)
+order by trainer_id, weekday
+`
+
+const createWorkingHoursQuery = `
+insert into scheduling.trainer_working_hours(trainer_id, weekday, start_time, end_time, timezone)
+VALUES ($1, $2, $3, $4, $5)
+returning ` + workingHoursColumns
+
+const updateWorkingHoursQuery = `
+update scheduling.trainer_working_hours
+set weekday = $3, start_time = $4, end_time = $5, timezone = $6, updated_at = now()
+where trainer_id = $1 and id = $2
+returning ` + workingHoursColumns
+
+const deleteWorkingHoursQuery = `
+delete from scheduling.trainer_working_hours where trainer_id = $1 and id = $2
+`
+
+// ListWorkingHours gets the working hours for a trainer, an empty list means the trainer works the default hours
+func (wr *WorkingHoursRepoType) ListWorkingHours(ctx context.Context, trainerID int64) ([]models.WorkingHours, error) {
+	hours := make([]models.WorkingHours, 0)
+	err := wr.db.SelectContext(ctx, &hours, listWorkingHoursQuery, trainerID)
+	if err != nil {
+		return []models.WorkingHours{}, errors.Wrap(err, "error getting working hours")
+	}
+
+	return hours, nil
+}
+
+// ListWorkingHoursForTrainers gets the working hours for every one of the trainers in one query, ordered by trainer
+// trainers without any aren't in the list, they work the default hours
+func (wr *WorkingHoursRepoType) ListWorkingHoursForTrainers(ctx context.Context, trainerIDs []int64) ([]models.WorkingHours, error) {
+	hours := make([]models.WorkingHours, 0)
+	err := wr.db.SelectContext(ctx, &hours, listWorkingHoursForTrainersQuery, pq.Array(trainerIDs))
+	if err != nil {
+		return []models.WorkingHours{}, errors.Wrap(err, "error getting working hours")
+	}
+
+	return hours, nil
+}
+
+func (wr *WorkingHoursRepoType) CreateWorkingHours(ctx context.Context, trainerID int64, hours models.WorkingHoursRequest) (models.WorkingHours, error) {
+	var wh models.WorkingHours
+	err := wr.db.QueryRowxContext(ctx, createWorkingHoursQuery, trainerID, hours.Weekday, hours.StartTime, hours.EndTime, hours.Timezone).StructScan(&wh)
+	if isConstraintViolation(err, uniqueViolation, trainerWorkingHoursWeekdayIndex) {
+		return models.WorkingHours{}, ErrWorkingHoursExist
+	}
+
+	if err != nil {
+		return models.WorkingHours{}, errors.Wrap(err, "error creating working hours")
+	}
+
+	return wh, nil
+}
+
+func (wr *WorkingHoursRepoType) UpdateWorkingHours(ctx context.Context, trainerID int64, id int64, hours models.WorkingHoursRequest) (models.WorkingHours, error) {
+	var wh models.WorkingHours
+	err := wr.db.QueryRowxContext(ctx, updateWorkingHoursQuery, trainerID, id, hours.Weekday, hours.StartTime, hours.EndTime, hours.Timezone).StructScan(&wh)
+	if isConstraintViolation(err, uniqueViolation, trainerWorkingHoursWeekdayIndex) {
+		return models.WorkingHours{}, ErrWorkingHoursExist
+	}
+
+	if err != nil {
+		return models.WorkingHours{}, errors.Wrap(err, "error updating working hours")
+	}
+
+	return wh, nil
+}
+
+// DeleteWorkingHours removes a shift, returns sql.ErrNoRows when the shift doesn't exist for the trainer
+func (wr *WorkingHoursRepoType) DeleteWorkingHours(ctx context.Context, trainerID int64, id int64) error {
+	res, err := wr.db.ExecContext(ctx, deleteWorkingHoursQuery, trainerID, id)
+	if err != nil {
+		return errors.Wrap(err, "error deleting working hours")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "error deleting working hours")
+	}
+
+	if n == 0 {
+		return errors.Wrap(sql.ErrNoRows, "error deleting working hours")
+	}
+
+	return nil
+}
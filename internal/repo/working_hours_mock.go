@@ -0,0 +1,43 @@
+package repo
+
+import (
+	"context"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+)
+
+// MockWorkingHours is an implementation of WorkingHoursRepository to set values to use as a mock when testing
+type MockWorkingHours struct {
+	ListWorkingHoursResponse []models.WorkingHours
+	ListWorkingHoursErr      error
+
+	ListWorkingHoursForTrainersResponse []models.WorkingHours
+	ListWorkingHoursForTrainersErr      error
+
+	CreateWorkingHoursResponse models.WorkingHours
+	CreateWorkingHoursErr      error
+
+	UpdateWorkingHoursResponse models.WorkingHours
+	UpdateWorkingHoursErr      error
+
+	DeleteWorkingHoursErr error
+}
+
+func (m *MockWorkingHours) ListWorkingHours(ctx context.Context, trainerID int64) ([]models.WorkingHours, error) {
+	return m.ListWorkingHoursResponse, m.ListWorkingHoursErr
+}
+
+func (m *MockWorkingHours) ListWorkingHoursForTrainers(ctx context.Context, trainerIDs []int64) ([]models.WorkingHours, error) {
+	return m.ListWorkingHoursForTrainersResponse, m.ListWorkingHoursForTrainersErr
+}
+
+func (m *MockWorkingHours) CreateWorkingHours(ctx context.Context, trainerID int64, hours models.WorkingHoursRequest) (models.WorkingHours, error) {
+	return m.CreateWorkingHoursResponse, m.CreateWorkingHoursErr
+}
+
+func (m *MockWorkingHours) UpdateWorkingHours(ctx context.Context, trainerID int64, id int64, hours models.WorkingHoursRequest) (models.WorkingHours, error) {
+	return m.UpdateWorkingHoursResponse, m.UpdateWorkingHoursErr
+}
+
+func (m *MockWorkingHours) DeleteWorkingHours(ctx context.Context, trainerID int64, id int64) error {
+	return m.DeleteWorkingHoursErr
+}
@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"github.com/pkg/errors"
+	"github.com/samuelmahr/appt-scheduling/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWorkingHoursRepository_CreateWorkingHours(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests []models.WorkingHoursRequest
+		want     models.WorkingHours
+		wantErr  error
+	}{
+		{
+			name: "happy path",
+			requests: []models.WorkingHoursRequest{
+				{Weekday: time.Saturday, StartTime: "09:00", EndTime: "13:30", Timezone: "America/New_York"},
+			},
+			want: models.WorkingHours{
+				ID:        1,
+				TrainerID: 1,
+				Weekday:   time.Saturday,
+				StartTime: "09:00",
+				EndTime:   "13:30",
+				Timezone:  "America/New_York",
+			},
+		},
+		{
+			name: "error weekday already has working hours",
+			requests: []models.WorkingHoursRequest{
+				{Weekday: time.Saturday, StartTime: "09:00", EndTime: "13:30", Timezone: "America/New_York"},
+				{Weekday: time.Saturday, StartTime: "14:00", EndTime: "16:00", Timezone: "America/New_York"},
+			},
+			wantErr: ErrWorkingHoursExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PurgeTables()
+
+			r := &WorkingHoursRepoType{
+				db: DB,
+			}
+
+			var got models.WorkingHours
+			var err error
+			for _, req := range tt.requests {
+				got, err = r.CreateWorkingHours(context.Background(), 1, req)
+				if err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, errors.Cause(err))
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.want.ID, got.ID)
+			assert.Equal(t, tt.want.TrainerID, got.TrainerID)
+			assert.Equal(t, tt.want.Weekday, got.Weekday)
+			assert.Equal(t, tt.want.StartTime, got.StartTime)
+			assert.Equal(t, tt.want.EndTime, got.EndTime)
+			assert.Equal(t, tt.want.Timezone, got.Timezone)
+		})
+	}
+}
+
+func TestWorkingHoursRepository_ListUpdateDeleteWorkingHours(t *testing.T) {
+	PurgeTables()
+
+	r := &WorkingHoursRepoType{
+		db: DB,
+	}
+
+	ctx := context.Background()
+	for _, req := range []models.WorkingHoursRequest{
+		{Weekday: time.Saturday, StartTime: "09:00", EndTime: "13:00", Timezone: models.DefaultTimezone},
+		{Weekday: time.Monday, StartTime: "06:00", EndTime: "14:00", Timezone: models.DefaultTimezone},
+	} {
+		if _, err := r.CreateWorkingHours(ctx, 1, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// another trainer's hours aren't listed
+	if _, err := r.CreateWorkingHours(ctx, 2, models.WorkingHoursRequest{Weekday: time.Monday, StartTime: "08:00", EndTime: "17:00", Timezone: models.DefaultTimezone}); err != nil {
+		t.Fatal(err)
+	}
+
+	hours, err := r.ListWorkingHours(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, hours, 2)
+	assert.Equal(t, time.Monday, hours[0].Weekday)
+	assert.Equal(t, time.Saturday, hours[1].Weekday)
+
+	updated, err := r.UpdateWorkingHours(ctx, 1, hours[0].ID, models.WorkingHoursRequest{Weekday: time.Tuesday, StartTime: "10:00", EndTime: "18:00", Timezone: models.DefaultTimezone})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, time.Tuesday, updated.Weekday)
+	assert.Equal(t, "10:00", updated.StartTime)
+	assert.Equal(t, "18:00", updated.EndTime)
+
+	// can't update or delete another trainer's hours
+	_, err = r.UpdateWorkingHours(ctx, 2, hours[0].ID, models.WorkingHoursRequest{Weekday: time.Tuesday, StartTime: "10:00", EndTime: "18:00", Timezone: models.DefaultTimezone})
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+
+	err = r.DeleteWorkingHours(ctx, 2, hours[0].ID)
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+
+	err = r.DeleteWorkingHours(ctx, 1, hours[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hours, err = r.ListWorkingHours(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, hours, 1)
+}
+
+func TestWorkingHoursRepository_ListWorkingHoursForTrainers(t *testing.T) {
+	PurgeTables()
+
+	r := &WorkingHoursRepoType{
+		db: DB,
+	}
+
+	ctx := context.Background()
+	for _, trainerID := range []int64{2, 1, 3} {
+		if _, err := r.CreateWorkingHours(ctx, trainerID, models.WorkingHoursRequest{Weekday: time.Monday, StartTime: "08:00", EndTime: "17:00", Timezone: models.DefaultTimezone}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// trainer 3 isn't asked for
+	hours, err := r.ListWorkingHoursForTrainers(ctx, []int64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, hours, 2)
+	assert.Equal(t, int64(1), hours[0].TrainerID)
+	assert.Equal(t, int64(2), hours[1].TrainerID)
+
+	hours, err = r.ListWorkingHoursForTrainers(ctx, []int64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, hours, 0)
+}
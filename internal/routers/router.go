@@ -10,16 +10,52 @@ import (
 type V1Router struct {
 	config *configuration.AppConfig
 	uRepo  repo.AppointmentsRepoType
+	whRepo repo.WorkingHoursRepoType
+	toRepo repo.TimeOffRepoType
+	stRepo repo.SessionTypesRepoType
+	trRepo repo.TrainersRepoType
+	usRepo repo.UsersRepoType
+	hRepo  repo.HoldsRepoType
+	wlRepo repo.WaitlistRepoType
+	wbRepo repo.WebhooksRepoType
+	caRepo repo.CalendarsRepoType
 }
 
-func NewV1Router(c *configuration.AppConfig, uRepo repo.AppointmentsRepoType) V1Router {
-	return V1Router{config: c, uRepo: uRepo}
+func NewV1Router(c *configuration.AppConfig, uRepo repo.AppointmentsRepoType, whRepo repo.WorkingHoursRepoType, toRepo repo.TimeOffRepoType, stRepo repo.SessionTypesRepoType, trRepo repo.TrainersRepoType, usRepo repo.UsersRepoType, hRepo repo.HoldsRepoType, wlRepo repo.WaitlistRepoType, wbRepo repo.WebhooksRepoType, caRepo repo.CalendarsRepoType) V1Router {
+	return V1Router{config: c, uRepo: uRepo, whRepo: whRepo, toRepo: toRepo, stRepo: stRepo, trRepo: trRepo, usRepo: usRepo, hRepo: hRepo, wlRepo: wlRepo, wbRepo: wbRepo, caRepo: caRepo}
 }
 
 // Register initialize all routes
 func (v *V1Router) Register(root *mux.Router) {
 	r := root.PathPrefix("/v1").Subrouter()
 
-	appointmentsController := controllers.NewV1AppointmentsController(v.config, &v.uRepo)
+	appointmentsController := controllers.NewV1AppointmentsController(v.config, &v.uRepo, &v.whRepo, &v.toRepo, &v.stRepo, &v.trRepo, &v.wlRepo)
 	appointmentsController.RegisterRoutes(r)
+
+	workingHoursController := controllers.NewV1WorkingHoursController(v.config, &v.whRepo)
+	workingHoursController.RegisterRoutes(r)
+
+	timeOffController := controllers.NewV1TimeOffController(v.config, &v.toRepo)
+	timeOffController.RegisterRoutes(r)
+
+	sessionTypesController := controllers.NewV1SessionTypesController(v.config, &v.stRepo)
+	sessionTypesController.RegisterRoutes(r)
+
+	trainersController := controllers.NewV1TrainersController(v.config, &v.trRepo)
+	trainersController.RegisterRoutes(r)
+
+	usersController := controllers.NewV1UsersController(v.config, &v.usRepo)
+	usersController.RegisterRoutes(r)
+
+	holdsController := controllers.NewV1HoldsController(v.config, &v.hRepo, appointmentsController)
+	holdsController.RegisterRoutes(r)
+
+	waitlistController := controllers.NewV1WaitlistController(v.config, &v.wlRepo, appointmentsController)
+	waitlistController.RegisterRoutes(r)
+
+	webhooksController := controllers.NewV1WebhooksController(v.config, &v.wbRepo)
+	webhooksController.RegisterRoutes(r)
+
+	calendarsController := controllers.NewV1CalendarsController(v.config, &v.caRepo, &v.uRepo)
+	calendarsController.RegisterRoutes(r)
 }
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS scheduling.trainer_working_hours;
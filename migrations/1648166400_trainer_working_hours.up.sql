@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS scheduling.trainer_working_hours
+(
+    id         serial PRIMARY KEY,
+    trainer_id bigint      not null,
+    weekday    smallint    not null check (weekday between 0 and 6), -- 0 is Sunday, same as go's time.Weekday
+    start_time time        not null,                                 -- local time of day in timezone
+    end_time   time        not null,
+    timezone   text        not null default 'America/Los_Angeles',
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    check (start_time < end_time)
+);
+
+-- one shift per day of the week for a trainer
+CREATE UNIQUE INDEX if not exists trainer_working_hours_weekday on scheduling.trainer_working_hours (trainer_id, weekday);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS scheduling.time_off -- blackout periods a trainer can't be booked
+(
+    id         serial PRIMARY KEY,
+    trainer_id bigint,                   -- null for gym-wide blackouts like holidays
+    starts_at  timestamptz not null,
+    ends_at    timestamptz not null,
+    reason     text        not null default '',
+    created_at timestamptz not null default now(),
+    check (starts_at < ends_at)
+);
+
+CREATE INDEX if not exists time_off_trainer on scheduling.time_off (trainer_id, starts_at, ends_at);
@@ -0,0 +1,3 @@
+ALTER TABLE scheduling.appointments DROP COLUMN IF EXISTS session_type_id;
+
+DROP TABLE IF EXISTS scheduling.session_types;
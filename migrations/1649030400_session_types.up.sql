@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS scheduling.session_types
+(
+    id                       serial PRIMARY KEY,
+    name                     text        not null,
+    duration_minutes         int         not null check (duration_minutes > 0),
+    slot_granularity_minutes int         not null check (slot_granularity_minutes > 0 and 60 % slot_granularity_minutes = 0), -- how far apart start times are
+    created_at               timestamptz not null default now()
+);
+
+INSERT INTO scheduling.session_types (name, duration_minutes, slot_granularity_minutes)
+VALUES ('30 minute session', 30, 30),
+       ('45 minute session', 45, 15),
+       ('60 minute session', 60, 30),
+       ('90 minute session', 90, 30);
+
+-- null for appointments booked before session types, which are all 30 minutes
+ALTER TABLE scheduling.appointments ADD COLUMN IF NOT EXISTS session_type_id int references scheduling.session_types (id);
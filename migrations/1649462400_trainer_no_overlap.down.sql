@@ -0,0 +1 @@
+ALTER TABLE scheduling.appointments DROP CONSTRAINT IF EXISTS trainer_no_overlap;
@@ -0,0 +1,6 @@
+-- btree_gist lets the exclusion constraint compare trainer_id with = alongside the range overlap
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- trainer_scheduled only stops identical time slots, with different session lengths bookings can overlap without matching
+ALTER TABLE scheduling.appointments
+    ADD CONSTRAINT trainer_no_overlap EXCLUDE USING gist (trainer_id WITH =, tstzrange(starts_at, ends_at) WITH &&) WHERE (canceled_at is null);
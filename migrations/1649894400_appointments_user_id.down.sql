@@ -0,0 +1 @@
+DROP INDEX IF EXISTS scheduling.appointments_user_id;
@@ -0,0 +1,2 @@
+-- users look up their own appointments, starts_at is included since they're listed in time order
+CREATE INDEX IF NOT EXISTS appointments_user_id on scheduling.appointments (user_id, starts_at);
@@ -0,0 +1,10 @@
+ALTER TABLE scheduling.appointments
+    DROP CONSTRAINT IF EXISTS appointments_trainer_id_fkey,
+    DROP CONSTRAINT IF EXISTS appointments_user_id_fkey;
+
+ALTER TABLE scheduling.appointments
+    ALTER COLUMN trainer_id TYPE text USING trainer_id::text,
+    ALTER COLUMN user_id TYPE text USING user_id::text;
+
+DROP TABLE IF EXISTS scheduling.users;
+DROP TABLE IF EXISTS scheduling.trainers;
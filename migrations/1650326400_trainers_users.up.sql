@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS scheduling.trainers
+(
+    id         bigserial PRIMARY KEY,
+    name       text        not null,
+    email      text        not null,
+    active     boolean     not null default true, -- inactive trainers keep their appointments but can't be booked
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS trainers_email on scheduling.trainers (lower(email));
+
+CREATE TABLE IF NOT EXISTS scheduling.users
+(
+    id         bigserial PRIMARY KEY,
+    name       text        not null,
+    email      text        not null,
+    active     boolean     not null default true,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email on scheduling.users (lower(email));
+
+-- trainer_id and user_id were text in the initial migration, foreign keys need them to be the same type as the ids
+-- postgres rebuilds trainer_scheduled, trainer_no_overlap and appointments_user_id for the new type
+ALTER TABLE scheduling.appointments
+    ALTER COLUMN trainer_id TYPE bigint USING trainer_id::bigint,
+    ALTER COLUMN user_id TYPE bigint USING user_id::bigint;
+
+-- existing appointments need a trainer and user to point to, placeholders until the real details are entered
+INSERT INTO scheduling.trainers (id, name, email)
+SELECT DISTINCT trainer_id, 'Trainer ' || trainer_id, 'trainer-' || trainer_id || '@example.com'
+FROM scheduling.appointments
+ON CONFLICT DO NOTHING;
+
+INSERT INTO scheduling.users (id, name, email)
+SELECT DISTINCT user_id, 'User ' || user_id, 'user-' || user_id || '@example.com'
+FROM scheduling.appointments
+ON CONFLICT DO NOTHING;
+
+SELECT setval('scheduling.trainers_id_seq', coalesce((SELECT max(id) FROM scheduling.trainers), 0) + 1, false);
+SELECT setval('scheduling.users_id_seq', coalesce((SELECT max(id) FROM scheduling.users), 0) + 1, false);
+
+ALTER TABLE scheduling.appointments
+    ADD CONSTRAINT appointments_trainer_id_fkey FOREIGN KEY (trainer_id) REFERENCES scheduling.trainers (id),
+    ADD CONSTRAINT appointments_user_id_fkey FOREIGN KEY (user_id) REFERENCES scheduling.users (id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS scheduling.appointments_series_id;
+ALTER TABLE scheduling.appointments DROP COLUMN IF EXISTS series_id;
+DROP TABLE IF EXISTS scheduling.appointment_series;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS scheduling.appointment_series
+(
+    id              bigserial PRIMARY KEY,
+    trainer_id      bigint      not null references scheduling.trainers (id),
+    user_id         bigint      not null references scheduling.users (id),
+    session_type_id int references scheduling.session_types (id),
+    frequency       text        not null check (frequency in ('weekly', 'biweekly')),
+    starts_at       timestamptz not null, -- first occurrence, later ones are at the same local time
+    ends_at         timestamptz not null,
+    count           int check (count > 0),
+    until           timestamptz,
+    created_at      timestamptz not null default now(),
+    check ((count is null) <> (until is null)) -- a series ends after a number of occurrences or on a date, not both
+);
+
+-- null for one-off appointments
+ALTER TABLE scheduling.appointments ADD COLUMN IF NOT EXISTS series_id bigint references scheduling.appointment_series (id);
+
+-- canceling "this and following" looks up a series' appointments from a start time
+CREATE INDEX IF NOT EXISTS appointments_series_id on scheduling.appointments (series_id, starts_at) where series_id is not null;
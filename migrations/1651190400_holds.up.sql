@@ -0,0 +1,17 @@
+-- a hold reserves a time slot for a user until it expires, is released or is confirmed into an appointment
+CREATE TABLE IF NOT EXISTS scheduling.holds
+(
+    id              bigserial PRIMARY KEY,
+    trainer_id      bigint      not null references scheduling.trainers (id),
+    user_id         bigint      not null references scheduling.users (id),
+    starts_at       timestamptz not null,
+    ends_at         timestamptz not null,
+    session_type_id int references scheduling.session_types (id),
+    expires_at      timestamptz not null,
+    released_at     timestamptz, -- set when the hold is released early or by the sweeper once it expires
+    appointment_id  bigint references scheduling.appointments (id), -- set when the hold is confirmed
+    created_at      timestamptz not null default now()
+);
+
+-- holds that haven't been released or confirmed are checked on every booking, expired ones are filtered by expires_at
+CREATE INDEX IF NOT EXISTS holds_trainer_open on scheduling.holds (trainer_id, starts_at) where released_at is null and appointment_id is null;
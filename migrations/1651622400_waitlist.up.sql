@@ -0,0 +1,19 @@
+-- a waitlist entry is a user waiting for a booked time slot, it is offered to them as a hold when the time slot frees up
+CREATE TABLE IF NOT EXISTS scheduling.waitlist
+(
+    id              bigserial PRIMARY KEY,
+    trainer_id      bigint      not null references scheduling.trainers (id),
+    user_id         bigint      not null references scheduling.users (id),
+    starts_at       timestamptz not null,
+    ends_at         timestamptz not null,
+    session_type_id int references scheduling.session_types (id),
+    status          text        not null default 'waiting' check (status in ('waiting', 'offered', 'removed')),
+    hold_id         bigint references scheduling.holds (id), -- set when the time slot is offered
+    created_at      timestamptz not null default now()
+);
+
+-- a user waits for a time slot once
+CREATE UNIQUE INDEX IF NOT EXISTS waitlist_user_slot on scheduling.waitlist (trainer_id, user_id, starts_at) where status = 'waiting';
+
+-- offers go to the user waiting the longest
+CREATE INDEX IF NOT EXISTS waitlist_trainer_waiting on scheduling.waitlist (trainer_id, starts_at, created_at) where status = 'waiting';
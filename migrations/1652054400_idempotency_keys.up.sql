@@ -0,0 +1,13 @@
+-- an idempotency key is the response to a request sent with an Idempotency-Key header, so a retry gets the same response
+-- response_status is null while the first request is still being handled
+CREATE TABLE IF NOT EXISTS scheduling.idempotency_keys
+(
+    endpoint        text        not null,
+    key             text        not null,
+    request_hash    text        not null,
+    response_status int,
+    response_body   bytea,
+    created_at      timestamptz not null default now(),
+    completed_at    timestamptz,
+    PRIMARY KEY (endpoint, key)
+);
@@ -0,0 +1 @@
+ALTER TABLE scheduling.appointments DROP COLUMN IF EXISTS version;
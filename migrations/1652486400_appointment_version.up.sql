@@ -0,0 +1,2 @@
+-- version is bumped on every change to an appointment, clients send it back in If-Match so they don't overwrite changes they haven't seen
+ALTER TABLE scheduling.appointments ADD COLUMN IF NOT EXISTS version int not null default 1;
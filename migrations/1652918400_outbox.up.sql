@@ -0,0 +1,16 @@
+-- the outbox has the events other services need, written in the same transaction as the change they're about
+-- the relay publishes them at least once, an event is retried with backoff until published_at is set
+CREATE TABLE IF NOT EXISTS scheduling.outbox
+(
+    id              bigserial PRIMARY KEY,
+    event_type      text        not null,
+    payload         jsonb       not null,
+    occurred_at     timestamptz not null,
+    attempts        int         not null default 0,
+    next_attempt_at timestamptz not null default now(),
+    last_error      text,
+    published_at    timestamptz
+);
+
+-- the relay only looks at unpublished events that are due
+CREATE INDEX IF NOT EXISTS outbox_unpublished on scheduling.outbox (next_attempt_at, id) where published_at is null;
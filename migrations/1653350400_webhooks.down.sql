@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS scheduling.webhook_delivery_attempts;
+DROP TABLE IF EXISTS scheduling.webhook_deliveries;
+DROP TABLE IF EXISTS scheduling.webhooks;
@@ -0,0 +1,44 @@
+-- a webhook is a partner's subscription to events, deliveries are signed with its secret
+CREATE TABLE IF NOT EXISTS scheduling.webhooks
+(
+    id          bigserial PRIMARY KEY,
+    url         text        not null,
+    secret      text        not null,
+    event_types text[]      not null,
+    active      boolean     not null default true,
+    created_at  timestamptz not null default now()
+);
+
+-- a delivery is an outbox event sent to a webhook, retried with backoff until delivered or it runs out of attempts
+CREATE TABLE IF NOT EXISTS scheduling.webhook_deliveries
+(
+    id              bigserial PRIMARY KEY,
+    webhook_id      bigint      not null references scheduling.webhooks (id),
+    event_id        bigint      not null references scheduling.outbox (id),
+    event_type      text        not null,
+    payload         jsonb       not null,
+    status          text        not null default 'pending' check (status in ('pending', 'delivered', 'failed')),
+    attempts        int         not null default 0,
+    next_attempt_at timestamptz not null default now(),
+    created_at      timestamptz not null default now(),
+    delivered_at    timestamptz
+);
+
+-- the relay publishes at least once, an event is only delivered to a webhook once
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_event on scheduling.webhook_deliveries (webhook_id, event_id);
+
+-- the dispatcher only looks at pending deliveries that are due
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending on scheduling.webhook_deliveries (next_attempt_at, id) where status = 'pending';
+
+-- every try at a delivery, response_status is null when there was no response (ex. a timeout)
+CREATE TABLE IF NOT EXISTS scheduling.webhook_delivery_attempts
+(
+    id              bigserial PRIMARY KEY,
+    delivery_id     bigint      not null references scheduling.webhook_deliveries (id),
+    response_status int,
+    error           text,
+    duration_ms     int         not null,
+    attempted_at    timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_attempts_delivery on scheduling.webhook_delivery_attempts (delivery_id, id);
@@ -0,0 +1,2 @@
+ALTER TABLE scheduling.users DROP COLUMN IF EXISTS calendar_token;
+ALTER TABLE scheduling.trainers DROP COLUMN IF EXISTS calendar_token;
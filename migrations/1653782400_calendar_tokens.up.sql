@@ -0,0 +1,4 @@
+-- calendar_token is the secret in a trainer's or user's calendar feed URL, calendar apps can't send auth headers
+-- it's null until a feed URL is created, creating another one replaces it so a leaked URL can be revoked
+ALTER TABLE scheduling.trainers ADD COLUMN IF NOT EXISTS calendar_token text;
+ALTER TABLE scheduling.users ADD COLUMN IF NOT EXISTS calendar_token text;
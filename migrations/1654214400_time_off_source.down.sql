@@ -0,0 +1,2 @@
+DELETE FROM scheduling.time_off WHERE source = 'ics';
+ALTER TABLE scheduling.time_off DROP COLUMN IF EXISTS source;
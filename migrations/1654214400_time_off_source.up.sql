@@ -0,0 +1,2 @@
+-- source is where the time off came from, 'ics' is imported from a trainer's calendar and is replaced on every import
+ALTER TABLE scheduling.time_off ADD COLUMN IF NOT EXISTS source text not null default 'manual' check (source in ('manual', 'ics'));
@@ -0,0 +1,4 @@
+-- the columns stay bigint, the foreign keys to trainers and users need them to be. the trainers and users migration
+-- converts them back to text when it's rolled back
+DROP INDEX IF EXISTS scheduling.trainer_scheduled;
+CREATE UNIQUE INDEX IF NOT EXISTS trainer_scheduled on scheduling.appointments (trainer_id, starts_at, ends_at) where canceled_at is null;
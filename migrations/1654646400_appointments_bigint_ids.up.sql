@@ -0,0 +1,26 @@
+-- trainer_id and user_id were text in the initial migration, trainers and users reference them by id, so they have to be
+-- numbers. the trainers and users migration already converts them for its foreign keys, this makes sure every database
+-- ends up with bigint no matter where it started. existing ids are all numeric, ::bigint fails the migration otherwise
+DO
+$$
+    BEGIN
+        IF (SELECT data_type
+            FROM information_schema.columns
+            WHERE table_schema = 'scheduling' AND table_name = 'appointments' AND column_name = 'trainer_id') <> 'bigint' THEN
+            ALTER TABLE scheduling.appointments
+                ALTER COLUMN trainer_id TYPE bigint USING trainer_id::bigint;
+        END IF;
+
+        IF (SELECT data_type
+            FROM information_schema.columns
+            WHERE table_schema = 'scheduling' AND table_name = 'appointments' AND column_name = 'user_id') <> 'bigint' THEN
+            ALTER TABLE scheduling.appointments
+                ALTER COLUMN user_id TYPE bigint USING user_id::bigint;
+        END IF;
+    END
+$$;
+
+-- postgres rebuilds trainer_no_overlap and appointments_user_id for the new type on its own,
+-- trainer_scheduled is rebuilt explicitly so it's created the same way as on a fresh database
+DROP INDEX IF EXISTS scheduling.trainer_scheduled;
+CREATE UNIQUE INDEX IF NOT EXISTS trainer_scheduled on scheduling.appointments (trainer_id, starts_at, ends_at) where canceled_at is null;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS scheduling.appointments_canceled_starts_at;
@@ -0,0 +1,3 @@
+-- the hold sweeper looks for canceled appointments that haven't started to offer their time to the waitlist again,
+-- only canceled rows are indexed so it doesn't scan every appointment as cancellations pile up
+CREATE INDEX IF NOT EXISTS appointments_canceled_starts_at on scheduling.appointments (starts_at) where canceled_at is not null;
@@ -39,6 +39,23 @@ func main() {
 
 	fmt.Println("unmarshaled")
 
+	// appointments reference trainers and users, placeholders are added for each one in the file
+	trainerIDs := make(map[int64]bool)
+	userIDs := make(map[int64]bool)
+	trainersQuery := sq.Insert("scheduling.trainers").Columns("id", "name", "email").Suffix("ON CONFLICT DO NOTHING").PlaceholderFormat(sq.Dollar)
+	usersQuery := sq.Insert("scheduling.users").Columns("id", "name", "email").Suffix("ON CONFLICT DO NOTHING").PlaceholderFormat(sq.Dollar)
+	for _, appt := range appointments {
+		if !trainerIDs[appt.TrainerID] {
+			trainerIDs[appt.TrainerID] = true
+			trainersQuery = trainersQuery.Values(appt.TrainerID, fmt.Sprintf("Trainer %d", appt.TrainerID), fmt.Sprintf("trainer-%d@example.com", appt.TrainerID))
+		}
+
+		if !userIDs[appt.UserID] {
+			userIDs[appt.UserID] = true
+			usersQuery = usersQuery.Values(appt.UserID, fmt.Sprintf("User %d", appt.UserID), fmt.Sprintf("user-%d@example.com", appt.UserID))
+		}
+	}
+
 	query := sq.Insert("scheduling.appointments").Columns("id", "trainer_id", "user_id", "starts_at", "ends_at").PlaceholderFormat(sq.Dollar)
 	fmt.Println("building insert query")
 	for _, appt := range appointments {
@@ -65,6 +82,28 @@ func main() {
 		panic(err)
 	}
 
+	fmt.Println("inserting trainers and users...")
+	for _, q := range []sq.InsertBuilder{trainersQuery, usersQuery} {
+		qSQL, qArgs, err := q.ToSql()
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = db.Exec(qSQL, qArgs...)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// ids were inserted directly, move the sequences past them
+	_, err = db.Exec(`
+SELECT setval('scheduling.trainers_id_seq', coalesce((SELECT max(id) FROM scheduling.trainers), 0) + 1, false);
+SELECT setval('scheduling.users_id_seq', coalesce((SELECT max(id) FROM scheduling.users), 0) + 1, false);
+`)
+	if err != nil {
+		panic(err)
+	}
+
 	fmt.Println("inserting...")
 	_, err = db.Exec(sql, args...)
 	if err != nil {